@@ -0,0 +1,133 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// maxDenied is the number of unwritable directories checkRepoAccess lists.
+const maxDenied = 10
+
+// checkRepoAccess checks that merde can write the objects and refs an operation stores.
+// On shared checkouts, such as root-owned CI caches or clones made with sudo,
+// unpacking the result and updating refs would otherwise fail only after the upload.
+func checkRepoAccess(ctx context.Context, cfg *Config) error {
+	commonDir, err := cfg.Git.CommonDir(ctx)
+	if err != nil {
+		return err
+	}
+	gitDir, err := cfg.Git.GitDir(ctx)
+	if err != nil {
+		return err
+	}
+	dirs := []string{commonDir, gitDir}
+	for _, name := range []string{"objects", "objects/pack", "refs", "logs"} {
+		dir, err := cfg.Git.GitPath(ctx, name)
+		if err != nil {
+			return err
+		}
+		dirs = append(dirs, dir)
+	}
+	// Loose objects go in fan-out directories, which another user may have created.
+	objectsDir := dirs[2]
+	entries, err := os.ReadDir(objectsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() && len(e.Name()) == 2 {
+			dirs = append(dirs, filepath.Join(objectsDir, e.Name()))
+		}
+	}
+	// Refs and their reflogs go in directories named after the ref.
+	for _, name := range []string{"refs/heads", "refs/merde", "logs/refs/heads", "logs/refs/merde"} {
+		root, err := cfg.Git.GitPath(ctx, name)
+		if err != nil {
+			return err
+		}
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() {
+				dirs = append(dirs, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	slices.Sort(dirs)
+	dirs = slices.Compact(dirs)
+	var denied []string
+	for _, dir := range dirs {
+		fi, err := os.Stat(dir)
+		if os.IsNotExist(err) {
+			continue // git creates it, in a parent that is checked
+		}
+		if err == nil {
+			err = probeWrite(dir)
+		}
+		if err == nil {
+			continue
+		}
+		desc := dir
+		if fi != nil {
+			if owner := fileOwner(fi); owner != "" {
+				desc += " (owned by " + owner + ")"
+			}
+		}
+		denied = append(denied, desc)
+	}
+	if len(denied) == 0 {
+		return nil
+	}
+	buf := new(strings.Builder)
+	fmt.Fprintf(buf, "cannot write to %d directories in the git repository, so merde could not store its result:\n", len(denied))
+	for i, desc := range denied {
+		if i == maxDenied {
+			fmt.Fprintf(buf, "  ...and %d more\n", len(denied)-maxDenied)
+			break
+		}
+		fmt.Fprintf(buf, "  %s\n", desc)
+	}
+	fmt.Fprintf(buf, "run merde as the user that owns the repository, or fix its ownership, for example: sudo chown -R \"$USER\" %s", commonDir)
+	return errors.New(buf.String())
+}
+
+// writable holds the directories found writable, so that a process running many operations,
+// such as merde bot, checks each once. Unwritable ones are checked again, in case they have been fixed.
+var (
+	writableMu sync.Mutex
+	writable   = make(map[string]bool)
+)
+
+// probeWrite checks that a file can be created in dir.
+func probeWrite(dir string) error {
+	writableMu.Lock()
+	defer writableMu.Unlock()
+	if writable[dir] {
+		return nil
+	}
+	err := canWrite(dir)
+	if err == nil {
+		writable[dir] = true
+	}
+	return err
+}
@@ -0,0 +1,375 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"merde.ai/git"
+)
+
+// Delete/modify policies.
+const (
+	keepModified = "keep-modified"
+	keepDeleted  = "keep-deleted"
+	leaveBoth    = "leave" // leave the conflict for the user to resolve
+	askUser      = "ask"   // ask on a terminal; leave the conflict otherwise
+)
+
+// deleteModifyRulePrefix prefixes config keys that set a per-path delete/modify policy,
+// e.g. "delete_modify:vendor" -> "keep-deleted".
+const deleteModifyRulePrefix = deleteModifyKey + ":"
+
+// A deleteModify is a path that one branch deleted and the other modified.
+type deleteModify struct {
+	path       string
+	deletedIn  string // ref that deleted path
+	modifiedIn string // ref that modified path
+	policy     string // keep-modified, keep-deleted, or leave, once decided
+}
+
+// findDeleteModify returns the paths deleted on one side and modified on the other, relative to base.
+func findDeleteModify(info *deconflictRequestInfo) []*deleteModify {
+	var found []*deleteModify
+	for p, mainStatus := range info.mainChanges {
+		topicStatus, ok := info.topicChanges[p]
+		if !ok {
+			continue
+		}
+		switch {
+		case mainStatus == "D" && isModification(topicStatus):
+			found = append(found, &deleteModify{path: p, deletedIn: info.mainRef, modifiedIn: info.topicRef})
+		case topicStatus == "D" && isModification(mainStatus):
+			found = append(found, &deleteModify{path: p, deletedIn: info.topicRef, modifiedIn: info.mainRef})
+		}
+	}
+	slices.SortFunc(found, func(a, b *deleteModify) int { return strings.Compare(a.path, b.path) })
+	return found
+}
+
+// String formats dm for the server as "policy:path".
+func (dm *deleteModify) String() string {
+	return dm.policy + ":" + dm.path
+}
+
+func isModification(status string) bool {
+	return status == "M" || status == "T"
+}
+
+// deleteModifyPolicy returns the configured delete/modify policy for p.
+// Per-path rules take precedence over the default; the longest matching rule wins.
+func deleteModifyPolicy(cfg *Config, p string) string {
+	policy := cfg.Get(deleteModifyKey)
+	best := -1
+	for key, value := range cfg.Values {
+		pattern, ok := strings.CutPrefix(key, deleteModifyRulePrefix)
+		if !ok || len(pattern) <= best {
+			continue
+		}
+		if pathMatches(pattern, p) {
+			policy = value
+			best = len(pattern)
+		}
+	}
+	return policy
+}
+
+// pathMatches reports whether p or any of its parent directories matches the glob pattern.
+func pathMatches(pattern, p string) bool {
+	pattern = strings.TrimSuffix(pattern, "/")
+	for {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+		dir := path.Dir(p)
+		if dir == "." || dir == p {
+			return false
+		}
+		p = dir
+	}
+}
+
+// resolveDeleteModify reports delete/modify conflicts to the user and decides each one according to policy.
+func resolveDeleteModify(ctx context.Context, cfg *Config, info *deconflictRequestInfo) error {
+	found := findDeleteModify(info)
+	for _, dm := range found {
+		ui.Status("delete/modify conflict: %s (deleted in %s, modified in %s)", dm.path, dm.deletedIn, dm.modifiedIn)
+		policy := deleteModifyPolicy(cfg, dm.path)
+		if answer, ok := detached.deleteModifyAnswer(dm.path); ok {
+			policy = answer
+		}
+		switch policy {
+		case keepModified, keepDeleted, leaveBoth:
+		case askUser:
+			// Without a terminal, as in CI, there is nobody to ask, and guessing could lose work.
+			if !interactive || !isTerminal(os.Stdin) {
+				policy = leaveBoth
+				break
+			}
+			answer, err := prompt(fmt.Sprintf("keep %s? [m]odified/[d]eleted/[l]eave conflicted", dm.path), "m", "d", "l")
+			if err != nil {
+				return err
+			}
+			switch answer {
+			case "m":
+				policy = keepModified
+			case "d":
+				policy = keepDeleted
+			case "l":
+				policy = leaveBoth
+			}
+		default:
+			return fmt.Errorf("invalid %s policy %q for %s, want one of %s, %s, %s, %s", deleteModifyKey, policy, dm.path, keepModified, keepDeleted, leaveBoth, askUser)
+		}
+		dm.policy = policy
+		ui.Status("  %s: %s", dm.path, policy)
+	}
+	info.deleteModify = found
+	return nil
+}
+
+// Git tree entry modes.
+const (
+	modeFile       = "100644"
+	modeExecutable = "100755"
+	modeSymlink    = "120000"
+	modeTree       = "040000"
+	modeSubmodule  = "160000"
+)
+
+// saneModes are the modes a well-formed tree may contain.
+var saneModes = []string{modeFile, modeExecutable, modeSymlink, modeTree, modeSubmodule}
+
+// A modeChange is a path whose mode varies across the branches being combined.
+// Absent modes are empty.
+type modeChange struct {
+	path  string
+	base  string // mode at the merge base
+	main  string // mode at the main tip
+	topic string // mode at the topic tip
+}
+
+// String formats mc for the server as "base,main,topic:path", with "-" for absent modes.
+func (mc *modeChange) String() string {
+	modes := []string{cmp.Or(mc.base, "-"), cmp.Or(mc.main, "-"), cmp.Or(mc.topic, "-")}
+	return strings.Join(modes, ",") + ":" + mc.path
+}
+
+// conflicts reports whether both sides changed the mode of mc.path, in different ways.
+func (mc *modeChange) conflicts() bool {
+	return mc.main != mc.base && mc.topic != mc.base && mc.main != mc.topic && mc.main != "" && mc.topic != ""
+}
+
+// expected returns the modes the combined tree may have at mc.path.
+func (mc *modeChange) expected() []string {
+	switch {
+	case mc.conflicts():
+		return []string{mc.main, mc.topic}
+	case mc.main == mc.base:
+		return []string{mc.topic}
+	default:
+		return []string{mc.main}
+	}
+}
+
+// analyzeModes gathers mode information for paths whose mode varies and reports mode conflicts.
+func analyzeModes(ctx context.Context, cfg *Config, info *deconflictRequestInfo, varying map[string][]string) error {
+	if len(varying) == 0 {
+		return nil
+	}
+	paths := slices.Sorted(maps.Keys(varying))
+	var modes [3]map[string]string
+	for i, sha := range []string{info.baseSHA, info.mainSHA, info.topicSHA} {
+		m, err := cfg.Git.PathModes(ctx, sha, paths)
+		if err != nil {
+			return err
+		}
+		modes[i] = m
+	}
+	for _, p := range paths {
+		mc := &modeChange{path: p, base: modes[0][p], main: modes[1][p], topic: modes[2][p]}
+		info.modes = append(info.modes, mc)
+		if mc.conflicts() {
+			ui.Status("mode conflict: %s (%s in %s, %s in %s)", p, describeMode(mc.main), info.mainRef, describeMode(mc.topic), info.topicRef)
+		}
+	}
+	return nil
+}
+
+func describeMode(mode string) string {
+	switch mode {
+	case modeFile:
+		return "regular file"
+	case modeExecutable:
+		return "executable file"
+	case modeSymlink:
+		return "symlink"
+	case modeTree:
+		return "directory"
+	case modeSubmodule:
+		return "submodule"
+	}
+	return "mode " + mode
+}
+
+// verifyModes checks that the tree of the resolved commit sha contains only sane modes,
+// and that every path whose mode varied has one of the modes the branches gave it.
+func verifyModes(ctx context.Context, cfg *Config, info *deconflictRequestInfo, sha string) error {
+	modes, err := cfg.Git.PathModes(ctx, sha, nil)
+	if err != nil {
+		return err
+	}
+	for p, mode := range modes {
+		if !slices.Contains(saneModes, mode) {
+			return fmt.Errorf("resolved commit %s has invalid mode %s for %s", sha, mode, p)
+		}
+	}
+	for _, mc := range info.modes {
+		mode, ok := modes[mc.path]
+		if !ok {
+			continue // deleted; content resolution decides that
+		}
+		want := mc.expected()
+		if !slices.Contains(want, mode) {
+			return fmt.Errorf("resolved commit %s has unexpected %s for %s, want %s", sha, describeMode(mode), mc.path, strings.Join(want, " or "))
+		}
+	}
+	return nil
+}
+
+// bothModified returns the paths modified (or added) on both sides, in sorted order.
+func bothModified(info *deconflictRequestInfo) []string {
+	var paths []string
+	for p, mainStatus := range info.mainChanges {
+		if (mainStatus == "M" || mainStatus == "A") && info.topicChanges[p] == mainStatus {
+			paths = append(paths, p)
+		}
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// unresolved returns the paths modified on both sides that have not been resolved locally.
+func unresolved(info *deconflictRequestInfo) []string {
+	paths := bothModified(info)
+	for _, lr := range info.resolved {
+		paths = slices.DeleteFunc(paths, func(p string) bool { return p == lr.path })
+	}
+	return paths
+}
+
+// readFileMerge reads the base, ours, and theirs contents of p.
+// When merging, the current branch (topic) is ours; when rebasing, main is.
+func readFileMerge(ctx context.Context, cfg *Config, info *deconflictRequestInfo, p string) (*git.FileMerge, error) {
+	ours, theirs := info.mainSHA, info.topicSHA
+	if info.verb == "merge" {
+		ours, theirs = theirs, ours
+	}
+	m := &git.FileMerge{Path: p}
+	sides := []struct {
+		sha string
+		dst *[]byte
+	}{
+		{info.baseSHA, &m.Base},
+		{ours, &m.Ours},
+		{theirs, &m.Theirs},
+	}
+	if info.mainChanges[p] == "A" {
+		sides = sides[1:] // added on both sides, so the base is empty
+	}
+	for _, side := range sides {
+		var err error
+		*side.dst, err = cfg.Git.ReadBlob(ctx, side.sha, p)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// A localResolution is a path resolved locally, without the server.
+type localResolution struct {
+	path string
+	sha  string // blob containing the resolved contents
+	how  string // how it was resolved, for humans
+}
+
+// String formats lr for the server as "sha:path".
+func (lr *localResolution) String() string {
+	return lr.sha + ":" + lr.path
+}
+
+// resolveWithMergeDrivers resolves paths modified on both sides whose gitattributes
+// request merge=union or a custom merge driver, using git's own machinery.
+// Paths that the driver cannot merge cleanly are left for the server.
+func resolveWithMergeDrivers(ctx context.Context, cfg *Config, info *deconflictRequestInfo) error {
+	paths := unresolved(info)
+	if len(paths) == 0 {
+		return nil
+	}
+	attrs, err := cfg.Git.CheckAttr(ctx, "merge", paths)
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		driver := attrs[p]
+		switch driver {
+		case "", "set", "unset", "unspecified", "text", "binary":
+			continue
+		}
+		m, err := readFileMerge(ctx, cfg, info, p)
+		if err != nil {
+			return err
+		}
+		var merged []byte
+		clean := true
+		if driver == "union" {
+			merged, err = cfg.Git.MergeUnion(ctx, m)
+		} else {
+			var cmd string
+			cmd, err = cfg.Git.ConfigValue(ctx, "merge."+driver+".driver")
+			if err != nil {
+				return err
+			}
+			if cmd == "" {
+				// git falls back to the text driver for undefined drivers; so do we, via the server
+				continue
+			}
+			merged, clean, err = cfg.Git.MergeWithDriver(ctx, m, cmd)
+		}
+		if err != nil {
+			return err
+		}
+		if !clean {
+			ui.Status("merge driver %s could not resolve %s, leaving it for the server", driver, p)
+			continue
+		}
+		err = resolveLocally(ctx, cfg, info, p, merged, "merge="+driver)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveLocally records that p was resolved locally to contents.
+func resolveLocally(ctx context.Context, cfg *Config, info *deconflictRequestInfo, p string, contents []byte, how string) error {
+	sha, err := cfg.Git.WriteBlob(ctx, contents)
+	if err != nil {
+		return err
+	}
+	info.resolved = append(info.resolved, &localResolution{path: p, sha: sha, how: how})
+	// hints for the server are moot once p is resolved
+	info.eols = slices.DeleteFunc(info.eols, func(h *eolHint) bool { return h.path == p })
+	ui.Status("resolved %s locally (%s)", p, how)
+	return nil
+}
@@ -0,0 +1,333 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// moveBranch moves branch from old to sha.
+// If branch is checked out, the working tree is updated too.
+func moveBranch(ctx context.Context, cfg *Config, branch, sha, old string) error {
+	full, err := cfg.Git.FullRefName(ctx, branch)
+	if err != nil {
+		return err
+	}
+	if full == "" {
+		return fmt.Errorf("%s is not a branch", branch)
+	}
+	head, err := cfg.Git.FullRefName(ctx, "HEAD")
+	if err != nil {
+		return err
+	}
+	if head != full {
+		return cfg.Git.UpdateRef(ctx, full, sha, old)
+	}
+	current, err := cfg.Git.ResolveRef(ctx, "HEAD")
+	if err != nil {
+		return err
+	}
+	if current != old {
+		return fmt.Errorf("%s has moved to %s (expected %s)", branch, current, old)
+	}
+	return cfg.Git.ResetKeep(ctx, sha)
+}
+
+// tagResult creates an annotated tag name on op's result.
+// If name is empty, it does nothing.
+func tagResult(ctx context.Context, cfg *Config, op *operation, name string, sign bool) error {
+	if name == "" {
+		return nil
+	}
+	result := op.result()
+	if result == "" {
+		return fmt.Errorf("cannot create tag %s: operation %s has no result", name, op.ID)
+	}
+	r := op.Report
+	message := fmt.Sprintf("merde %s of %s and %s\n\nmerde operation %s\n", r.Verb, r.MainRef, r.TopicRef, op.ID)
+	err := cfg.Git.CreateTag(ctx, name, result, message, sign)
+	if err != nil {
+		return err
+	}
+	ui.Status("tagged %s as %s", result, name)
+	return nil
+}
+
+// requireCheckedOut checks that branch is the current branch.
+func requireCheckedOut(ctx context.Context, cfg *Config, branch string) error {
+	full, err := cfg.Git.FullRefName(ctx, branch)
+	if err != nil {
+		return err
+	}
+	head, err := cfg.Git.FullRefName(ctx, "HEAD")
+	if err != nil {
+		return err
+	}
+	if full == "" || full != head {
+		return fmt.Errorf("%s must be checked out", branch)
+	}
+	return nil
+}
+
+// stageMerge puts the result of merge operation op in the index and working tree
+// and leaves the merge in progress, as git merge --no-commit would,
+// so that the user can adjust it and commit it themselves.
+func stageMerge(ctx context.Context, cfg *Config, op *operation) error {
+	result := op.result()
+	if result == "" {
+		return fmt.Errorf("operation %s has no result to stage", op.ID)
+	}
+	r := op.Report
+	head, err := cfg.Git.ResolveRef(ctx, "HEAD")
+	if err != nil {
+		return err
+	}
+	if head != r.TopicSHA {
+		return fmt.Errorf("HEAD has moved since operation %s (now %s, was %s)", op.ID, head, r.TopicSHA)
+	}
+	msg, err := cfg.Git.CommitMessage(ctx, result)
+	if err != nil {
+		return err
+	}
+	gitDir, err := cfg.Git.GitDir(ctx)
+	if err != nil {
+		return err
+	}
+	err = cfg.Git.CheckoutTree(ctx, head, result)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(filepath.Join(gitDir, "MERGE_HEAD"), []byte(r.MainSHA+"\n"), 0o644)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(filepath.Join(gitDir, "MERGE_MSG"), []byte(msg), 0o644)
+	if err != nil {
+		return err
+	}
+	ui.Status("staged the resolved merge; adjust it and run git commit (or git merge --abort)")
+	return nil
+}
+
+// confirmUnexpected asks the user to confirm adopting op if its result changes files that had no conflicts.
+func confirmUnexpected(cfg *Config, op *operation) error {
+	if len(op.Report.Unexpected) == 0 || flagAdoptUnexpected {
+		return nil
+	}
+	op.Report.printUnexpected(ui)
+	yes, err := confirm(cfg, askAdoptUnexpected, "adopt it anyway?")
+	if errors.Is(err, errUnattended) {
+		return fmt.Errorf("not adopting operation %s, which changes files that had no conflicts; check them with merde review %s, then run: merde adopt --accept-unexpected %s", op.ID, op.ID, op.ID)
+	}
+	if err != nil {
+		return err
+	}
+	if !yes {
+		return fmt.Errorf("not adopting operation %s", op.ID)
+	}
+	return nil
+}
+
+// autoAdopt adopts op if the user asked for it with --adopt or the auto_adopt config.
+func autoAdopt(ctx context.Context, cfg *Config, op *operation) error {
+	if !flagAdopt && cfg.Get(autoAdoptKey) != "on" {
+		return nil
+	}
+	return adoptOperation(ctx, cfg, op)
+}
+
+// adoptOperation moves op's topic branch to op's result, keeping a backup ref so that it can be undone.
+// It then runs the configured adopt hook, deletes op's other refs, and records the adoption.
+func adoptOperation(ctx context.Context, cfg *Config, op *operation) error {
+	unlock, err := lockRepo(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	result := op.result()
+	if result == "" {
+		return fmt.Errorf("operation %s has no result to adopt", op.ID)
+	}
+	r := op.Report
+	current, err := cfg.Git.ResolveRef(ctx, r.TopicRef)
+	if err != nil {
+		return err
+	}
+	if current == result {
+		return fmt.Errorf("operation %s is already adopted", op.ID)
+	}
+	err = confirmUnexpected(cfg, op)
+	if err != nil {
+		return err
+	}
+	if current != r.TopicSHA {
+		return fmt.Errorf("%s has moved since operation %s (now %s, was %s)", r.TopicRef, op.ID, current, r.TopicSHA)
+	}
+	backup := backupRef(cfg, op)
+	err = cfg.Git.CreateRef(ctx, backup, r.TopicSHA)
+	if err != nil {
+		return err
+	}
+	err = moveBranch(ctx, cfg, r.TopicRef, result, r.TopicSHA)
+	if err != nil {
+		return err
+	}
+	ui.Status("%s: %s -> %s (backup in %s)", r.TopicRef, r.TopicSHA, result, backup)
+
+	now := time.Now()
+	op.Adopted = &now
+	err = writeOperation(ctx, cfg, op)
+	if err != nil {
+		return err
+	}
+	deleteResultRefs(ctx, cfg, op)
+	err = rememberResolutions(ctx, cfg, op)
+	if err != nil {
+		// The branch has moved; failing to share is not worth failing the adoption.
+		ui.Warn("sharing resolutions with your team: %v", err)
+	}
+	return runAdoptHook(ctx, cfg, op)
+}
+
+// deleteResultRefs deletes the refs op created in the merde namespace, other than its backup ref.
+// It is best effort: a later operation may have reused a ref.
+func deleteResultRefs(ctx context.Context, cfg *Config, op *operation) {
+	for _, ref := range ownedRefs(cfg, op) {
+		if ref.Ref == backupRef(cfg, op) {
+			continue
+		}
+		if sha, err := cfg.Git.ResolveRef(ctx, ref.Ref); err == nil && sha == ref.SHA {
+			cfg.Git.DeleteRef(ctx, ref.Ref, ref.SHA)
+		}
+	}
+}
+
+// runAdoptHook runs the configured adopt hook, if any, at the top of the working tree.
+// The hook sees the operation in MERDE_OPERATION, MERDE_BRANCH, MERDE_OLD, and MERDE_NEW.
+func runAdoptHook(ctx context.Context, cfg *Config, op *operation) error {
+	command := cfg.Get(adoptHookKey)
+	if command == "" {
+		return nil
+	}
+	root, err := cfg.Git.RootDir(ctx)
+	if err != nil {
+		return err
+	}
+	ui.Status("running adopt hook: %s", command)
+	err = cfg.Git.Shell(ctx, command).
+		Dir(root).
+		AppendEnv(os.Environ()...).
+		AppendEnv(
+			"MERDE_OPERATION="+op.ID,
+			"MERDE_BRANCH="+op.Report.TopicRef,
+			"MERDE_OLD="+op.Report.TopicSHA,
+			"MERDE_NEW="+op.result(),
+		).
+		Stdout(ui.Output()).
+		Stderr(os.Stderr).
+		Describef("adopt hook").
+		Run().
+		Wait()
+	if err != nil {
+		return fmt.Errorf("adopt hook failed (the branch was moved anyway; the old value is in %s): %w", backupRef(cfg, op), err)
+	}
+	return nil
+}
+
+// undoOperation restores op's topic branch to its value before op was applied.
+func undoOperation(ctx context.Context, cfg *Config, op *operation) error {
+	unlock, err := lockRepo(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	r := op.Report
+	backup, err := cfg.Git.ResolveRef(ctx, backupRef(cfg, op))
+	if err != nil {
+		return fmt.Errorf("operation %s has not been applied", op.ID)
+	}
+	current, err := cfg.Git.ResolveRef(ctx, r.TopicRef)
+	if err != nil {
+		return err
+	}
+	if current != op.result() {
+		return fmt.Errorf("%s has moved since operation %s was applied (now %s, was %s)", r.TopicRef, op.ID, current, op.result())
+	}
+	err = moveBranch(ctx, cfg, r.TopicRef, backup, current)
+	if err != nil {
+		return err
+	}
+	err = cfg.Git.DeleteRef(ctx, backupRef(cfg, op), backup)
+	if err != nil {
+		return err
+	}
+	op.Adopted = nil
+	err = writeOperation(ctx, cfg, op)
+	if err != nil {
+		return err
+	}
+	ui.Status("%s: %s -> %s (restored)", r.TopicRef, current, backup)
+	return nil
+}
+
+// undo reverses the last step of op: an adopted operation has its topic branch restored,
+// and any other has the refs it created deleted.
+func undo(ctx context.Context, cfg *Config, op *operation) error {
+	if op.Adopted != nil {
+		return undoOperation(ctx, cfg, op)
+	}
+	return discardOperation(ctx, cfg, op)
+}
+
+// discardOperation deletes the refs op created, and records that op was undone.
+// Refs that have moved since op are left alone.
+func discardOperation(ctx context.Context, cfg *Config, op *operation) error {
+	unlock, err := lockRepo(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if op.Undone != nil {
+		return fmt.Errorf("operation %s is already undone", op.ID)
+	}
+	// The server may only create refs that do not exist yet, so deleting them restores the prior state.
+	// A ref rewritten during the operation appears more than once; its last value is the one to expect.
+	final := make(map[string]string)
+	var refs []string
+	for _, r := range op.Report.Refs {
+		if _, ok := final[r.Ref]; !ok {
+			refs = append(refs, r.Ref)
+		}
+		final[r.Ref] = r.SHA
+	}
+	for _, ref := range refs {
+		current, err := cfg.Git.ResolveRef(ctx, ref)
+		if err != nil {
+			continue // already gone
+		}
+		if current != final[ref] {
+			ui.Warn("%s has moved since operation %s (now %s, was %s); leaving it", ref, op.ID, current, final[ref])
+			continue
+		}
+		err = cfg.Git.DeleteRef(ctx, ref, current)
+		if err != nil {
+			return err
+		}
+		ui.Status("%s: deleted (was %s)", ref, current)
+	}
+	now := time.Now()
+	op.Undone = &now
+	err = writeOperation(ctx, cfg, op)
+	if err != nil {
+		return err
+	}
+	ui.Status("operation %s undone", op.ID)
+	return nil
+}
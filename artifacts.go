@@ -0,0 +1,111 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/dustin/go-humanize"
+)
+
+// An artifact is a file the server keeps for an operation, such as its resolution report, raw patches, or logs.
+type artifact struct {
+	Name        string `json:"name"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+	SHA256      string `json:"sha256"` // hex; empty if the server does not say
+}
+
+func doArtifacts(ctx context.Context, args []string) error {
+	if len(args) > 1 {
+		return usageErrorf("merde artifacts takes at most 1 argument")
+	}
+	cfg, err := LoadDefault(ctx)
+	if err != nil {
+		return err
+	}
+	var id string
+	if len(args) == 1 {
+		id = args[0]
+	}
+	op, err := loadOperation(ctx, cfg, id)
+	if err != nil {
+		return err
+	}
+	if op.ServerID == "" {
+		return fmt.Errorf("the server gave operation %s no ID, so it has no artifacts to fetch", op.ID)
+	}
+	dir := flagArtifactsDir
+	if dir == "" {
+		dir = "merde-artifacts-" + op.ID
+	}
+	return fetchArtifacts(ctx, cfg, op, dir)
+}
+
+// fetchArtifacts downloads the server's artifacts for op into dir,
+// with a manifest.json listing them and op's local record in operation.json.
+func fetchArtifacts(ctx context.Context, cfg *Config, op *operation, dir string) error {
+	opPath := "/cli/operations/" + url.PathEscape(op.ServerID) + "/artifacts"
+	var resp struct {
+		Artifacts []artifact `json:"artifacts"`
+	}
+	err := baseRequest(cfg).
+		Path(opPath).
+		Accept("application/json").
+		ToJSON(&resp).
+		Fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("listing artifacts of operation %s: %w", op.ID, err)
+	}
+	if len(resp.Artifacts) == 0 {
+		ui.Status("the server keeps no artifacts for operation %s", op.ID)
+		return nil
+	}
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return err
+	}
+	for _, a := range resp.Artifacts {
+		// Names come from the server; keep them inside dir.
+		if a.Name == "" || a.Name != filepath.Base(a.Name) || a.Name == "." || a.Name == ".." {
+			return fmt.Errorf("the server sent an invalid artifact name %q", a.Name)
+		}
+		buf := new(bytes.Buffer)
+		err := baseRequest(cfg).
+			Path(opPath + "/" + url.PathEscape(a.Name)).
+			Accept("*/*").
+			ToBytesBuffer(buf).
+			Fetch(ctx)
+		if err != nil {
+			return fmt.Errorf("downloading artifact %s: %w", a.Name, err)
+		}
+		if a.SHA256 != "" && sha256Hex(buf.Bytes()) != a.SHA256 {
+			return fmt.Errorf("artifact %s does not match its checksum", a.Name)
+		}
+		err = os.WriteFile(filepath.Join(dir, a.Name), buf.Bytes(), 0o644)
+		if err != nil {
+			return err
+		}
+		ui.Status("%s (%s)", filepath.Join(dir, a.Name), humanize.Bytes(uint64(buf.Len())))
+	}
+	for name, v := range map[string]any{"manifest.json": resp.Artifacts, "operation.json": op} {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(filepath.Join(dir, name), data, 0o644)
+		if err != nil {
+			return err
+		}
+	}
+	ui.Status("fetched %d artifacts of operation %s into %s", len(resp.Artifacts), op.ID, dir)
+	return nil
+}
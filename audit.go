@@ -0,0 +1,147 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/carlmjohnson/requests"
+)
+
+// An auditEntry records one upload to the server, for the audit log.
+type auditEntry struct {
+	Time          time.Time `json:"time"`
+	User          string    `json:"user"`               // OS user
+	GitUser       string    `json:"git_user,omitempty"` // git user.email
+	Host          string    `json:"host"`
+	Command       string    `json:"command"` // merge, rebase, resolve-file, ...
+	Server        string    `json:"server"`
+	Repo          string    `json:"repo,omitempty"` // working tree root
+	Remotes       []string  `json:"remotes,omitempty"`
+	Path          string    `json:"path,omitempty"` // file uploaded by resolve-file
+	MainRef       string    `json:"main_ref,omitempty"`
+	TopicRef      string    `json:"topic_ref,omitempty"`
+	MainSHA       string    `json:"main_sha,omitempty"`
+	TopicSHA      string    `json:"topic_sha,omitempty"`
+	BaseSHA       string    `json:"base_sha,omitempty"`
+	BytesSent     int64     `json:"bytes_sent"`
+	BytesReceived int64     `json:"bytes_received"`
+	OperationID   string    `json:"operation_id,omitempty"` // the server's ID for the operation, if it sent one
+	Ephemeral     bool      `json:"ephemeral,omitempty"`    // the upload asked the server not to keep the objects
+	Deleted       bool      `json:"deleted,omitempty"`      // the server acknowledged deleting them
+	Error         string    `json:"error,omitempty"`
+
+	log *os.File
+}
+
+// beginAudit starts an audit log entry for an upload by command,
+// or returns nil if the audit log is off.
+// It opens the log first, so that an upload that cannot be logged never happens.
+func beginAudit(ctx context.Context, cfg *Config, command string) (*auditEntry, error) {
+	path := cfg.Get(auditLogKey)
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open audit log, refusing to upload: %w", err)
+	}
+	e := &auditEntry{
+		Time:    time.Now().UTC(),
+		Command: command,
+		Server:  cfg.Get(serverRootKey),
+		log:     f,
+	}
+	if u, err := user.Current(); err == nil {
+		e.User = u.Username
+	}
+	e.Host, _ = os.Hostname()
+	if cfg.Git != nil {
+		// Best effort: resolve-file may run outside a repository.
+		e.GitUser, _ = cfg.Git.ConfigValue(ctx, "user.email")
+		e.Repo, _ = cfg.Git.RootDir(ctx)
+		e.Remotes, _ = cfg.Git.Remotes(ctx, preferredRemote(cfg))
+	}
+	return e, nil
+}
+
+// recordDeconflict records what a merge or rebase uploads.
+func (e *auditEntry) recordDeconflict(info *deconflictRequestInfo) {
+	if e == nil {
+		return
+	}
+	e.MainRef, e.TopicRef = info.mainRef, info.topicRef
+	e.MainSHA, e.TopicSHA, e.BaseSHA = info.mainSHA, info.topicSHA, info.baseSHA
+	e.BytesSent = int64(info.packSize())
+	e.Ephemeral = info.ephemeral
+	e.Deleted = info.objectsDeleted
+}
+
+// finish completes the entry with the outcome of the upload, appends it to the audit log,
+// and forwards it to audit_forward, if set.
+// Forwarding is best effort; the local log is the record.
+func (e *auditEntry) finish(ctx context.Context, cfg *Config, uploadErr error) error {
+	if e == nil {
+		return nil
+	}
+	defer e.log.Close()
+	if uploadErr != nil {
+		e.Error = uploadErr.Error()
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = e.log.Write(append(line, '\n'))
+	if err != nil {
+		return fmt.Errorf("writing audit log: %w", err)
+	}
+	if dest := cfg.Get(auditForwardKey); dest != "" {
+		err = forwardAudit(ctx, dest, line)
+		if err != nil {
+			ui.Warn("cannot forward audit entry to %s: %v", dest, err)
+		}
+	}
+	return nil
+}
+
+// forwardAudit sends an audit log line to dest: an http(s) URL, which receives it as a JSON POST,
+// or a udp:// or tcp:// syslog address, which receives it as an RFC 5424 message.
+func forwardAudit(ctx context.Context, dest string, line []byte) error {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return requests.URL(dest).
+			ContentType("application/json").
+			BodyBytes(line).
+			Fetch(ctx)
+	case "udp", "tcp":
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, u.Scheme, u.Host)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		host, _ := os.Hostname()
+		// Facility authpriv (10), severity info (6).
+		msg := fmt.Sprintf("<86>1 %s %s merde %d - - %s", time.Now().UTC().Format(time.RFC3339), host, os.Getpid(), line)
+		if u.Scheme == "tcp" {
+			msg = fmt.Sprintf("%d %s", len(msg), msg) // octet counting, RFC 6587
+		}
+		_, err = conn.Write([]byte(msg))
+		return err
+	}
+	return fmt.Errorf("unsupported %s scheme %q; use https, udp, or tcp", auditForwardKey, u.Scheme)
+}
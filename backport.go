@@ -0,0 +1,219 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// pickCommit cherry-picks commit onto tip, resolving any conflicts with merde, and returns the new commit.
+// It returns "" if commit's changes are already in tip.
+func pickCommit(ctx context.Context, cfg *Config, commit, tip string) (string, error) {
+	tree, _, err := pickedTree(ctx, cfg, commit, tip)
+	if err != nil {
+		return "", err
+	}
+	tipTree, err := cfg.Git.Tree(ctx, tip)
+	if err != nil {
+		return "", err
+	}
+	if tree == tipTree {
+		return "", nil
+	}
+	return cfg.Git.CherryPickCommit(ctx, commit, tree, tip)
+}
+
+// pickedTree returns the tree of cherry-picking commit onto tip, resolving any conflicts with merde,
+// and the paths that conflicted.
+func pickedTree(ctx context.Context, cfg *Config, commit, tip string) (string, []string, error) {
+	onto, pick, err := pickCommits(ctx, cfg, commit, tip)
+	if err != nil {
+		return "", nil, err
+	}
+	tree, conflicts, err := cfg.Git.MergeTree(ctx, onto, pick)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(conflicts) > 0 {
+		ui.Status("%s: %d conflicting paths, resolving with merde", commit[:12], len(conflicts))
+		tree, err = resolvePick(ctx, cfg, onto, pick)
+		if err != nil {
+			return "", nil, fmt.Errorf("picking %s: %w", commit, err)
+		}
+	}
+	return tree, conflicts, nil
+}
+
+// pickCommits returns synthetic commits onto and pick whose merge is the cherry-pick of commit onto tip.
+//
+// The server knows how to rebase, so a conflicted pick is posed as a rebase:
+// synthetic copies of tip and commit with a common parent holding the tree of commit's parent,
+// which makes that tree the merge base, as in a cherry-pick.
+func pickCommits(ctx context.Context, cfg *Config, commit, tip string) (onto, pick string, err error) {
+	parents, err := cfg.Git.Parents(ctx, commit)
+	if err != nil {
+		return "", "", err
+	}
+	if len(parents) != 1 {
+		return "", "", fmt.Errorf("cannot pick %s: it has %d parents", commit, len(parents))
+	}
+	baseTree, err := cfg.Git.Tree(ctx, parents[0])
+	if err != nil {
+		return "", "", err
+	}
+	tipTree, err := cfg.Git.Tree(ctx, tip)
+	if err != nil {
+		return "", "", err
+	}
+	pickTree, err := cfg.Git.Tree(ctx, commit)
+	if err != nil {
+		return "", "", err
+	}
+	base, err := cfg.Git.CommitTree(ctx, baseTree, "merde: parent of "+commit)
+	if err != nil {
+		return "", "", err
+	}
+	onto, err = cfg.Git.CommitTree(ctx, tipTree, "merde: "+tip, base)
+	if err != nil {
+		return "", "", err
+	}
+	pick, err = cfg.Git.CommitTree(ctx, pickTree, "merde: "+commit, base)
+	if err != nil {
+		return "", "", err
+	}
+	return onto, pick, nil
+}
+
+// resolvePick rebases the synthetic commit pick onto onto with merde, and returns the resolved tree.
+func resolvePick(ctx context.Context, cfg *Config, onto, pick string) (string, error) {
+	ns := refNamespace(cfg)
+	ontoRef, pickRef := ns+"tmp/pick-onto", ns+"tmp/pick"
+	for ref, sha := range map[string]string{ontoRef: onto, pickRef: pick} {
+		err := cfg.Git.SetRef(ctx, ref, sha)
+		if err != nil {
+			return "", err
+		}
+		defer cfg.Git.DeleteRef(ctx, ref, sha)
+	}
+	op, err := deconflict(ctx, cfg, "rebase", ontoRef, pickRef)
+	if err != nil {
+		return "", err
+	}
+	// The operation's refs are only a step towards the picked commit.
+	defer deleteResultRefs(ctx, cfg, op)
+	result := op.result()
+	if result == "" {
+		return "", fmt.Errorf("merde produced no result")
+	}
+	return cfg.Git.Tree(ctx, result)
+}
+
+// replayCommits cherry-picks commits, oldest first, onto the commit ontoRef names,
+// and stores the result in a ref named after verb and ontoRef, which it returns.
+// Commits whose changes are already present are skipped, as are those for which skip returns true.
+func replayCommits(ctx context.Context, cfg *Config, verb string, commits []string, ontoRef string, skip func(commit string) bool) (string, error) {
+	start, err := cfg.Git.ResolveRef(ctx, ontoRef)
+	if err != nil {
+		return "", err
+	}
+	tip := start
+	for _, c := range commits {
+		subject, err := cfg.Git.CommitMessage(ctx, c)
+		if err != nil {
+			return "", err
+		}
+		subject, _, _ = strings.Cut(subject, "\n")
+		if skip != nil && skip(c) {
+			ui.Status("%s  skipped, already in %s: %s", c[:12], ontoRef, subject)
+			continue
+		}
+		picked, err := pickCommit(ctx, cfg, c, tip)
+		if err != nil {
+			return "", err
+		}
+		if picked == "" {
+			ui.Status("%s  skipped, no changes left: %s", c[:12], subject)
+			continue
+		}
+		ui.Status("%s  -> %s %s", c[:12], picked[:12], subject)
+		tip = picked
+	}
+	if tip == start {
+		return "", fmt.Errorf("nothing to %s: every commit is already in %s", verb, ontoRef)
+	}
+	return saveReplay(ctx, cfg, verb, ontoRef, tip)
+}
+
+// saveReplay stores tip, the result of replaying commits onto ontoRef, in a ref named after verb and ontoRef,
+// which it returns, and tells the user how to review and apply it.
+func saveReplay(ctx context.Context, cfg *Config, verb, ontoRef, tip string) (string, error) {
+	branch := strings.TrimPrefix(ontoRef, "refs/heads/")
+	ref := refNamespace(cfg) + verb + "/" + branch
+	err := cfg.Git.SetRef(ctx, ref, tip)
+	if err != nil {
+		return "", err
+	}
+	ui.Status("%s result in %s", verb, ref)
+	ui.Status("review it with: git log %s..%s", ontoRef, ref)
+	ui.Status("apply it with: git checkout %s && git merge --ff-only %s", branch, ref)
+	return ref, nil
+}
+
+func doBackport(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return usageErrorf("merde backport takes exactly one commit or commit range")
+	}
+	if flagBackportOnto == "" {
+		return usageErrorf("merde backport needs --onto")
+	}
+	cfg, err := LoadDefault(ctx)
+	if err != nil {
+		return err
+	}
+	commits, err := cfg.Git.CommitsInRange(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		return fmt.Errorf("no commits in %s", args[0])
+	}
+	ui.Status("plan: backport %d commits onto %s", len(commits), flagBackportOnto)
+	_, err = replayCommits(ctx, cfg, "backport", commits, flagBackportOnto, nil)
+	return err
+}
+
+func doCherryPick(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return usageErrorf("merde cherry-pick takes at least one commit or commit range")
+	}
+	cfg, err := LoadDefault(ctx)
+	if err != nil {
+		return err
+	}
+	head, err := cfg.Git.FullRefName(ctx, "HEAD")
+	if err != nil {
+		return err
+	}
+	branch, ok := strings.CutPrefix(head, "refs/heads/")
+	if !ok {
+		return fmt.Errorf("cannot cherry-pick onto a detached HEAD; check out a branch first")
+	}
+	var commits []string
+	for _, arg := range args {
+		cs, err := cfg.Git.CommitsInRange(ctx, arg)
+		if err != nil {
+			return err
+		}
+		if len(cs) == 0 {
+			return fmt.Errorf("no commits in %s", arg)
+		}
+		commits = append(commits, cs...)
+	}
+	ui.Status("plan: cherry-pick %d commits onto %s", len(commits), branch)
+	_, err = replayCommits(ctx, cfg, "cherry-pick", commits, branch, nil)
+	return err
+}
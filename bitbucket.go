@@ -0,0 +1,252 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/carlmjohnson/requests"
+)
+
+// bitbucketRepoRx extracts workspace and repo from a Bitbucket Cloud remote URL,
+// e.g. git@bitbucket.org:workspace/repo.git or https://user@bitbucket.org/workspace/repo.git.
+var bitbucketRepoRx = regexp.MustCompile(`bitbucket\.org[:/]([^/]+)/([^/]+?)(?:\.git)?/?$`)
+
+// A bitbucketForge is a repository on Bitbucket Cloud.
+type bitbucketForge struct {
+	cfg                 *Config
+	workspace, repoSlug string
+}
+
+func matchBitbucket(cfg *Config, url string) forge {
+	m := bitbucketRepoRx.FindStringSubmatch(url)
+	if m == nil {
+		return nil
+	}
+	return &bitbucketForge{cfg: cfg, workspace: m[1], repoSlug: m[2]}
+}
+
+func (b *bitbucketForge) String() string {
+	return b.workspace + "/" + b.repoSlug
+}
+
+func (b *bitbucketForge) tokenKey() string {
+	return bitbucketTokenKey
+}
+
+// request returns a request builder for the repository in the Bitbucket Cloud API.
+// The token is an access token, or username:app-password.
+func (b *bitbucketForge) request() *requests.Builder {
+	rb := requests.URL("https://api.bitbucket.org/2.0/repositories/").
+		Path(b.workspace + "/" + b.repoSlug + "/")
+	token := b.cfg.Get(bitbucketTokenKey)
+	if user, pass, ok := strings.Cut(token, ":"); ok {
+		return rb.BasicAuth(user, pass)
+	}
+	return rb.Bearer(token)
+}
+
+func (b *bitbucketForge) findPullRequest(ctx context.Context, branch string) (int, error) {
+	var page struct {
+		Values []struct {
+			ID int `json:"id"`
+		} `json:"values"`
+	}
+	err := b.request().
+		Path("pullrequests").
+		Param("q", fmt.Sprintf(`source.branch.name = %q AND state = "OPEN"`, branch)).
+		ToJSON(&page).
+		Fetch(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(page.Values) == 0 {
+		return 0, nil
+	}
+	return page.Values[0].ID, nil
+}
+
+func (b *bitbucketForge) comment(ctx context.Context, pr int, body string) error {
+	return b.request().
+		Pathf("pullrequests/%d/comments", pr).
+		BodyJSON(map[string]any{"content": map[string]string{"raw": body}}).
+		Post().
+		Fetch(ctx)
+}
+
+func (b *bitbucketForge) openPullRequest(ctx context.Context, title, head, base, body string) (int, error) {
+	var created struct {
+		ID int `json:"id"`
+	}
+	err := b.request().
+		Path("pullrequests").
+		BodyJSON(map[string]any{
+			"title":       title,
+			"description": body,
+			"source":      map[string]any{"branch": map[string]string{"name": head}},
+			"destination": map[string]any{"branch": map[string]string{"name": base}},
+		}).
+		ToJSON(&created).
+		Fetch(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return created.ID, nil
+}
+
+// bitbucketLinks are the clone links of a Bitbucket repository, in both Cloud's and Server's APIs.
+type bitbucketLinks struct {
+	Clone []struct {
+		Name string `json:"name"`
+		Href string `json:"href"`
+	} `json:"clone"`
+}
+
+// https returns the HTTPS clone URL in l, or "" if there is none.
+func (l *bitbucketLinks) https() string {
+	for _, c := range l.Clone {
+		if c.Name == "https" || c.Name == "http" {
+			return c.Href
+		}
+	}
+	return ""
+}
+
+func (b *bitbucketForge) parentURL(ctx context.Context) (string, error) {
+	var info struct {
+		Parent *struct {
+			Links bitbucketLinks `json:"links"`
+		} `json:"parent"`
+	}
+	err := b.request().
+		ToJSON(&info).
+		Fetch(ctx)
+	if err != nil {
+		return "", fmt.Errorf("looking up %s: %w", b, err)
+	}
+	if info.Parent == nil {
+		return "", nil
+	}
+	return info.Parent.Links.https(), nil
+}
+
+// bitbucketServerPathRx extracts project key and repo slug from the path of a Bitbucket Server remote URL,
+// e.g. https://host/scm/proj/repo.git or ssh://git@host:7999/proj/repo.git.
+var bitbucketServerPathRx = regexp.MustCompile(`^/(?:scm/)?([^/]+)/([^/]+?)(?:\.git)?/?$`)
+
+// A bitbucketServerForge is a repository on a self-hosted Bitbucket Server or Data Center,
+// configured with bitbucket_server.
+type bitbucketServerForge struct {
+	cfg               *Config
+	server            string // root URL of the server
+	project, repoSlug string
+}
+
+func matchBitbucketServer(cfg *Config, remote string) forge {
+	server := cfg.Get(bitbucketServerKey)
+	if server == "" {
+		return nil
+	}
+	su, err := url.Parse(server)
+	if err != nil {
+		return nil
+	}
+	ru, err := parseRemote(remote)
+	if err != nil || ru.Hostname() != su.Hostname() {
+		return nil
+	}
+	m := bitbucketServerPathRx.FindStringSubmatch(strings.TrimPrefix(ru.Path, su.Path))
+	if m == nil {
+		return nil
+	}
+	return &bitbucketServerForge{cfg: cfg, server: server, project: m[1], repoSlug: m[2]}
+}
+
+func (b *bitbucketServerForge) String() string {
+	return b.project + "/" + b.repoSlug
+}
+
+func (b *bitbucketServerForge) tokenKey() string {
+	return bitbucketServerTokenKey
+}
+
+// request returns a request builder for the repository in the Bitbucket Server REST API.
+func (b *bitbucketServerForge) request() *requests.Builder {
+	return requests.URL(b.server).
+		Pathf("rest/api/1.0/projects/%s/repos/%s/", b.project, b.repoSlug).
+		Bearer(b.cfg.Get(bitbucketServerTokenKey))
+}
+
+func (b *bitbucketServerForge) findPullRequest(ctx context.Context, branch string) (int, error) {
+	var page struct {
+		Values []struct {
+			ID int `json:"id"`
+		} `json:"values"`
+	}
+	err := b.request().
+		Path("pull-requests").
+		Param("at", "refs/heads/"+branch).
+		Param("direction", "OUTGOING").
+		Param("state", "OPEN").
+		ToJSON(&page).
+		Fetch(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(page.Values) == 0 {
+		return 0, nil
+	}
+	return page.Values[0].ID, nil
+}
+
+func (b *bitbucketServerForge) comment(ctx context.Context, pr int, body string) error {
+	return b.request().
+		Pathf("pull-requests/%d/comments", pr).
+		BodyJSON(map[string]string{"text": body}).
+		Post().
+		Fetch(ctx)
+}
+
+func (b *bitbucketServerForge) openPullRequest(ctx context.Context, title, head, base, body string) (int, error) {
+	var created struct {
+		ID int `json:"id"`
+	}
+	err := b.request().
+		Path("pull-requests").
+		BodyJSON(map[string]any{
+			"title":       title,
+			"description": body,
+			"fromRef":     map[string]string{"id": "refs/heads/" + head},
+			"toRef":       map[string]string{"id": "refs/heads/" + base},
+		}).
+		ToJSON(&created).
+		Fetch(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return created.ID, nil
+}
+
+func (b *bitbucketServerForge) parentURL(ctx context.Context) (string, error) {
+	var info struct {
+		Origin *struct {
+			Links bitbucketLinks `json:"links"`
+		} `json:"origin"`
+	}
+	err := b.request().
+		ToJSON(&info).
+		Fetch(ctx)
+	if err != nil {
+		return "", fmt.Errorf("looking up %s: %w", b, err)
+	}
+	if info.Origin == nil {
+		return "", nil
+	}
+	return info.Origin.Links.https(), nil
+}
@@ -0,0 +1,226 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// A botJob is one branch pair the bot keeps reconciled.
+type botJob struct {
+	Verb        string `json:"verb"`         // merge or rebase; default rebase
+	Main        string `json:"main"`         // e.g. origin/main
+	Topic       string `json:"topic"`        // e.g. origin/feature
+	Fetch       string `json:"fetch"`        // remote to fetch before each run, if any
+	Ref         string `json:"ref"`          // ref that receives the result; default refs/heads/merde/<topic>
+	Push        string `json:"push"`         // remote to push Ref to, if any; otherwise Ref is updated locally
+	PullRequest bool   `json:"pull_request"` // open a pull request from the pushed Ref into Topic
+}
+
+// A botConfig is the contents of the file given to merde bot.
+type botConfig struct {
+	Jobs []*botJob `json:"jobs"`
+}
+
+// topicBranch returns the name of job's topic branch, without any remote.
+func (job *botJob) topicBranch() string {
+	branch := strings.TrimPrefix(job.Topic, "refs/heads/")
+	branch = strings.TrimPrefix(branch, "refs/remotes/")
+	for _, remote := range []string{job.Fetch, job.Push} {
+		if remote != "" {
+			branch = strings.TrimPrefix(branch, remote+"/")
+		}
+	}
+	return branch
+}
+
+// target names where job delivers its results: Ref, on the Push remote if there is one.
+func (job *botJob) target() string {
+	if job.Push != "" {
+		return job.Push + ":" + job.Ref
+	}
+	return job.Ref
+}
+
+// lastDelivered returns the most recent operation whose result merde bot delivered to job's target, or nil if none.
+func lastDelivered(ctx context.Context, cfg *Config, job *botJob) (*operation, error) {
+	ids, err := operationIDs(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	// IDs from the same second sort by topic, not by time, so compare times.
+	var last *operation
+	for _, id := range ids {
+		op, err := loadOperation(ctx, cfg, id)
+		if err != nil {
+			return nil, err
+		}
+		if op.BotRef == job.target() && op.Report.Verb == job.Verb && (last == nil || !op.Time.Before(last.Time)) {
+			last = op
+		}
+	}
+	return last, nil
+}
+
+// botDeconflict resolves a job's branches; tests replace it to stand in for the server.
+var botDeconflict = deconflict
+
+func loadBotConfig(file string) (*botConfig, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	bc := new(botConfig)
+	err = json.Unmarshal(data, bc)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", file, err)
+	}
+	for i, job := range bc.Jobs {
+		if job.Verb == "" {
+			job.Verb = "rebase"
+		}
+		if job.Verb != "merge" && job.Verb != "rebase" {
+			return nil, fmt.Errorf("%s: job %d: unknown verb %q", file, i, job.Verb)
+		}
+		if job.Main == "" || job.Topic == "" {
+			return nil, fmt.Errorf("%s: job %d: main and topic are required", file, i)
+		}
+		if job.Ref == "" {
+			job.Ref = "refs/heads/merde/" + job.topicBranch()
+		}
+		if job.PullRequest && job.Push == "" {
+			return nil, fmt.Errorf("%s: job %d: pull_request requires push", file, i)
+		}
+	}
+	return bc, nil
+}
+
+// runBotJob brings job's result up to date, logging what happened to log.
+func runBotJob(ctx context.Context, cfg *Config, log *slog.Logger, job *botJob) error {
+	log = log.With("verb", job.Verb, "main", job.Main, "topic", job.Topic)
+	start := time.Now()
+	if job.Fetch != "" {
+		err := cfg.Git.Fetch(ctx, job.Fetch)
+		if err != nil {
+			return err
+		}
+	}
+	mainSHA, err := cfg.Git.ResolveRef(ctx, job.Main)
+	if err != nil {
+		return err
+	}
+	topicSHA, err := cfg.Git.ResolveRef(ctx, job.Topic)
+	if err != nil {
+		return err
+	}
+	base, err := cfg.Git.UniqueAncestorMergeBase(ctx, []string{mainSHA, topicSHA})
+	if err != nil {
+		return err
+	}
+	if base == mainSHA {
+		log.Info("up to date", "event", "skipped")
+		return nil
+	}
+	// Resolving the same branches again would only spend credits on the result job.Ref already has.
+	last, err := lastDelivered(ctx, cfg, job)
+	if err != nil {
+		return err
+	}
+	if last != nil && last.Report.MainSHA == mainSHA && last.Report.TopicSHA == topicSHA {
+		current := last.result()
+		if job.Push == "" {
+			current, _ = cfg.Git.ResolveRef(ctx, job.Ref)
+		}
+		if current == last.result() {
+			log.Info("up to date", "event", "skipped", "operation", last.ID, "result", current, "ref", job.Ref)
+			return nil
+		}
+	}
+	op, err := botDeconflict(ctx, cfg, job.Verb, job.Main, job.Topic)
+	if err != nil {
+		return err
+	}
+	result := op.result()
+	if result == "" {
+		return fmt.Errorf("operation %s produced no result", op.ID)
+	}
+	if job.Push != "" {
+		err = cfg.Git.Push(ctx, job.Push, result, job.Ref)
+	} else {
+		err = cfg.Git.SetRef(ctx, job.Ref, result)
+	}
+	if err != nil {
+		return err
+	}
+	// The result lives in job.Ref now; don't let result refs pile up between runs.
+	deleteResultRefs(ctx, cfg, op)
+	log = log.With("operation", op.ID, "result", result, "ref", job.Ref, "duration_ms", time.Since(start).Milliseconds())
+	if job.PullRequest {
+		pr, err := openPullRequest(ctx, cfg, job, op)
+		if err != nil {
+			return err
+		}
+		log = log.With("pull_request", pr)
+	}
+	op.BotRef = job.target()
+	err = writeOperation(ctx, cfg, op)
+	if err != nil {
+		return err
+	}
+	log.Info("resolved", "event", "resolved", "conflicts", len(op.Report.Conflicts))
+	return nil
+}
+
+func doBot(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return usageErrorf("merde bot takes exactly one jobs file")
+	}
+	bc, err := loadBotConfig(args[0])
+	if err != nil {
+		return err
+	}
+	cfg, err := LoadDefault(ctx)
+	if err != nil {
+		return err
+	}
+	// stdout carries the structured log.
+	log := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	err = takeOverStdout()
+	if err != nil {
+		return err
+	}
+	for {
+		var failed int
+		for _, job := range bc.Jobs {
+			err := requireCleanGitStatus(ctx, cfg)
+			if err == nil {
+				err = collectExit(ctx, func(ctx context.Context) error {
+					return runBotJob(ctx, cfg, log, job)
+				})
+			}
+			if err != nil {
+				failed++
+				log.Error("failed", "event", "failed", "error", err, "verb", job.Verb, "main", job.Main, "topic", job.Topic)
+			}
+		}
+		if flagBotInterval == 0 {
+			if failed > 0 {
+				return fmt.Errorf("%d of %d jobs failed", failed, len(bc.Jobs))
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(flagBotInterval):
+		}
+	}
+}
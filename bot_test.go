@@ -0,0 +1,75 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"merde.ai/git/gittest"
+)
+
+func TestRunBotJobSkipsUnchangedInputs(t *testing.T) {
+	cfg, dir, run := testRepo(t)
+	ctx := context.Background()
+	base := gittest.Commit(t, dir, run, "base", map[string]string{"a.txt": "a\n"})
+	gittest.Commit(t, dir, run, "main", map[string]string{"main.txt": "main\n"})
+	run("checkout", "-q", "-b", "topic", base)
+	gittest.Commit(t, dir, run, "topic", map[string]string{"topic.txt": "topic\n"})
+
+	// Stand in for the server: merge the branches locally and record the operation as deconflict would.
+	calls := 0
+	old := botDeconflict
+	defer func() { botDeconflict = old }()
+	botDeconflict = func(ctx context.Context, cfg *Config, verb, mainRef, topicRef string) (*operation, error) {
+		calls++
+		info := &deconflictRequestInfo{verb: verb, mainRef: mainRef, topicRef: topicRef}
+		info.mainSHA = run("rev-parse", mainRef)
+		info.topicSHA = run("rev-parse", topicRef)
+		info.baseSHA = run("merge-base", info.mainSHA, info.topicSHA)
+		tree := run("merge-tree", "--write-tree", info.mainSHA, info.topicSHA)
+		result := run("commit-tree", "-m", "merged", "-p", info.mainSHA, "-p", info.topicSHA, tree)
+		run("update-ref", "refs/merde/result", result)
+		info.createdRefs = append(info.createdRefs, createdRef{ref: "refs/merde/result", sha: result})
+		return saveOperation(ctx, cfg, info)
+	}
+
+	var logs bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&logs, nil))
+	job := &botJob{Verb: "merge", Main: "main", Topic: "topic", Ref: "refs/heads/merged"}
+	tick := func(wantCalls int, wantEvent string) {
+		t.Helper()
+		logs.Reset()
+		err := runBotJob(ctx, cfg, log, job)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if calls != wantCalls {
+			t.Errorf("after this tick, deconflict ran %d times; want %d", calls, wantCalls)
+		}
+		if !strings.Contains(logs.String(), `"event":"`+wantEvent+`"`) {
+			t.Errorf("tick logged %s; want a %s event", logs.String(), wantEvent)
+		}
+	}
+
+	tick(1, "resolved")
+	merged := run("rev-parse", "refs/heads/merged")
+	tick(1, "skipped") // nothing moved
+	if got := run("rev-parse", "refs/heads/merged"); got != merged {
+		t.Errorf("skipped tick moved %s from %s to %s", job.Ref, merged, got)
+	}
+
+	// Someone else moved job.Ref, so the last result is no longer there.
+	run("update-ref", "refs/heads/merged", base)
+	tick(2, "resolved")
+
+	// A new topic commit needs a new result.
+	gittest.Commit(t, dir, run, "more topic", map[string]string{"topic.txt": "more\n"})
+	tick(3, "resolved")
+	tick(3, "skipped")
+}
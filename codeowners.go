@@ -0,0 +1,214 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// codeownersPaths lists where CODEOWNERS files live, in the order GitHub looks for them.
+var codeownersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// A codeownersRule assigns owners to the paths matching a pattern.
+type codeownersRule struct {
+	rx     *regexp.Regexp
+	owners []string
+}
+
+// codeowners is a parsed CODEOWNERS file.
+type codeowners []codeownersRule
+
+// parseCodeowners parses a CODEOWNERS file.
+// Lines that cannot be parsed are skipped, as GitHub does.
+func parseCodeowners(data []byte) codeowners {
+	var co codeowners
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := codeownersFields(line)
+		// GitHub does not support negation, so it ignores such lines.
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "!") {
+			continue
+		}
+		rx, err := regexp.Compile(codeownersPattern(fields[0]))
+		if err != nil {
+			continue
+		}
+		co = append(co, codeownersRule{rx: rx, owners: fields[1:]})
+	}
+	return co
+}
+
+// codeownersFields splits a CODEOWNERS line into its whitespace-separated fields, dropping any comment,
+// which starts with a # at the start of a field. A backslash escapes the next character,
+// so that \# starts a pattern rather than a comment and "\ " puts a space in a pattern;
+// the escapes are kept for codeownersPattern.
+func codeownersFields(line string) []string {
+	var fields []string
+	var field strings.Builder
+	flush := func() {
+		if field.Len() > 0 {
+			fields = append(fields, field.String())
+			field.Reset()
+		}
+	}
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && i+1 < len(line):
+			field.WriteString(line[i : i+2])
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			flush()
+		case c == '#' && field.Len() == 0:
+			return fields
+		default:
+			field.WriteByte(c)
+		}
+	}
+	flush()
+	return fields
+}
+
+// codeownersPattern translates a gitignore-style CODEOWNERS pattern to a regular expression.
+// As in gitignore, a pattern with a slash other than at its end is relative to the repository root,
+// and one without matches at any depth; a pattern naming a file or directory also matches everything in the directory.
+// As on GitHub, a pattern whose last component has a wildcard, such as docs/*, matches only at that level.
+func codeownersPattern(pattern string) string {
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	dir := strings.HasSuffix(pattern, "/") && !strings.HasSuffix(pattern, "\\/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	if dir {
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	var rx strings.Builder
+	if anchored {
+		rx.WriteString("^")
+	} else {
+		rx.WriteString("(^|/)")
+	}
+	wild := false // whether the last component so far has a wildcard
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			rx.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case strings.HasPrefix(pattern[i:], "**/"):
+			rx.WriteString("(.*/)?")
+			i += 2
+			wild = false
+		case strings.HasPrefix(pattern[i:], "**"):
+			rx.WriteString(".*")
+			i++
+			wild = true
+		case c == '*':
+			rx.WriteString("[^/]*")
+			wild = true
+		case c == '?':
+			rx.WriteString("[^/]")
+			wild = true
+		case c == '/':
+			rx.WriteString("/")
+			wild = false
+		default:
+			rx.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	switch {
+	case dir:
+		rx.WriteString("/")
+	case wild:
+		rx.WriteString("$")
+	default:
+		rx.WriteString("(/|$)")
+	}
+	return rx.String()
+}
+
+// owners returns the owners of p: those of the last matching rule.
+func (co codeowners) owners(p string) []string {
+	for i := len(co) - 1; i >= 0; i-- {
+		if co[i].rx.MatchString(p) {
+			return co[i].owners
+		}
+	}
+	return nil
+}
+
+// loadCodeowners reads the CODEOWNERS file in commit, if any.
+func loadCodeowners(ctx context.Context, cfg *Config, commit string) codeowners {
+	blobs, err := cfg.Git.PathBlobs(ctx, commit, codeownersPaths)
+	if err != nil {
+		return nil
+	}
+	for _, p := range codeownersPaths {
+		if blobs[p] == "" {
+			continue
+		}
+		data, err := cfg.Git.ReadBlob(ctx, commit, p)
+		if err == nil {
+			return parseCodeowners(data)
+		}
+	}
+	return nil
+}
+
+// manualOwners returns the configured owners whose paths merde must leave for manual resolution.
+func manualOwners(cfg *Config) []string {
+	var owners []string
+	for _, o := range strings.Split(cfg.Get(manualOwnersKey), ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			owners = append(owners, o)
+		}
+	}
+	return owners
+}
+
+// routeByOwner records the CODEOWNERS owners of each conflicting path, taken from main,
+// and leaves the conflicts in paths owned by a manual owner as conflict markers, for those owners to resolve.
+func routeByOwner(ctx context.Context, cfg *Config, info *deconflictRequestInfo) error {
+	co := loadCodeowners(ctx, cfg, info.mainSHA)
+	if co == nil {
+		return nil
+	}
+	info.owners = make(map[string][]string)
+	paths := bothModified(info)
+	for _, dm := range info.deleteModify {
+		paths = append(paths, dm.path)
+	}
+	for _, p := range paths {
+		if owners := co.owners(p); len(owners) > 0 {
+			info.owners[p] = owners
+		}
+	}
+	manual := manualOwners(cfg)
+	if len(manual) == 0 {
+		return nil
+	}
+	ours, theirs := info.mainRef, info.topicRef
+	if info.verb == "merge" {
+		ours, theirs = theirs, ours
+	}
+	for _, p := range unresolved(info) {
+		i := slices.IndexFunc(info.owners[p], func(o string) bool { return slices.Contains(manual, o) })
+		if i < 0 {
+			continue
+		}
+		m, err := readFileMerge(ctx, cfg, info, p)
+		if err != nil {
+			return err
+		}
+		data, err := cfg.Git.MergeMarkers(ctx, m, ours, theirs)
+		if err != nil {
+			return err
+		}
+		err = resolveLocally(ctx, cfg, info, p, data, fmt.Sprintf("left for %s to resolve", info.owners[p][i]))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
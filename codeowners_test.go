@@ -0,0 +1,76 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestCodeownersPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		match   []string
+		noMatch []string
+	}{
+		{"*", []string{"a", "a/b.go"}, nil},
+		{"*.js", []string{"a.js", "src/a.js", "src/lib/a.js"}, []string{"a.jsx", "a.js/b"}},
+		{"docs/*", []string{"docs/getting-started.md"}, []string{"docs/build-app/troubleshooting.md", "src/docs/a.md", "docs"}},
+		{"docs/*.md", []string{"docs/a.md"}, []string{"docs/sub/a.md"}},
+		{"docs/", []string{"docs/a.md", "src/docs/a.md", "docs/sub/a.md"}, []string{"docs", "mydocs/a.md"}},
+		{"/build/logs/", []string{"build/logs/a.log", "build/logs/x/b.log"}, []string{"src/build/logs/a.log"}},
+		{"apps/github", []string{"apps/github", "apps/github/a.go"}, []string{"x/apps/github/a.go", "apps/githubs"}},
+		{"/scripts", []string{"scripts", "scripts/a.sh"}, []string{"src/scripts/a.sh"}},
+		{"scripts", []string{"scripts/a.sh", "src/scripts/a.sh", "scripts"}, []string{"myscripts/a.sh"}},
+		{"**/logs", []string{"logs", "logs/a", "a/b/logs/c"}, []string{"a/blogs/c"}},
+		{"docs/**", []string{"docs/a", "docs/a/b"}, []string{"docs", "x/docs/a"}},
+		{"a/**/b", []string{"a/b", "a/x/b", "a/x/y/b/c"}, []string{"a/xb"}},
+		{"?.go", []string{"a.go", "x/b.go"}, []string{"ab.go"}},
+		{`\#notes`, []string{"#notes", "x/#notes/a"}, []string{"notes"}},
+		{`with\ space.txt`, []string{"with space.txt"}, []string{"with"}},
+		{"a+b.txt", []string{"a+b.txt"}, []string{"aab.txt"}},
+	}
+	for _, tt := range tests {
+		rx := regexp.MustCompile(codeownersPattern(tt.pattern))
+		for _, p := range tt.match {
+			if !rx.MatchString(p) {
+				t.Errorf("%q (%s) does not match %q", tt.pattern, rx, p)
+			}
+		}
+		for _, p := range tt.noMatch {
+			if rx.MatchString(p) {
+				t.Errorf("%q (%s) matches %q", tt.pattern, rx, p)
+			}
+		}
+	}
+}
+
+func TestParseCodeowners(t *testing.T) {
+	co := parseCodeowners([]byte(`# comment
+*       @everyone
+*.go    @gophers # trailing comment
+\#tags  @taggers
+!vendor @nobody
+docs/*  @writers @editors
+`))
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"README", []string{"@everyone"}},
+		{"main.go", []string{"@gophers"}},
+		{"#tags", []string{"@taggers"}},
+		{"vendor/a.c", []string{"@everyone"}},
+		{"docs/index.md", []string{"@writers", "@editors"}},
+		{"docs/guide/a.go", []string{"@gophers"}},
+		{"docs/guide/a.md", []string{"@everyone"}},
+	}
+	for _, tt := range tests {
+		if got := co.owners(tt.path); !slices.Equal(got, tt.want) {
+			t.Errorf("owners(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
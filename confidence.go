@@ -0,0 +1,193 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// A Hunk is one resolved region of a file, as annotated by the server.
+type Hunk struct {
+	Start      int     `json:"start"`      // first line of the resolution in the resolved file, 1-based
+	Lines      int     `json:"lines"`      // number of lines of the resolution
+	Ours       string  `json:"ours"`       // our side of the original conflict
+	Theirs     string  `json:"theirs"`     // their side of the original conflict
+	Confidence float64 `json:"confidence"` // between 0 and 1
+}
+
+// minConfidence returns the configured confidence threshold, or 0 if there is none.
+func minConfidence(cfg *Config) (float64, error) {
+	s := cfg.Get(minConfidenceKey)
+	if s == "" {
+		return 0, nil
+	}
+	threshold, err := strconv.ParseFloat(s, 64)
+	if err != nil || threshold < 0 || threshold > 1 {
+		return 0, fmt.Errorf("invalid %s %q, want a number between 0 and 1", minConfidenceKey, s)
+	}
+	return threshold, nil
+}
+
+// applyConfidenceThreshold replaces resolved hunks whose confidence is below the configured threshold
+// with conflict markers, for manual resolution.
+// For a rebase, it does so in each replayed commit the resolutions are in, and carries the markers forward
+// through the later commits that leave the path alone, replaying those onto the rewritten ones.
+// It moves ref from the resolved commit sha to the rewritten result.
+func applyConfidenceThreshold(ctx context.Context, cfg *Config, info *deconflictRequestInfo, ref, sha string) error {
+	threshold, err := minConfidence(cfg)
+	if err != nil || threshold == 0 {
+		return err
+	}
+	commits := []string{sha}
+	if info.verb != "merge" {
+		chain, err := cfg.Git.CommitsWithParents(ctx, info.mainSHA, sha)
+		if err != nil {
+			return err
+		}
+		commits = commits[:0]
+		for i := len(chain) - 1; i >= 0; i-- {
+			commits = append(commits, chain[i][0])
+		}
+	}
+	// Resolutions name the commit they are in; those that name none, or a commit since rewritten, are in the last.
+	byCommit := make(map[string][]Resolution)
+	for _, res := range info.serverResolutions {
+		c := res.Commit
+		if !slices.Contains(commits, c) {
+			c = sha
+		}
+		byCommit[c] = append(byCommit[c], res)
+	}
+	carried := make(map[string][2]string) // path -> the resolved blob and the blob with markers that replaces it
+	rewritten := ""                       // the rewritten copy of the previous commit, once one is rewritten
+	for _, c := range commits {
+		blobs := make(map[string]string)
+		if len(carried) > 0 {
+			current, err := cfg.Git.PathBlobs(ctx, c, slices.Collect(maps.Keys(carried)))
+			if err != nil {
+				return err
+			}
+			for p, b := range carried {
+				if current[p] != b[0] {
+					delete(carried, p) // c changes p, so its version stands
+					continue
+				}
+				blobs[p] = b[1]
+			}
+		}
+		for _, res := range byCommit[c] {
+			resolved, unresolved, err := unresolveLowConfidence(ctx, cfg, info, c, res, threshold)
+			if err != nil {
+				return err
+			}
+			if unresolved != "" {
+				blobs[res.Path] = unresolved
+				carried[res.Path] = [2]string{resolved, unresolved}
+			}
+		}
+		if len(blobs) == 0 && rewritten == "" {
+			continue
+		}
+		tree, err := cfg.Git.Tree(ctx, c)
+		if len(blobs) > 0 {
+			tree, err = cfg.Git.ReplaceBlobsTree(ctx, c, blobs)
+		}
+		if err != nil {
+			return err
+		}
+		if rewritten == "" {
+			rewritten, err = cfg.Git.CopyCommit(ctx, c, tree, "")
+		} else {
+			rewritten, err = cfg.Git.CopyCommitOnto(ctx, c, tree, rewritten)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if rewritten == "" {
+		return nil
+	}
+	err = cfg.Git.UpdateRef(ctx, ref, rewritten, sha)
+	if err != nil {
+		return err
+	}
+	info.createdRefs = append(info.createdRefs, createdRef{ref: ref, sha: rewritten, old: sha})
+	return nil
+}
+
+// unresolveLowConfidence writes a copy of res's path in commit with its hunks below threshold
+// replaced by conflict markers. It returns the path's resolved blob and the copy,
+// or "", "" if no hunk is below threshold.
+func unresolveLowConfidence(ctx context.Context, cfg *Config, info *deconflictRequestInfo, commit string, res Resolution, threshold float64) (string, string, error) {
+	var low []Hunk
+	for _, h := range res.Hunks {
+		if h.Confidence < threshold {
+			low = append(low, h)
+		}
+	}
+	if len(low) == 0 {
+		return "", "", nil
+	}
+	ours, theirs := info.mainRef, info.topicRef
+	if info.verb == "merge" {
+		ours, theirs = theirs, ours
+	}
+	resolved, err := cfg.Git.PathBlobs(ctx, commit, []string{res.Path})
+	if err != nil {
+		return "", "", err
+	}
+	data, err := cfg.Git.ReadBlob(ctx, commit, res.Path)
+	if err != nil {
+		return "", "", err
+	}
+	data, err = unresolveHunks(data, low, ours, theirs)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", res.Path, err)
+	}
+	blob, err := cfg.Git.WriteBlob(ctx, data)
+	if err != nil {
+		return "", "", err
+	}
+	if info.verb == "merge" {
+		ui.Status("left %d low-confidence hunks in %s for manual resolution", len(low), res.Path)
+	} else {
+		ui.Status("left %d low-confidence hunks in %s, in commit %s, for manual resolution", len(low), res.Path, commit[:12])
+	}
+	return resolved[res.Path], blob, nil
+}
+
+// unresolveHunks replaces each of hunks in data with conflict markers between its two sides.
+func unresolveHunks(data []byte, hunks []Hunk, ours, theirs string) ([]byte, error) {
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	// Replace from the bottom up, so that earlier line numbers stay valid.
+	slices.SortFunc(hunks, func(a, b Hunk) int { return b.Start - a.Start })
+	for _, h := range hunks {
+		start, end := h.Start-1, h.Start-1+h.Lines
+		if start < 0 || end > len(lines) || end < start {
+			return nil, fmt.Errorf("hunk at line %d (%d lines) out of range", h.Start, h.Lines)
+		}
+		markers := new(bytes.Buffer)
+		fmt.Fprintf(markers, "<<<<<<< %s\n", ours)
+		writeLines(markers, h.Ours)
+		fmt.Fprintf(markers, "=======\n")
+		writeLines(markers, h.Theirs)
+		fmt.Fprintf(markers, ">>>>>>> %s\n", theirs)
+		lines = slices.Replace(lines, start, end, markers.Bytes())
+	}
+	return bytes.Join(lines, nil), nil
+}
+
+// writeLines writes s to buf, ensuring that it ends in a newline if non-empty.
+func writeLines(buf *bytes.Buffer, s string) {
+	buf.WriteString(s)
+	if s != "" && s[len(s)-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+}
@@ -22,10 +22,179 @@ const (
 	tokenKey      = "token"
 	serverRootKey = "server"
 	gitExeKey     = "git"
+
+	deleteModifyKey = "delete_modify" // default delete/modify policy: keep-modified, keep-deleted, leave, or ask
+	whitespaceKey   = "whitespace"    // whitespace changes to resolve locally: off, trailing, amount, or all
+
+	generatedKey       = "generated"        // comma-separated globs of generated files, which are never AI-merged
+	generatedPolicyKey = "generated_policy" // what to do with generated files changed on both sides: main, topic, or leave
+	regenerateKey      = "regenerate"       // command that regenerates generated files, e.g. "go mod tidy"
+
+	githubTokenKey = "github_token" // GitHub token used to comment on pull requests
+	githubAPIKey   = "github_api"   // GitHub API root, for GitHub Enterprise
+
+	bitbucketTokenKey       = "bitbucket_token"        // Bitbucket Cloud access token, or username:app-password
+	bitbucketServerKey      = "bitbucket_server"       // root URL of a self-hosted Bitbucket Server
+	bitbucketServerTokenKey = "bitbucket_server_token" // HTTP access token for bitbucket_server
+
+	minConfidenceKey = "min_confidence" // hunks resolved with lower confidence (0 to 1) are left as conflict markers
+
+	refNamespaceKey = "ref_namespace" // prefix for all refs merde creates
+	adoptHookKey    = "adopt_hook"    // command run after merde adopt moves a branch
+	autoAdoptKey    = "auto_adopt"    // adopt each merge or rebase result right away: on or off
+	allowedRefsKey  = "allowed_refs"  // comma-separated ref patterns outside ref_namespace that the server may create
+
+	transportKey = "transport" // how responses stream from the server: multipart, sse, or ws
+
+	maxPartSizeKey     = "max_part_size"     // largest single part accepted from the server, e.g. 1GB
+	maxResponseSizeKey = "max_response_size" // largest total response accepted from the server, e.g. 2GB
+
+	confirmCreditsKey = "confirm_credits" // ask before operations estimated to use more credits than this
+	assumeYesKey      = "assume_yes"      // comma-separated confirmations to accept without asking, or all
+	assumeNoKey       = "assume_no"       // comma-separated confirmations to decline without asking, or all; wins over assume_yes
+
+	manualOwnersKey = "manual_owners" // comma-separated CODEOWNERS owners whose conflicts are left for manual resolution
+
+	teamMemoryKey = "team_memory" // share accepted resolutions with your team through the server: on or off
+
+	telemetryKey = "telemetry" // whether to send anonymous usage metrics: on or off; unset means not yet asked
+
+	maintenanceKey = "maintenance" // pack the loose objects merde writes after each operation: on or off
+	objectsKey     = "objects"     // how to store objects received from the server: loose or pack
+
+	preferredRemoteKey = "preferred_remote" // remote whose default branch is main and whose repository merde reports first
+
+	redactKey = "redact" // comma-separated optional metadata not to send: os, arch, go, git, remotes, refs, language
+
+	auditLogKey     = "audit_log"     // file to append a JSON record of every upload to; unset means no audit log
+	auditForwardKey = "audit_forward" // also send each audit record to this https URL, or udp:// or tcp:// syslog address
+
+	authHeadersKey = "auth_headers" // extra headers for an SSO proxy in front of the server: "Name: value", separated by semicolons
+	sessionKey     = "session"      // cookies, to keep an SSO proxy's session cookies across runs
+	ssoLoginKey    = "sso_login"    // SSO login page, if the proxy does not redirect to a well-known identity provider
+
+	resultFormatKey = "result_format" // how the server sends back its result: pack or patch
+	retentionKey    = "retention"     // what the server keeps of uploaded objects: unset for its default, or ephemeral
+
+	outputKey        = "output"        // how merde reports progress: plain, rich, json, or quiet; unset means rich on a terminal, else plain
+	accessibilityKey = "accessibility" // output for screen readers and dumb terminals, without color or animation: on or off
+	languageKey      = "language"      // language for messages, as a locale such as de_DE; unset means the locale from LC_ALL, LC_MESSAGES, or LANG
+
+	streamPackKey    = "stream_pack"    // upload the pack while it is being built: on or off
+	historyBudgetKey = "history_budget" // most recent commits on each side sent with their trees and blobs; unset means all
+
+	limitRateKey = "limit_rate" // upload bandwidth limit in bytes per second, e.g. 2m
+
+	serverIPKey       = "server_ip"       // IP address to connect to for the server's hostname, instead of resolving it
+	serverResolverKey = "server_resolver" // DNS server to resolve the server's hostname with, e.g. 10.0.0.2 or 10.0.0.2:53
+
+	clientCertKey = "client_cert" // PEM client certificate for mTLS: a file, or !command that prints it
+	clientKeyKey  = "client_key"  // PEM private key for client_cert: a file, or !command that prints it
+
+	serverKeyKey = "server_key" // base64 Ed25519 key the server must sign each response part with, or tofu to pin the first one it presents
+
+	encryptionKey     = "encryption"      // how secrets in the config are encrypted: keyfile or passphrase; unset means not encrypted
+	keyFileKey        = "key_file"        // key file for encryption=keyfile
+	encryptionSaltKey = "encryption_salt" // salt for encryption=passphrase
 )
 
 var defaultValues = map[string]string{
-	serverRootKey: "https://merde.ai",
+	serverRootKey:   "https://merde.ai",
+	deleteModifyKey: askUser,
+	whitespaceKey:   whitespaceTrailing,
+
+	generatedKey:       "go.sum,package-lock.json,yarn.lock,pnpm-lock.yaml,Cargo.lock,*.pb.go",
+	generatedPolicyKey: generatedLeave,
+
+	githubAPIKey: "https://api.github.com",
+
+	refNamespaceKey: defaultRefNamespace,
+
+	transportKey: transportMultipart,
+
+	maxPartSizeKey:     "1GB",
+	maxResponseSizeKey: "2GB",
+
+	objectsKey: objectsLoose,
+
+	resultFormatKey: resultPack,
+}
+
+// keyHelp documents each config key, for merde docs.
+var keyHelp = map[string]string{
+	tokenKey:      "merde.ai API token, set by merde auth",
+	serverRootKey: "merde.ai server URL",
+	gitExeKey:     "path to the git binary (default: git from PATH)",
+
+	deleteModifyKey: "default delete/modify policy: keep-modified, keep-deleted, leave (for manual resolution), or ask (on a terminal; otherwise leave)",
+	whitespaceKey:   "whitespace changes to resolve locally: off, trailing, amount, or all",
+
+	generatedKey:       "comma-separated globs of generated files, which are never AI-merged",
+	generatedPolicyKey: "what to do with generated files changed on both sides: main or topic to take that side's version, or leave to leave them conflicted (default leave)",
+	regenerateKey:      "command that regenerates generated files, e.g. \"go mod tidy\"",
+
+	githubTokenKey: "GitHub token used to comment on and open pull requests",
+	githubAPIKey:   "GitHub API root, for GitHub Enterprise",
+
+	bitbucketTokenKey:       "Bitbucket Cloud access token, or username:app-password, used to comment on and open pull requests",
+	bitbucketServerKey:      "root URL of a self-hosted Bitbucket Server or Data Center, such as https://bitbucket.example.com",
+	bitbucketServerTokenKey: "HTTP access token for bitbucket_server",
+
+	minConfidenceKey: "hunks resolved with lower confidence (0 to 1) are left as conflict markers",
+
+	refNamespaceKey: "prefix for all refs merde creates",
+	adoptHookKey:    "command run after merde adopt moves a branch",
+	autoAdoptKey:    "adopt each merge or rebase result as soon as it is verified, moving the topic branch as merde adopt does, with the same backup: on or off (default off); --adopt turns it on once; --detach refuses to run with it on",
+	allowedRefsKey:  "comma-separated ref patterns outside ref_namespace that the server may create",
+
+	transportKey: "how responses stream from the server: multipart, sse, or ws",
+
+	maxPartSizeKey:     "largest single part accepted from the server, e.g. 1GB",
+	maxResponseSizeKey: "largest total response accepted from the server, e.g. 2GB",
+
+	confirmCreditsKey: "ask before operations estimated to use more credits than this; non-interactive runs fail instead",
+	assumeYesKey:      "comma-separated confirmations to accept without asking, or all, as --yes does for every one: adopt-unexpected, cost, drop-landed, split-history, delete-repo, watch-resolve; delete-repo is only accepted by name",
+	assumeNoKey:       "comma-separated confirmations to decline without asking, or all, as --no does for every one; wins over assume_yes",
+
+	manualOwnersKey: "comma-separated CODEOWNERS owners, such as @org/security, whose conflicts merde leaves as conflict markers",
+
+	teamMemoryKey: "share adopted resolutions with your team through the server, and reuse theirs: on or off (default off)",
+
+	telemetryKey: "whether to send anonymous usage metrics: on or off (default off; merde asks once)",
+
+	preferredRemoteKey: "remote, such as upstream in a fork, whose default branch is main and which merde associates the repository with (default: the topic's upstream, then origin)",
+	objectsKey:         "how to store objects received from the server: loose, or pack to keep the received pack whole, which is faster and more compact in big repositories",
+	maintenanceKey:     "run git maintenance's loose-objects task after each operation, to pack the objects merde writes: on or off (default off)",
+
+	redactKey: "comma-separated optional metadata not to send to the server: os, arch, go, git, remotes, refs, language",
+
+	auditLogKey:     "file to append a JSON record of every upload to; unset means no audit log",
+	auditForwardKey: "also send each audit record to this https URL, or udp:// or tcp:// syslog address",
+
+	authHeadersKey: "extra headers for an SSO proxy in front of the server: \"Name: value\", separated by semicolons",
+	sessionKey:     "cookies, to keep an SSO proxy's session cookies across runs",
+	ssoLoginKey:    "SSO login page; set it if the proxy does not redirect to a well-known identity provider, so that merde recognizes the proxy's challenges",
+
+	resultFormatKey:  "how the server sends back its result: pack, or patch to rebuild it locally from diffs",
+	outputKey:        "how merde reports progress and warnings: plain, rich (color), json (one object per line), or quiet; unset means rich on a terminal, otherwise plain; --output overrides it",
+	accessibilityKey: "output for screen readers and dumb terminals: on for plain status lines without color, animation, or other escape sequences, as with --plain",
+	languageKey:      "language for merde's messages and the server's help, as a locale such as de_DE or a tag such as de; unset means the locale from LC_ALL, LC_MESSAGES, or LANG",
+	retentionKey:     "what the server keeps of uploaded objects: unset for its default, or ephemeral to have it delete them as soon as the conflicts are resolved, as with --ephemeral",
+	streamPackKey:    "upload the pack while it is being built: on or off (default off); a streamed upload cannot tell the server its size in advance, for quotes",
+	historyBudgetKey: "how many of the most recent commits on each side to send with their trees and blobs; older commits are sent without them, unless they touch a conflicting path; unset means all",
+	limitRateKey:     "upload bandwidth limit in bytes per second, e.g. 2m; --limit-rate overrides it",
+
+	serverIPKey:       "IP address to connect to for the server's hostname, instead of resolving it",
+	serverResolverKey: "DNS server to resolve the server's hostname with, e.g. 10.0.0.2 or 10.0.0.2:53",
+
+	clientCertKey: "PEM client certificate for mTLS: a file, or !command that prints it, run without a shell",
+	clientKeyKey:  "PEM private key for client_cert: a file, or !command that prints it",
+
+	serverKeyKey: "base64 Ed25519 public key the server must sign each response part with, or tofu to pin the first key it presents; unset means responses are not checked",
+
+	encryptionKey:     "how secrets in the config are encrypted: keyfile or passphrase, set by merde config encrypt; unset means not encrypted",
+	keyFileKey:        "key file for encryption=keyfile",
+	encryptionSaltKey: "salt for encryption=passphrase",
 }
 
 type Config struct {
@@ -36,6 +205,7 @@ type Config struct {
 	Git        *git.Git `json:"-"`
 	GitVersion string   `json:"-"`
 	path       string
+	plain      map[string]string // decrypted secrets, by encrypted value
 }
 
 func LoadDefault(ctx context.Context) (*Config, error) {
@@ -47,28 +217,35 @@ func LoadDefault(ctx context.Context) (*Config, error) {
 }
 
 func Load(ctx context.Context, path string) (*Config, error) {
-	data, err := os.ReadFile(path)
-	if os.IsNotExist(err) {
-		return &Config{Values: make(map[string]string), path: path}, nil
-	}
+	cfg, err := loadValues(path)
 	if err != nil {
 		return nil, err
 	}
-	var values map[string]string
-	err = json.Unmarshal(data, &values)
+	gg, err := git.NewGit(ctx, cfg.Get(gitExeKey))
 	if err != nil {
 		return nil, err
 	}
-	cfg := &Config{
-		Values: values,
-		path:   path,
+	observeGit(gg)
+	cfg.Git = gg
+	cfg.GitVersion, _ = gg.Version(ctx) // best effort
+	return cfg, nil
+}
+
+// loadValues loads the config stored at path, without looking for a git repository.
+func loadValues(path string) (*Config, error) {
+	cfg := &Config{Values: make(map[string]string), path: path, plain: make(map[string]string)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
 	}
-	gg, err := git.NewGit(ctx, cfg.Get(gitExeKey))
+	err = json.Unmarshal(data, &cfg.Values)
 	if err != nil {
 		return nil, err
 	}
-	cfg.Git = gg
-	cfg.GitVersion, _ = gg.Version(ctx) // best effort
+	cfg.decryptSecrets()
 	return cfg, nil
 }
 
@@ -93,6 +270,18 @@ func (c *Config) Update(pairs ...string) error {
 		key, value := pairs[i], pairs[i+1]
 		c.Values[key] = value
 	}
+	for i := 0; i < len(pairs); i += 2 {
+		key := pairs[i]
+		plain := c.Values[key]
+		value, err := c.encrypt(key, plain)
+		if err != nil {
+			return fmt.Errorf("encrypting %s: %w", key, err)
+		}
+		c.Values[key] = value
+		if value != plain {
+			c.plain[value] = plain
+		}
+	}
 	err := os.MkdirAll(filepath.Dir(c.path), 0o700)
 	if err != nil {
 		return err
@@ -109,6 +298,29 @@ func (c *Config) Update(pairs ...string) error {
 }
 
 // Get reads the value for key from c.Values, or from an environment variable override.
+// Encrypted values are decrypted when the config is loaded; one that could not be is "".
 func (c *Config) Get(key string) string {
-	return cmp.Or(os.Getenv("MERDE_"+strings.ToUpper(key)), c.Values[key], defaultValues[key])
+	value := cmp.Or(os.Getenv(configEnv(key)), c.Values[key], defaultValues[key])
+	if strings.HasPrefix(value, encryptedPrefix) {
+		return c.plain[value]
+	}
+	return value
+}
+
+// Source reports where Get finds the value for key: "env", "config", "default", or "" if key is unset.
+func (c *Config) Source(key string) string {
+	switch {
+	case os.Getenv(configEnv(key)) != "":
+		return "env"
+	case c.Values[key] != "":
+		return "config"
+	case defaultValues[key] != "":
+		return "default"
+	}
+	return ""
+}
+
+// configEnv returns the environment variable that overrides key.
+func configEnv(key string) string {
+	return "MERDE_" + strings.ToUpper(key)
 }
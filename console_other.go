@@ -0,0 +1,41 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// enableANSI reports whether escape sequences can be used on stdout.
+func enableANSI() bool {
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0 && os.Getenv("TERM") != "dumb"
+}
+
+// isTerminal reports whether f is a terminal.
+// /dev/null is a character device too, but one nobody types into, as when CI runs merde </dev/null.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(fi, null)
+}
+
+// disableEcho stops the terminal on stdin from echoing what the user types,
+// and returns a function that restores it.
+func disableEcho() func() {
+	stty := func(arg string) {
+		cmd := exec.Command("stty", arg)
+		cmd.Stdin = os.Stdin
+		cmd.Run() // best effort: stdin may not be a terminal
+	}
+	stty("-echo")
+	return func() { stty("echo") }
+}
@@ -0,0 +1,49 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+const enableVirtualTerminalProcessing = 0x4
+
+var setConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// enableANSI turns on escape sequence processing for stdout, which older Windows consoles
+// leave off, and reports whether escape sequences can be used.
+func enableANSI() bool {
+	h := syscall.Handle(os.Stdout.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(h, &mode); err != nil {
+		return false // not a console
+	}
+	if mode&enableVirtualTerminalProcessing != 0 {
+		return true
+	}
+	ok, _, _ := setConsoleMode.Call(uintptr(h), uintptr(mode|enableVirtualTerminalProcessing))
+	return ok != 0
+}
+
+// isTerminal reports whether f is a console. NUL, which nobody types into, is not.
+func isTerminal(f *os.File) bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(f.Fd()), &mode) == nil
+}
+
+const enableEchoInput = 0x4
+
+// disableEcho stops the console on stdin from echoing what the user types,
+// and returns a function that restores it.
+func disableEcho() func() {
+	h := syscall.Handle(os.Stdin.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(h, &mode); err != nil {
+		return func() {} // not a console
+	}
+	setConsoleMode.Call(uintptr(h), uintptr(mode&^enableEchoInput))
+	return func() { setConsoleMode.Call(uintptr(h), uintptr(mode)) }
+}
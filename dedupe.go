@@ -0,0 +1,220 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"slices"
+)
+
+// A duplicateConflict is a conflicting path whose conflict is identical to that of another path, its leader.
+// The server resolves only the leader, and the client applies the same resolution to the duplicate.
+type duplicateConflict struct {
+	path   string
+	leader string
+}
+
+// String formats d for the server as "leader:path".
+func (d *duplicateConflict) String() string {
+	return d.leader + ":" + d.path
+}
+
+// A repeatedHunk is one occurrence of a conflict hunk that recurs as a rebase replays the topic's commits,
+// as when several commits edit lines that main also changed.
+// The server resolves the first occurrence of each hunk and reuses its resolution for the rest.
+type repeatedHunk struct {
+	fingerprint string // identifies the hunk by its contents
+	commit      string // topic commit whose replay conflicts
+	path        string
+}
+
+// String formats h for the server as "fingerprint:commit:path".
+func (h *repeatedHunk) String() string {
+	return h.fingerprint + ":" + h.commit + ":" + h.path
+}
+
+// findDuplicateConflicts finds conflicts that repeat others, so that each unique conflict goes to the server once.
+// A merge resolves the tips once, so it looks for paths whose base, ours, and theirs contents are all identical,
+// as happens with vendored or copied files. A rebase resolves each replayed commit in turn,
+// so it looks for conflict hunks that recur across the commits.
+func findDuplicateConflicts(ctx context.Context, cfg *Config, info *deconflictRequestInfo) error {
+	if info.verb == "rebase" {
+		return findRepeatedHunks(ctx, cfg, info)
+	}
+	leaders := make(map[[sha256.Size]byte]string)
+	for _, p := range unresolved(info) {
+		m, err := readFileMerge(ctx, cfg, info, p)
+		if err != nil {
+			return err
+		}
+		// Unlike conflictFingerprint, the sides' order matters: the resolution is copied verbatim.
+		h := sha256.New()
+		for _, side := range [][]byte{m.Base, m.Ours, m.Theirs} {
+			binary.Write(h, binary.BigEndian, uint64(len(side)))
+			h.Write(side)
+		}
+		key := [sha256.Size]byte(h.Sum(nil))
+		leader, ok := leaders[key]
+		if !ok {
+			leaders[key] = p
+			continue
+		}
+		info.duplicates = append(info.duplicates, &duplicateConflict{path: p, leader: leader})
+	}
+	if len(info.duplicates) > 0 {
+		ui.Status("%d conflicting paths repeat other conflicts; each unique conflict will be resolved once", len(info.duplicates))
+	}
+	return nil
+}
+
+// findRepeatedHunks replays the topic's commits in memory, as the server will, and records every occurrence
+// of each conflict hunk that occurs more than once, in replay order.
+// The outcome of a conflicted step is unknown until the server resolves it, so the replay carries on
+// with the conflicted paths as they were before the step; a later commit that edits the same lines
+// then meets the same conflict again, as it would if the step were resolved in main's favor.
+func findRepeatedHunks(ctx context.Context, cfg *Config, info *deconflictRequestInfo) error {
+	if len(info.plan) > 0 {
+		return nil // the plan replays different commits
+	}
+	commits, err := cfg.Git.CommitsInRange(ctx, info.baseSHA+".."+info.topicSHA)
+	if err != nil {
+		return err
+	}
+	if info.dropLanded {
+		commits = slices.DeleteFunc(commits, func(c string) bool { return slices.Contains(info.landed, c) })
+	}
+	var found []*repeatedHunk
+	count := make(map[string]int)
+	tip := info.mainSHA
+	for _, c := range commits {
+		onto, pick, err := pickCommits(ctx, cfg, c, tip)
+		if err != nil {
+			return err
+		}
+		tree, conflicts, err := cfg.Git.MergeTree(ctx, onto, pick)
+		if err != nil {
+			return err
+		}
+		if len(conflicts) > 0 {
+			present, err := cfg.Git.PathBlobs(ctx, tree, conflicts)
+			if err != nil {
+				return err
+			}
+			for _, p := range conflicts {
+				if _, ok := present[p]; !ok {
+					continue // deleted on one side: no hunks
+				}
+				merged, err := cfg.Git.ReadBlob(ctx, tree, p)
+				if err != nil {
+					return err
+				}
+				for _, hunk := range conflictHunks(merged) {
+					fp := fmt.Sprintf("%x", sha256.Sum256(hunk))
+					found = append(found, &repeatedHunk{fingerprint: fp, commit: c, path: p})
+					count[fp]++
+				}
+			}
+			before, err := cfg.Git.PathBlobs(ctx, onto, conflicts)
+			if err != nil {
+				return err
+			}
+			tree, err = cfg.Git.ReplaceBlobsTree(ctx, tree, before)
+			if err != nil {
+				return err
+			}
+		}
+		tip, err = cfg.Git.CommitTree(ctx, tree, "merde: "+c, tip)
+		if err != nil {
+			return err
+		}
+	}
+	repeats := 0
+	for _, h := range found {
+		if count[h.fingerprint] > 1 {
+			info.repeatedHunks = append(info.repeatedHunks, h)
+		}
+	}
+	for _, n := range count {
+		repeats += max(n-1, 0)
+	}
+	if repeats > 0 {
+		ui.Status("%d conflicts recur across the replayed commits; each unique conflict will be resolved once", repeats)
+	}
+	return nil
+}
+
+// conflictHunks returns each conflict hunk in data, which has conflict markers,
+// without the labels on its markers, which name the commits rather than the conflict.
+func conflictHunks(data []byte) [][]byte {
+	var hunks [][]byte
+	var hunk []byte
+	inside := false
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		switch {
+		case bytes.HasPrefix(line, []byte("<<<<<<< ")):
+			inside = true
+			hunk = []byte("<<<<<<<\n")
+		case !inside:
+		case bytes.HasPrefix(line, []byte("||||||| ")):
+			hunk = append(hunk, "|||||||\n"...)
+		case bytes.HasPrefix(line, []byte(">>>>>>> ")):
+			hunks = append(hunks, append(hunk, ">>>>>>>\n"...))
+			inside = false
+		default:
+			hunk = append(hunk, line...)
+		}
+	}
+	return hunks
+}
+
+// applyDuplicateResolutions gives each duplicate conflict the resolution of its leader in the resolved commit sha,
+// unless the server already did, and moves ref to the rewritten commit.
+func applyDuplicateResolutions(ctx context.Context, cfg *Config, info *deconflictRequestInfo, ref, sha string) error {
+	if len(info.duplicates) == 0 {
+		return nil
+	}
+	paths := make([]string, 0, 2*len(info.duplicates))
+	for _, d := range info.duplicates {
+		paths = append(paths, d.path, d.leader)
+	}
+	blobs, err := cfg.Git.PathBlobs(ctx, sha, paths)
+	if err != nil {
+		return err
+	}
+	replace := make(map[string]string)
+	for _, d := range info.duplicates {
+		leader, ok := blobs[d.leader]
+		if ok && blobs[d.path] != leader {
+			replace[d.path] = leader
+		}
+		if !slices.ContainsFunc(info.serverResolutions, func(r Resolution) bool { return r.Path == d.path }) {
+			i := slices.IndexFunc(info.serverResolutions, func(r Resolution) bool { return r.Path == d.leader })
+			if i >= 0 {
+				r := info.serverResolutions[i]
+				r.Path = d.path
+				r.Explanation = fmt.Sprintf("same conflict as %s: %s", d.leader, r.Explanation)
+				info.serverResolutions = append(info.serverResolutions, r)
+			}
+		}
+	}
+	if len(replace) == 0 {
+		return nil
+	}
+	rewritten, err := cfg.Git.ReplaceBlobs(ctx, sha, replace)
+	if err != nil {
+		return err
+	}
+	err = cfg.Git.UpdateRef(ctx, ref, rewritten, sha)
+	if err != nil {
+		return err
+	}
+	info.createdRefs = append(info.createdRefs, createdRef{ref: ref, sha: rewritten, old: sha})
+	ui.Status("applied %d repeated resolutions", len(replace))
+	return nil
+}
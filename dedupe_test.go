@@ -0,0 +1,52 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestConflictHunks(t *testing.T) {
+	data := "a\n" +
+		"<<<<<<< 1f05b44 (one commit)\n" +
+		"main\n" +
+		"=======\n" +
+		"topic\n" +
+		">>>>>>> 2f80b87 (another)\n" +
+		"b\n" +
+		"<<<<<<< ours\n" +
+		"x\n" +
+		"||||||| base\n" +
+		"y\n" +
+		"=======\n" +
+		">>>>>>> theirs\n"
+	got := conflictHunks([]byte(data))
+	want := []string{
+		"<<<<<<<\nmain\n=======\ntopic\n>>>>>>>\n",
+		"<<<<<<<\nx\n|||||||\ny\n=======\n>>>>>>>\n",
+	}
+	if !slices.Equal(stringsOfBytes(got), want) {
+		t.Errorf("conflictHunks = %q; want %q", got, want)
+	}
+
+	// The same conflict between other commits is the same hunk.
+	other := "<<<<<<< abc\nmain\n=======\ntopic\n>>>>>>> def\n"
+	if h := conflictHunks([]byte(other)); len(h) != 1 || string(h[0]) != want[0] {
+		t.Errorf("conflictHunks(%q) = %q; want [%q]", other, h, want[0])
+	}
+
+	if h := conflictHunks([]byte("no conflicts\n")); len(h) != 0 {
+		t.Errorf("conflictHunks without markers = %q; want none", h)
+	}
+}
+
+func stringsOfBytes(bs [][]byte) []string {
+	var ss []string
+	for _, b := range bs {
+		ss = append(ss, string(b))
+	}
+	return ss
+}
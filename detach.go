@@ -0,0 +1,353 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// jobEnv names the job a background merde process runs, for --detach.
+const jobEnv = "MERDE_JOB"
+
+// errDetached reports that the operation continues in the background.
+var errDetached = errors.New("detached")
+
+// A detachedJob is a merge or rebase handed to a background process by --detach.
+type detachedJob struct {
+	ID           string            `json:"id"`
+	Args         []string          `json:"args"` // the background process's command line
+	PID          int               `json:"pid"`
+	Started      time.Time         `json:"started"`
+	DeleteModify map[string]string `json:"delete_modify,omitempty"` // delete/modify answers given before detaching
+	DropLanded   *bool             `json:"drop_landed,omitempty"`   // landed-commits answer given before detaching
+	Plan         []string          `json:"plan,omitempty"`          // rebase plan edited before detaching, as action:commit
+	Rewords      []string          `json:"rewords,omitempty"`       // new messages of the plan's reworded commits, as commit:message
+
+	Done      bool   `json:"done"`
+	Operation string `json:"operation,omitempty"` // ID of the recorded operation, once done
+	Error     string `json:"error,omitempty"`
+}
+
+// detached is the job this process runs in the background, or nil.
+var detached *detachedJob
+
+// jobPaths returns the record and log file of job id.
+func jobPaths(ctx context.Context, cfg *Config, id string) (string, string, error) {
+	dir, err := merdeDir(ctx, cfg)
+	if err != nil {
+		return "", "", err
+	}
+	base := filepath.Join(dir, "jobs", id)
+	return base + ".json", base + ".log", nil
+}
+
+func loadJob(ctx context.Context, cfg *Config, id string) (*detachedJob, error) {
+	if id == "" {
+		ids, err := jobIDs(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return nil, fmt.Errorf("no background jobs; start one with merde merge --detach")
+		}
+		id = ids[len(ids)-1]
+	}
+	path, _, err := jobPaths(ctx, cfg, id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no background job %s", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	job := new(detachedJob)
+	err = json.Unmarshal(data, job)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func saveJob(ctx context.Context, cfg *Config, job *detachedJob) error {
+	path, _, err := jobPaths(ctx, cfg, job.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write then rename, so that merde attach never reads half a record.
+	err = os.WriteFile(path+".tmp", data, 0o644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// jobIDs returns the IDs of all background jobs, oldest first.
+func jobIDs(ctx context.Context, cfg *Config) ([]string, error) {
+	dir, err := merdeDir(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, "jobs", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, m := range matches {
+		ids = append(ids, strings.TrimSuffix(filepath.Base(m), ".json"))
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// checkDetach rejects --detach with options, or settings, that need the user during or after the upload.
+func checkDetach(cfg *Config) error {
+	if !flagDetach {
+		return nil
+	}
+	switch {
+	case !flagAdopt && cfg.Get(autoAdoptKey) == "on":
+		return fmt.Errorf("--detach cannot be used with %s=on: the background job would move your branch; run without --detach, or with MERDE_AUTO_ADOPT=off", autoAdoptKey)
+	case flagEdit:
+		return usageErrorf("--detach and --edit cannot be combined")
+	case flagAdopt:
+		return usageErrorf("--detach and --adopt cannot be combined: the background job would move your branch")
+	case flagNoCommit, flagMergeContinue, flagRebaseContinue:
+		return usageErrorf("--detach cannot be combined with --no-commit or --continue: the background job would change your working tree")
+	case flagSplitCommits:
+		return usageErrorf("--detach and --split-commits cannot be combined: the split needs your approval")
+	}
+	return nil
+}
+
+// startDetachedJob hands the rest of the operation described by info to a background merde process,
+// along with the answers the user has given so far.
+// The pack is cached, so the background process reuses it rather than building it again.
+func startDetachedJob(ctx context.Context, cfg *Config, info *deconflictRequestInfo) error {
+	err := cachePack(ctx, cfg, info)
+	if err != nil {
+		return err
+	}
+	job := &detachedJob{
+		ID:      time.Now().UTC().Format("20060102-150405") + "-" + info.topicSHA[:8],
+		Started: time.Now(),
+	}
+	for _, dm := range info.deleteModify {
+		if job.DeleteModify == nil {
+			job.DeleteModify = make(map[string]string)
+		}
+		job.DeleteModify[dm.path] = dm.policy
+	}
+	if len(info.landed) > 0 {
+		job.DropLanded = &info.dropLanded
+	}
+	job.Plan = stringsOf(info.plan)
+	job.Rewords = rewords(info.plan)
+	// The background process waits for this one to release the repository lock.
+	job.Args = append([]string{"--lock-wait", "10m"}, withoutDetach(os.Args[1:])...)
+
+	recordPath, logPath, err := jobPaths(ctx, cfg, job.ID)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(recordPath), 0o755)
+	if err != nil {
+		return err
+	}
+	log, err := os.Create(logPath)
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(exe, job.Args...)
+	cmd.Env = append(os.Environ(), jobEnv+"="+job.ID)
+	cmd.Stdout = log
+	cmd.Stderr = log
+	cmd.SysProcAttr = detachedProcAttr()
+	// Record the job before starting it, so that the background process can find it.
+	err = saveJob(ctx, cfg, job)
+	if err != nil {
+		return err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return err
+	}
+	job.PID = cmd.Process.Pid
+	cmd.Process.Release()
+	err = saveJob(ctx, cfg, job)
+	if err != nil {
+		return err
+	}
+	ui.Status("continuing in the background as job %s; watch it with: merde attach %s", job.ID, job.ID)
+	return nil
+}
+
+// withoutDetach returns args without the --detach flag, and without -C,
+// which the background process's working directory already reflects.
+func withoutDetach(args []string) []string {
+	var out []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		name, _, hasValue := strings.Cut(strings.TrimLeft(a, "-"), "=")
+		switch {
+		case !strings.HasPrefix(a, "-"):
+		case name == "detach":
+			continue
+		case name == "C":
+			if !hasValue {
+				i++ // skip the path too
+			}
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// startJob loads the job this process runs in the background, if it is one.
+// The job cannot ask the user anything; it uses the answers recorded before detaching.
+func startJob(ctx context.Context, cfg *Config) error {
+	id := os.Getenv(jobEnv)
+	if id == "" {
+		return nil
+	}
+	os.Unsetenv(jobEnv) // not for the processes this one starts
+	job, err := loadJob(ctx, cfg, id)
+	if err != nil {
+		return err
+	}
+	detached = job
+	interactive = false
+	return nil
+}
+
+// finishJob records the outcome of the background job, for merde attach.
+func finishJob(ctx context.Context, cfg *Config, op *operation, runErr error) {
+	if detached == nil {
+		return
+	}
+	detached.Done = true
+	if op != nil {
+		detached.Operation = op.ID
+	}
+	if runErr != nil {
+		detached.Error = runErr.Error()
+	}
+	err := saveJob(ctx, cfg, detached)
+	if err != nil {
+		ui.Error(fmt.Errorf("recording the job's outcome: %w", err))
+	}
+}
+
+// deleteModifyAnswer returns the delete/modify policy the user chose for p before detaching, if any.
+func (job *detachedJob) deleteModifyAnswer(p string) (string, bool) {
+	if job == nil {
+		return "", false
+	}
+	policy, ok := job.DeleteModify[p]
+	return policy, ok
+}
+
+// planAnswer returns the rebase plan the user edited before detaching, if any.
+func (job *detachedJob) planAnswer() []*planStep {
+	if job == nil {
+		return nil
+	}
+	messages := make(map[string]string)
+	for _, rw := range job.Rewords {
+		commit, message, _ := strings.Cut(rw, ":")
+		messages[commit] = message
+	}
+	var steps []*planStep
+	for _, s := range job.Plan {
+		action, commit, _ := strings.Cut(s, ":")
+		steps = append(steps, &planStep{action: action, commit: commit, message: messages[commit]})
+	}
+	return steps
+}
+
+func doAttach(ctx context.Context, args []string) error {
+	if len(args) > 1 {
+		return usageErrorf("merde attach takes at most 1 argument")
+	}
+	cfg, err := LoadDefault(ctx)
+	if err != nil {
+		return err
+	}
+	var id string
+	if len(args) == 1 {
+		id = args[0]
+	}
+	job, err := loadJob(ctx, cfg, id)
+	if err != nil {
+		return err
+	}
+	_, logPath, err := jobPaths(ctx, cfg, job.ID)
+	if err != nil {
+		return err
+	}
+	log, err := os.Open(logPath)
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+	ui.Status("attached to job %s (started %s)", job.ID, job.Started.Format(time.DateTime))
+	for {
+		_, err = io.Copy(ui.Output(), log)
+		if err != nil {
+			return err
+		}
+		if job.Done {
+			break
+		}
+		if !processAlive(job.PID) {
+			// It may have finished between reads; otherwise it died without recording why.
+			job, err = loadJob(ctx, cfg, job.ID)
+			if err != nil {
+				return err
+			}
+			if !job.Done {
+				io.Copy(ui.Output(), log)
+				return fmt.Errorf("job %s stopped without finishing; rerun the operation, or merde retry to reuse its pack", job.ID)
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+		}
+		job, err = loadJob(ctx, cfg, job.ID)
+		if err != nil {
+			return err
+		}
+	}
+	if job.Error != "" {
+		return fmt.Errorf("job %s failed: %s", job.ID, job.Error)
+	}
+	ui.Status("job %s finished: operation %s", job.ID, job.Operation)
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// dialContext dials all connections, to the server and elsewhere.
+// configureDial replaces it to honor server_ip and server_resolver.
+var dialContext = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+
+// configureDial pins the server's hostname to server_ip, or resolves it with server_resolver,
+// for split-horizon DNS and air-gapped mirrors. Other hosts resolve as usual.
+// TLS still verifies the server's certificate against its hostname.
+func configureDial(cfg *Config, server *url.URL, transport *http.Transport) error {
+	pin, resolver := cfg.Get(serverIPKey), cfg.Get(serverResolverKey)
+	if pin == "" && resolver == "" {
+		return nil
+	}
+	if pin != "" && net.ParseIP(pin) == nil {
+		return fmt.Errorf("invalid %s %q: want an IP address", serverIPKey, pin)
+	}
+	d := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if resolver != "" {
+		if _, _, err := net.SplitHostPort(resolver); err != nil {
+			resolver = net.JoinHostPort(resolver, "53")
+		}
+		d.Resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var rd net.Dialer
+				return rd.DialContext(ctx, network, resolver)
+			},
+		}
+	}
+	base := dialContext
+	dialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || host != server.Hostname() {
+			return base(ctx, network, addr)
+		}
+		if pin != "" {
+			addr = net.JoinHostPort(pin, port)
+		}
+		return d.DialContext(ctx, network, addr)
+	}
+	transport.DialContext = dialContext
+	return nil
+}
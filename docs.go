@@ -0,0 +1,208 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+)
+
+// A docPage is the documentation of one command, in a form both generators share.
+type docPage struct {
+	cmd  *ffcli.Command
+	path string // full command name, e.g. "merde hook install"
+	subs []*docPage
+}
+
+// name returns the page's file name, without extension, e.g. "merde-hook-install".
+func (p *docPage) name() string {
+	return strings.ReplaceAll(p.path, " ", "-")
+}
+
+// description returns the page's description paragraphs.
+func (p *docPage) description() string {
+	return strings.TrimSpace(p.cmd.ShortHelp + "\n\n" + p.cmd.LongHelp)
+}
+
+// flags returns the page's flags, sorted by name.
+func (p *docPage) flags() []*flag.Flag {
+	var flags []*flag.Flag
+	if p.cmd.FlagSet != nil {
+		p.cmd.FlagSet.VisitAll(func(f *flag.Flag) {
+			flags = append(flags, f)
+		})
+	}
+	return flags
+}
+
+// docPages returns the pages for cmd and all its subcommands, depth first.
+func docPages(cmd *ffcli.Command, parent string) []*docPage {
+	p := &docPage{cmd: cmd, path: strings.TrimSpace(parent + " " + cmd.Name)}
+	pages := []*docPage{p}
+	for _, sub := range cmd.Subcommands {
+		subPages := docPages(sub, p.path)
+		p.subs = append(p.subs, subPages[0])
+		pages = append(pages, subPages...)
+	}
+	return pages
+}
+
+// configKeys returns the documented config keys, sorted.
+func configKeys() []string {
+	return slices.Sorted(maps.Keys(keyHelp))
+}
+
+// markdownPage renders p as markdown.
+func markdownPage(p *docPage) []byte {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "# %s\n\n", p.path)
+	fmt.Fprintf(buf, "```\n%s\n```\n\n", p.cmd.ShortUsage)
+	fmt.Fprintf(buf, "%s\n\n", p.description())
+	if flags := p.flags(); len(flags) > 0 {
+		fmt.Fprintf(buf, "## Flags\n\n")
+		for _, f := range flags {
+			arg, usage := flag.UnquoteUsage(f)
+			fmt.Fprintf(buf, "- `-%s", f.Name)
+			if arg != "" {
+				fmt.Fprintf(buf, " %s", arg)
+			}
+			fmt.Fprintf(buf, "`: %s", usage)
+			if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" {
+				fmt.Fprintf(buf, " (default %s)", f.DefValue)
+			}
+			fmt.Fprintf(buf, "\n")
+		}
+		fmt.Fprintf(buf, "\n")
+	}
+	if len(p.subs) > 0 {
+		fmt.Fprintf(buf, "## Subcommands\n\n")
+		for _, sub := range p.subs {
+			fmt.Fprintf(buf, "- [%s](%s.md): %s\n", sub.path, sub.name(), sub.cmd.ShortHelp)
+		}
+		fmt.Fprintf(buf, "\n")
+	}
+	if p.cmd == configCommand {
+		fmt.Fprintf(buf, "## Keys\n\n")
+		fmt.Fprintf(buf, "Each key can also be set with an environment variable, which takes precedence.\n\n")
+		fmt.Fprintf(buf, "| Key | Environment | Default | Description |\n|---|---|---|---|\n")
+		for _, key := range configKeys() {
+			fmt.Fprintf(buf, "| `%s` | `%s` | %s | %s |\n", key, configEnv(key), markdownCode(defaultValues[key]), keyHelp[key])
+		}
+		fmt.Fprintf(buf, "\n")
+	}
+	return buf.Bytes()
+}
+
+// markdownCode formats s as inline code, or as nothing if it is empty.
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + s + "`"
+}
+
+// roffEscape escapes s for use in a man page.
+func roffEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	s = strings.ReplaceAll(s, "-", `\-`)
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		// Lines starting with a control character would be read as requests.
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			line = `\&` + line
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// manDate returns the date to put in man pages.
+// It honors SOURCE_DATE_EPOCH, so that packagers can build them reproducibly.
+func manDate() string {
+	t := time.Now()
+	if sec, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64); err == nil {
+		t = time.Unix(sec, 0)
+	}
+	return t.UTC().Format("2006-01-02")
+}
+
+// manPage renders p as a section 1 man page.
+func manPage(p *docPage) []byte {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, ".TH %q 1 %q \"merde %s\" \"merde Manual\"\n", strings.ToUpper(p.name()), manDate(), version)
+	fmt.Fprintf(buf, ".SH NAME\n%s \\- %s\n", roffEscape(p.name()), roffEscape(p.cmd.ShortHelp))
+	fmt.Fprintf(buf, ".SH SYNOPSIS\n.B %s\n", roffEscape(p.cmd.ShortUsage))
+	fmt.Fprintf(buf, ".SH DESCRIPTION\n")
+	for _, para := range strings.Split(p.description(), "\n\n") {
+		fmt.Fprintf(buf, ".PP\n.nf\n%s\n.fi\n", roffEscape(para))
+	}
+	if flags := p.flags(); len(flags) > 0 {
+		fmt.Fprintf(buf, ".SH OPTIONS\n")
+		for _, f := range flags {
+			arg, usage := flag.UnquoteUsage(f)
+			fmt.Fprintf(buf, ".TP\n.B \\-%s", roffEscape(f.Name))
+			if arg != "" {
+				fmt.Fprintf(buf, " \\fI%s\\fR", roffEscape(arg))
+			}
+			fmt.Fprintf(buf, "\n%s\n", roffEscape(usage))
+		}
+	}
+	if p.cmd == configCommand {
+		fmt.Fprintf(buf, ".SH KEYS\nEach key can also be set with an environment variable, which takes precedence.\n")
+		for _, key := range configKeys() {
+			fmt.Fprintf(buf, ".TP\n.B %s\n%s\n", roffEscape(key), roffEscape(keyHelp[key]))
+			fmt.Fprintf(buf, ".br\nEnvironment: %s\n", roffEscape(configEnv(key)))
+			if def := defaultValues[key]; def != "" {
+				fmt.Fprintf(buf, ".br\nDefault: %s\n", roffEscape(def))
+			}
+		}
+	}
+	if len(p.subs) > 0 {
+		var refs []string
+		for _, sub := range p.subs {
+			refs = append(refs, fmt.Sprintf(".BR %s (1)", roffEscape(sub.name())))
+		}
+		fmt.Fprintf(buf, ".SH SEE ALSO\n%s\n", strings.Join(refs, ",\n"))
+	}
+	return buf.Bytes()
+}
+
+func doDocs(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return usageErrorf("merde docs takes exactly one output directory")
+	}
+	if flagDocsMan == flagDocsMarkdown {
+		return usageErrorf("merde docs needs exactly one of --man and --markdown")
+	}
+	dir := args[0]
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return err
+	}
+	pages := docPages(rootCommand, "")
+	for _, p := range pages {
+		name, data := p.name()+".md", markdownPage(p)
+		if flagDocsMan {
+			name, data = p.name()+".1", manPage(p)
+		}
+		err := os.WriteFile(filepath.Join(dir, name), data, 0o644)
+		if err != nil {
+			return err
+		}
+	}
+	ui.Status("wrote %d pages to %s", len(pages), dir)
+	return nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// A drift is how far a branch has diverged from main.
+type drift struct {
+	Branch      string   `json:"branch"`
+	Behind      int      `json:"behind"` // commits on main missing from the branch
+	Ahead       int      `json:"ahead"`  // commits on the branch missing from main
+	Conflicts   int      `json:"conflicts"`
+	Conflicting []string `json:"conflicting"` // paths that would conflict in a merge with main
+	Error       string   `json:"error,omitempty"`
+}
+
+// defaultMain returns the repository's main branch:
+// the preferred remote's default branch, main, or master, whichever exists first.
+func defaultMain(ctx context.Context, cfg *Config) (string, error) {
+	remoteHead := cmp.Or(preferredRemote(cfg), "origin") + "/HEAD"
+	for _, ref := range []string{remoteHead, "main", "master"} {
+		if _, err := cfg.Git.ResolveRef(ctx, ref); err == nil {
+			return ref, nil
+		}
+	}
+	return "", fmt.Errorf("cannot find the main branch among %s, main, and master", remoteHead)
+}
+
+// measureDrift measures how far branch has diverged from mainRef.
+func measureDrift(ctx context.Context, cfg *Config, mainRef, branch string) *drift {
+	d := &drift{Branch: branch}
+	var err error
+	d.Behind, err = cfg.Git.CountCommits(ctx, branch, mainRef)
+	if err == nil {
+		d.Ahead, err = cfg.Git.CountCommits(ctx, mainRef, branch)
+	}
+	if err == nil {
+		_, d.Conflicting, err = cfg.Git.MergeTree(ctx, mainRef, branch)
+		d.Conflicts = len(d.Conflicting)
+	}
+	if err != nil {
+		d.Error = err.Error()
+	}
+	return d
+}
+
+func doDrift(ctx context.Context, args []string) error {
+	cfg, err := LoadDefault(ctx)
+	if err != nil {
+		return err
+	}
+	mainRef := flagDriftMain
+	if mainRef == "" {
+		mainRef, err = defaultMain(ctx, cfg)
+		if err != nil {
+			return err
+		}
+	}
+	mainSHA, err := cfg.Git.ResolveRef(ctx, mainRef)
+	if err != nil {
+		return err
+	}
+	branches := args
+	if len(branches) == 0 {
+		all, err := cfg.Git.Branches(ctx)
+		if err != nil {
+			return err
+		}
+		for _, b := range all {
+			// Skip main itself, under whatever name.
+			if sha, err := cfg.Git.ResolveRef(ctx, b); b != "" && err == nil && sha != mainSHA {
+				branches = append(branches, b)
+			}
+		}
+	}
+	var drifts []*drift
+	for _, b := range branches {
+		drifts = append(drifts, measureDrift(ctx, cfg, mainRef, b))
+	}
+	if flagDriftJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(map[string]any{"main": mainRef, "branches": drifts})
+	}
+	fmt.Printf("drift from %s:\n", mainRef)
+	for _, d := range drifts {
+		if d.Error != "" {
+			fmt.Printf("  %-30s error: %s\n", d.Branch, d.Error)
+			continue
+		}
+		fmt.Printf("  %-30s %4d behind %4d ahead %4d conflicting files\n", d.Branch, d.Behind, d.Ahead, d.Conflicts)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dustin/go-humanize"
+	"merde.ai/git"
+)
+
+// dryRun reports which paths conflict between mainRef and topicRef and how large the pack would be,
+// without contacting the server.
+// For a rebase, like merde estimate, it judges the branch tips, not each replayed commit.
+func dryRun(ctx context.Context, cfg *Config, verb, mainRef, topicRef string) error {
+	mainSHA, err := cfg.Git.ResolveRef(ctx, mainRef)
+	if err != nil {
+		return err
+	}
+	topicSHA, err := cfg.Git.ResolveRef(ctx, topicRef)
+	if err != nil {
+		return err
+	}
+	if mainSHA == topicSHA {
+		return fmt.Errorf("%v and %v are the same", mainRef, topicRef)
+	}
+	baseSHA, err := cfg.Git.UniqueAncestorMergeBase(ctx, []string{mainSHA, topicSHA})
+	if err != nil {
+		return err
+	}
+	if baseSHA == "" {
+		return fmt.Errorf("%v and %v have no common ancestor", mainRef, topicRef)
+	}
+	_, conflicts, err := cfg.Git.MergeTree(ctx, topicSHA, mainSHA)
+	if err != nil {
+		return err
+	}
+	budget, err := historyBudget(cfg)
+	if err != nil {
+		return err
+	}
+	plan, err := cfg.Git.PlanMergePack(ctx, mainSHA, topicSHA, &git.PackOptions{HistoryBudget: budget, Conflicts: conflicts})
+	if err != nil {
+		return err
+	}
+	var size int64
+	err = cfg.Git.WritePack(ctx, plan.Objects, writerFunc(func(p []byte) (int, error) {
+		size += int64(len(p))
+		return len(p), nil
+	}))
+	if err != nil {
+		return err
+	}
+
+	if verb == "merge" {
+		ui.Status("dry run: merge %s into %s (merge base %s)", mainRef, topicRef, baseSHA[:12])
+	} else {
+		ui.Status("dry run: rebase %s onto %s (merge base %s)", topicRef, mainRef, baseSHA[:12])
+	}
+	if len(conflicts) == 0 {
+		ui.Status("no conflicting paths; git can do this on its own")
+	} else {
+		ui.Status("%d conflicting paths:\n%s", len(conflicts), indent(conflicts))
+	}
+	ui.Status("pack: %d objects, %s (before any conflicts merde resolves locally)", len(plan.Objects), humanize.Bytes(uint64(size)))
+	ui.Status("nothing was sent to the server")
+	return nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/josharian/xc"
+	"merde.ai/git"
+)
+
+// An environment is the effective environment merde runs in, as reported by merde env.
+type environment struct {
+	Version    string   `json:"version"`
+	OS         string   `json:"os"`
+	Arch       string   `json:"arch"`
+	ConfigPath string   `json:"config_path"`
+	CachePath  string   `json:"cache_path"`
+	Server     string   `json:"server"`
+	Proxy      string   `json:"proxy"` // "" if none
+	Token      bool     `json:"token"` // whether a token is configured; never the token itself
+	Transport  string   `json:"transport"`
+	Git        string   `json:"git"`
+	GitVersion string   `json:"git_version"`
+	Repo       string   `json:"repo"`      // "" if not in a repository
+	Overrides  []string `json:"overrides"` // MERDE_* environment variables in effect
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// detectEnvironment gathers the environment, recording problems rather than stopping at them.
+func detectEnvironment(ctx context.Context) *environment {
+	env := &environment{
+		Version: version,
+		OS:      runtime.GOOS,
+		Arch:    runtime.GOARCH,
+	}
+	fail := func(err error) {
+		env.Errors = append(env.Errors, err.Error())
+	}
+	path, err := DefaultPath()
+	if err != nil {
+		fail(err)
+		return env
+	}
+	env.ConfigPath = path
+	env.CachePath = filepath.Join(filepath.Dir(path), "help")
+	cfg, err := loadValues(path)
+	if err != nil {
+		fail(fmt.Errorf("reading config: %w", err))
+		cfg = &Config{Values: make(map[string]string), path: path}
+	}
+	env.Server = cfg.Get(serverRootKey)
+	env.Token = cfg.Get(tokenKey) != ""
+	env.Transport = cfg.Get(transportKey)
+	if u, err := url.Parse(env.Server); err != nil {
+		fail(fmt.Errorf("parsing server URL: %w", err))
+	} else if proxy, err := http.ProxyFromEnvironment(&http.Request{URL: u}); err != nil {
+		fail(fmt.Errorf("finding proxy: %w", err))
+	} else if proxy != nil {
+		proxy.User = nil // may hold credentials
+		env.Proxy = proxy.String()
+	}
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(key, "MERDE_") {
+			env.Overrides = append(env.Overrides, key)
+		}
+	}
+	slices.Sort(env.Overrides)
+
+	env.Git, err = git.Exe(cfg.Get(gitExeKey))
+	if err != nil {
+		fail(err)
+		return env
+	}
+	env.GitVersion, err = xc.Command(ctx, env.Git, "--version").Run().TrimSpace().String()
+	if err != nil {
+		fail(err)
+	}
+	gg, err := git.NewGit(ctx, env.Git)
+	if err == nil {
+		env.Repo, _ = gg.RootDir(ctx)
+		if env.Repo == "" {
+			env.Repo, _ = gg.GitDir(ctx) // bare
+		}
+	}
+	return env
+}
+
+func doEnv(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		return usageErrorf("merde env takes no arguments")
+	}
+	env := detectEnvironment(ctx)
+	if flagEnvJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(env)
+	}
+	token := "not set"
+	if env.Token {
+		token = "set"
+	}
+	rows := [][2]string{
+		{"version", fmt.Sprintf("%s (%s/%s)", env.Version, env.OS, env.Arch)},
+		{"config", env.ConfigPath},
+		{"cache", env.CachePath},
+		{"server", env.Server},
+		{"proxy", cmp.Or(env.Proxy, "none")},
+		{"token", token},
+		{"transport", env.Transport},
+		{"git", env.Git},
+		{"git version", env.GitVersion},
+		{"repository", cmp.Or(env.Repo, "none")},
+		{"overrides", cmp.Or(strings.Join(env.Overrides, ", "), "none")},
+	}
+	for _, row := range rows {
+		fmt.Printf("%-12s %s\n", row[0]+":", row[1])
+	}
+	for _, e := range env.Errors {
+		fmt.Printf("%-12s %s\n", "error:", e)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+)
+
+// Line-ending conventions.
+const (
+	eolLF   = "lf"
+	eolCRLF = "crlf"
+)
+
+// An eolHint tells the server which line-ending convention a conflicted path uses.
+type eolHint struct {
+	path string
+	eol  string // lf or crlf
+}
+
+// String formats h for the server as "eol:path".
+func (h *eolHint) String() string {
+	return h.eol + ":" + h.path
+}
+
+// lineEnding reports the predominant line-ending convention of data.
+func lineEnding(data []byte) string {
+	crlf := bytes.Count(data, []byte("\r\n"))
+	lf := bytes.Count(data, []byte("\n")) - crlf
+	if crlf > lf {
+		return eolCRLF
+	}
+	return eolLF
+}
+
+// normalizeEOL converts CRLF line endings in data to LF.
+func normalizeEOL(data []byte) []byte {
+	return bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+}
+
+// convertEOL converts the line endings of LF-normalized data to eol.
+func convertEOL(data []byte, eol string) []byte {
+	if eol != eolCRLF {
+		return data
+	}
+	return bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
+}
+
+// isBinary reports whether data looks like binary content, using git's heuristic.
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0
+}
+
+// eolConventions returns the line-ending convention stored in the repository for each of paths.
+// Paths with the text or eol attribute are normalized to LF by git on commit;
+// otherwise the convention is whatever the merge base used.
+func eolConventions(ctx context.Context, cfg *Config, paths []string, bases map[string][]byte) (map[string]string, error) {
+	text, err := cfg.Git.CheckAttr(ctx, "text", paths)
+	if err != nil {
+		return nil, err
+	}
+	eol, err := cfg.Git.CheckAttr(ctx, "eol", paths)
+	if err != nil {
+		return nil, err
+	}
+	autocrlf, err := cfg.Git.ConfigValue(ctx, "core.autocrlf")
+	if err != nil {
+		return nil, err
+	}
+	conventions := make(map[string]string)
+	for _, p := range paths {
+		normalized := text[p] == "set" || text[p] == "auto" || (text[p] != "unset" && eol[p] != "unspecified")
+		if autocrlf == "true" || autocrlf == "input" {
+			normalized = normalized || text[p] == "unspecified"
+		}
+		if normalized {
+			conventions[p] = eolLF
+		} else {
+			conventions[p] = lineEnding(bases[p])
+		}
+	}
+	return conventions, nil
+}
+
+// resolveEOLNoise resolves paths modified on both sides where at least one side only changed line endings.
+// The other side's changes are kept, in the file's original line-ending convention.
+// For paths that genuinely conflict, it records the convention so that the server can preserve it.
+func resolveEOLNoise(ctx context.Context, cfg *Config, info *deconflictRequestInfo) error {
+	paths := unresolved(info)
+	if len(paths) == 0 {
+		return nil
+	}
+	bases := make(map[string][]byte)
+	merges := make(map[string][3][]byte)
+	var text []string
+	for _, p := range paths {
+		m, err := readFileMerge(ctx, cfg, info, p)
+		if err != nil {
+			return err
+		}
+		if isBinary(m.Base) || isBinary(m.Ours) || isBinary(m.Theirs) {
+			continue
+		}
+		bases[p] = m.Base
+		merges[p] = [3][]byte{normalizeEOL(m.Base), normalizeEOL(m.Ours), normalizeEOL(m.Theirs)}
+		text = append(text, p)
+	}
+	if len(text) == 0 {
+		return nil
+	}
+	conventions, err := eolConventions(ctx, cfg, text, bases)
+	if err != nil {
+		return err
+	}
+	for _, p := range text {
+		base, ours, theirs := merges[p][0], merges[p][1], merges[p][2]
+		var result []byte
+		switch {
+		case bytes.Equal(ours, base):
+			result = theirs
+		case bytes.Equal(theirs, base), bytes.Equal(ours, theirs):
+			result = ours
+		default:
+			info.eols = append(info.eols, &eolHint{path: p, eol: conventions[p]})
+			continue
+		}
+		how := fmt.Sprintf("line endings only, kept %s", conventions[p])
+		err := resolveLocally(ctx, cfg, info, p, convertEOL(result, conventions[p]), how)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyEOL warns about resolved paths whose line-ending convention differs from the original.
+func verifyEOL(ctx context.Context, cfg *Config, info *deconflictRequestInfo, sha string) {
+	for _, h := range info.eols {
+		data, err := cfg.Git.ReadBlob(ctx, sha, h.path)
+		if err != nil {
+			continue // deleted by the resolution
+		}
+		if got := lineEnding(data); got != h.eol && bytes.Contains(data, []byte("\n")) {
+			ui.Warn("resolved %s uses %s line endings, originally %s", h.path, got, h.eol)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+)
+
+// An estimate summarizes how hard the conflicts between two branches are, judged locally.
+type estimate struct {
+	bothModified int      // paths changed on both sides
+	conflicted   int      // of those, paths git cannot merge cleanly
+	hunks        int      // conflict hunks in those paths
+	lines        int      // lines inside conflict hunks, where both sides rewrote the same code
+	overlap      []string // declarations changed on both sides, as path: declaration, even where the lines merge cleanly
+	deleteModify int      // paths deleted on one side and modified on the other
+	binary       int      // binary paths changed on both sides
+	generated    int      // generated paths changed on both sides
+}
+
+// score combines e into a single complexity score.
+func (e *estimate) score() int {
+	return e.conflicted + 2*e.hunks + e.lines/10 + 2*len(e.overlap) + 3*e.deleteModify + 5*e.binary + e.generated
+}
+
+// recommendation returns advice based on e.
+func (e *estimate) recommendation() string {
+	switch score := e.score(); {
+	case e.conflicted+e.deleteModify+e.binary+e.generated == 0 && len(e.overlap) > 0:
+		return "trivial for git, which merges the lines cleanly; still, review the declarations both sides changed"
+	case score == 0:
+		return "trivial: no conflicts; just use git"
+	case score < 20:
+		return "moderate: merde should handle this quickly"
+	default:
+		return "gnarly: a good job for merde; consider merde split to review it in pieces"
+	}
+}
+
+// countConflicts returns the number of conflict hunks in data, and the lines inside them.
+func countConflicts(data []byte) (hunks, lines int) {
+	inside := false
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		switch {
+		case bytes.HasPrefix(line, []byte("<<<<<<< ")):
+			hunks++
+			inside = true
+		case bytes.HasPrefix(line, []byte(">>>>>>> ")):
+			inside = false
+		case inside && !bytes.Equal(line, []byte("=======")):
+			lines++
+		}
+	}
+	return hunks, lines
+}
+
+// estimateConflicts analyzes the conflicts between mainRef and topicRef locally.
+// For a rebase, it judges the branch tips, not each replayed commit.
+func estimateConflicts(ctx context.Context, cfg *Config, verb, mainRef, topicRef string) (*estimate, error) {
+	info := &deconflictRequestInfo{verb: verb, mainRef: mainRef, topicRef: topicRef}
+	var err error
+	info.mainSHA, err = cfg.Git.ResolveRef(ctx, mainRef)
+	if err != nil {
+		return nil, err
+	}
+	info.topicSHA, err = cfg.Git.ResolveRef(ctx, topicRef)
+	if err != nil {
+		return nil, err
+	}
+	info.baseSHA, err = cfg.Git.UniqueAncestorMergeBase(ctx, []string{info.mainSHA, info.topicSHA})
+	if err != nil {
+		return nil, err
+	}
+	if info.baseSHA == "" {
+		return nil, fmt.Errorf("%v and %v have no common ancestor", mainRef, topicRef)
+	}
+	info.mainChanges, err = cfg.Git.ChangedPaths(ctx, info.baseSHA, info.mainSHA)
+	if err != nil {
+		return nil, err
+	}
+	info.topicChanges, err = cfg.Git.ChangedPaths(ctx, info.baseSHA, info.topicSHA)
+	if err != nil {
+		return nil, err
+	}
+	e := &estimate{deleteModify: len(findDeleteModify(info))}
+	for _, p := range bothModified(info) {
+		e.bothModified++
+		if isGenerated(cfg, p) {
+			e.generated++
+			continue
+		}
+		m, err := readFileMerge(ctx, cfg, info, p)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(m.Ours, m.Theirs) {
+			continue
+		}
+		if isBinary(m.Base) || isBinary(m.Ours) || isBinary(m.Theirs) {
+			e.binary++
+			continue
+		}
+		// Both sides changing the same function is worth a look even where their lines do not touch.
+		mainDecls, err := cfg.Git.ChangedDeclarations(ctx, info.baseSHA, info.mainSHA, p)
+		if err != nil {
+			return nil, err
+		}
+		topicDecls, err := cfg.Git.ChangedDeclarations(ctx, info.baseSHA, info.topicSHA, p)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range mainDecls {
+			if slices.Contains(topicDecls, d) {
+				e.overlap = append(e.overlap, p+": "+d)
+			}
+		}
+		merged, err := cfg.Git.MergeMarkers(ctx, m, "ours", "theirs")
+		if err != nil {
+			return nil, err
+		}
+		hunks, lines := countConflicts(merged)
+		if hunks > 0 {
+			e.conflicted++
+			e.hunks += hunks
+			e.lines += lines
+		}
+	}
+	return e, nil
+}
+
+func doEstimate(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return usageErrorf("merde estimate needs merge or rebase, and its arguments")
+	}
+	verb, args := args[0], args[1:]
+	if verb != "merge" && verb != "rebase" {
+		return usageErrorf("merde estimate needs merge or rebase, not %q", verb)
+	}
+	cfg, err := LoadDefault(ctx)
+	if err != nil {
+		return err
+	}
+	mainRef, topicRef, err := mainTopic(ctx, cfg, verb, args)
+	if err != nil {
+		return err
+	}
+	e, err := estimateConflicts(ctx, cfg, verb, mainRef, topicRef)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s %s and %s:\n", verb, mainRef, topicRef)
+	fmt.Printf("  %d paths changed on both sides, %d of them conflicting\n", e.bothModified, e.conflicted)
+	fmt.Printf("  %d conflict hunks, %d lines rewritten on both sides\n", e.hunks, e.lines)
+	fmt.Printf("  %d declarations changed on both sides\n", len(e.overlap))
+	for _, d := range e.overlap {
+		fmt.Printf("    %s\n", d)
+	}
+	fmt.Printf("  %d delete/modify, %d binary, %d generated\n", e.deleteModify, e.binary, e.generated)
+	fmt.Printf("complexity: %d (%s)\n", e.score(), e.recommendation())
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// An ExitError reports that a command should end with exit code Code,
+// usually because the server asked for it after showing its own explanation.
+// Only main turns it into an actual exit, so that the lsp, mcp, and bot modes,
+// and anything else that runs merde's commands in-process, keep running.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("the server ended the operation with exit code %d", e.Code)
+}
+
+// An exitStatus collects the exit codes the server asks for while a command runs.
+// Exiting as soon as the server asks would skip the rest of its response and any cleanup,
+// so the command finishes first and the highest code is reported afterwards.
+type exitStatus struct {
+	mu  sync.Mutex
+	max int
+}
+
+type exitStatusKey struct{}
+
+// withExitStatus returns a context that collects exit code requests into the returned status.
+func withExitStatus(ctx context.Context) (context.Context, *exitStatus) {
+	s := new(exitStatus)
+	return context.WithValue(ctx, exitStatusKey{}, s), s
+}
+
+// requestExit records in ctx's exit status that the server asked the client to exit with code.
+// It reports false if ctx collects no exit status, in which case the caller should return an *ExitError.
+func requestExit(ctx context.Context, code int) bool {
+	s, ok := ctx.Value(exitStatusKey{}).(*exitStatus)
+	if !ok {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.max = max(s.max, code)
+	return true
+}
+
+// code returns the highest exit code requested, or 0 if none was.
+func (s *exitStatus) code() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.max
+}
+
+// err returns an *ExitError for the highest exit code requested, or nil if none was.
+func (s *exitStatus) err() error {
+	if code := s.code(); code > 0 {
+		return &ExitError{Code: code}
+	}
+	return nil
+}
+
+// collectExit runs f with its own exit status and returns f's error,
+// or failing that an *ExitError for any exit code the server asked for.
+func collectExit(ctx context.Context, f func(ctx context.Context) error) error {
+	ctx, status := withExitStatus(ctx)
+	err := f(ctx)
+	if err != nil {
+		return err
+	}
+	return status.err()
+}
@@ -0,0 +1,29 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"merde.ai/git"
+)
+
+// conflictFingerprint identifies the conflict in m by its contents, whatever the path.
+// It does not depend on which side is ours, so a merge and the matching rebase share fingerprints.
+func conflictFingerprint(m *git.FileMerge) string {
+	a, b := m.Ours, m.Theirs
+	if bytes.Compare(a, b) > 0 {
+		a, b = b, a
+	}
+	h := sha256.New()
+	for _, side := range [][]byte{m.Base, a, b} {
+		binary.Write(h, binary.BigEndian, uint64(len(side)))
+		h.Write(side)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
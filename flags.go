@@ -5,13 +5,123 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"time"
 
 	"github.com/peterbourgon/ff/v3/ffcli"
 )
 
 var (
-	rootFlagSet = flag.NewFlagSet("merde", flag.ContinueOnError)
+	rootFlagSet        = flag.NewFlagSet("merde", flag.ContinueOnError)
+	mergeFlagSet       = flag.NewFlagSet("merde merge", flag.ContinueOnError)
+	rebaseFlagSet      = flag.NewFlagSet("merde rebase", flag.ContinueOnError)
+	lspFlagSet         = flag.NewFlagSet("merde lsp", flag.ContinueOnError)
+	hookFlagSet        = flag.NewFlagSet("merde hook", flag.ContinueOnError)
+	watchFlagSet       = flag.NewFlagSet("merde watch", flag.ContinueOnError)
+	foreachFlagSet     = flag.NewFlagSet("merde foreach", flag.ContinueOnError)
+	cleanupFlagSet     = flag.NewFlagSet("merde cleanup", flag.ContinueOnError)
+	botFlagSet         = flag.NewFlagSet("merde bot", flag.ContinueOnError)
+	queueFlagSet       = flag.NewFlagSet("merde queue", flag.ContinueOnError)
+	docsFlagSet        = flag.NewFlagSet("merde docs", flag.ContinueOnError)
+	envFlagSet         = flag.NewFlagSet("merde env", flag.ContinueOnError)
+	splitFlagSet       = flag.NewFlagSet("merde split", flag.ContinueOnError)
+	driftFlagSet       = flag.NewFlagSet("merde drift", flag.ContinueOnError)
+	backportFlagSet    = flag.NewFlagSet("merde backport", flag.ContinueOnError)
+	forwardportFlagSet = flag.NewFlagSet("merde forwardport", flag.ContinueOnError)
+	resolveFileFlagSet = flag.NewFlagSet("merde resolve-file", flag.ContinueOnError)
+	forkFlagSet        = flag.NewFlagSet("merde fork", flag.ContinueOnError)
+	configGetFlagSet   = flag.NewFlagSet("merde config get", flag.ContinueOnError)
+	configListFlagSet  = flag.NewFlagSet("merde config list", flag.ContinueOnError)
+	artifactsFlagSet   = flag.NewFlagSet("merde artifacts", flag.ContinueOnError)
+	authFlagSet        = flag.NewFlagSet("merde auth", flag.ContinueOnError)
+	versionFlagSet     = flag.NewFlagSet("merde version", flag.ContinueOnError)
+	adoptFlagSet       = flag.NewFlagSet("merde adopt", flag.ContinueOnError)
+	configEncryptFlags = flag.NewFlagSet("merde config encrypt", flag.ContinueOnError)
+	reviewFlagSet      = flag.NewFlagSet("merde review", flag.ContinueOnError)
+	cherryPickFlagSet  = flag.NewFlagSet("merde cherry-pick", flag.ContinueOnError)
+	revertFlagSet      = flag.NewFlagSet("merde revert", flag.ContinueOnError)
+
+	flagChdir       string
+	flagLockWait    time.Duration
+	flagForceUnlock bool
+	flagRemote      string
+	flagLimitRate   string
+	flagEphemeral   bool
+	flagOutput      string
+	flagPlain       bool
+	flagYes         bool
+	flagVerbose     bool
+	flagGitTimings  bool
+	flagNo          bool
+
+	// flags shared by merge and rebase
+	flagReport string
+	flagAdopt  bool
+	flagDryRun bool
+	flagPR     int
+	flagTag    string
+	flagSign   bool
+
+	flagNoCommit       bool
+	flagMergeContinue  bool
+	flagRebaseContinue bool
+	flagMessage        string
+	flagEdit           bool
+
+	flagInteractive     bool
+	flagAdoptUnexpected bool
+	flagVersionCheck    bool
+	flagAuthRepo        bool
+	flagArtifactsDir    string
+	flagDetach          bool
+	flagSplitCommits    bool
+
+	flagHookAuto bool
+
+	flagWatchAuto   bool
+	flagWatchNotify bool
+
+	flagForeachRepos    string
+	flagForeachManifest string
+	flagForeachJobs     int
+
+	flagCleanupOlderThan string
+	flagCleanupDryRun    bool
+
+	flagBotInterval time.Duration
+
+	flagQueueTimeout time.Duration
+	flagQueueRef     string
+	flagQueuePush    string
+	flagQueueVerb    string
+
+	flagEnvJSON bool
+
+	flagConfigJSON       bool
+	flagConfigPassphrase bool
+	flagConfigKeyFile    string
+
+	flagBackportOnto string
+
+	flagForwardportOnto string
+
+	flagResolveFileOutput string
+	flagResolveFilePath   string
+
+	flagForkRemote string
+	flagForkRebase bool
+	flagForkNoPush bool
+
+	flagDriftMain string
+	flagDriftJSON bool
+
+	flagSplitBy    string
+	flagSplitDepth int
+
+	flagDocsMan      bool
+	flagDocsMarkdown bool
 
 	rootCommand = &ffcli.Command{
 		Name:        "merde",
@@ -19,48 +129,573 @@ var (
 		ShortHelp:   "merde.ai client",
 		FlagSet:     rootFlagSet,
 		Exec:        doRoot,
-		Subcommands: []*ffcli.Command{authCommand, versionCommand, configCommand, helpCommand, mergeCommand, rebaseCommand},
+		Subcommands: []*ffcli.Command{authCommand, versionCommand, configCommand, helpCommand, mergeCommand, rebaseCommand, reviewCommand, lspCommand, mcpCommand, hookCommand, continueCommand, watchCommand, foreachCommand, cleanupCommand, adoptCommand, undoCommand, attachCommand, botCommand, queueCommand, retryCommand, docsCommand, envCommand, telemetryCommand, memoryCommand, splitCommand, estimateCommand, driftCommand, backportCommand, forwardportCommand, cherryPickCommand, revertCommand, resolveFileCommand, resolveDirCommand, forkCommand, privacyCommand, reposCommand, artifactsCommand},
 	}
 
 	versionCommand = &ffcli.Command{
 		Name:       "version",
-		ShortUsage: "merde version",
+		ShortUsage: "merde version [--check]",
 		ShortHelp:  "print version information and exit",
-		Exec:       doVersion,
+		LongHelp: `merde version prints the version of merde and of git.
+
+With --check, it also prints how merde was built, reports whether a newer
+release is available, and checks that this binary is byte for byte the one in
+the official release of its version, verified against the release checksums.`,
+		FlagSet: versionFlagSet,
+		Exec:    doVersion,
 	}
 
 	configCommand = &ffcli.Command{
-		Name:       "config",
-		ShortUsage: "merde config [key] [value]",
-		ShortHelp:  "get/set config values (low level, for debugging/development)",
-		Exec:       doConfig,
+		Name:        "config",
+		ShortUsage:  "merde config [key] [value]",
+		ShortHelp:   "get/set config values (low level, for debugging/development)",
+		Subcommands: []*ffcli.Command{configGetCommand, configListCommand, configEncryptCommand},
+		Exec:        doConfig,
+	}
+
+	configGetCommand = &ffcli.Command{
+		Name:       "get",
+		ShortUsage: "merde config get <key> [--json]",
+		ShortHelp:  "print the effective value of a config key; with --json, also where it comes from",
+		FlagSet:    configGetFlagSet,
+		Exec:       doConfigGet,
+	}
+
+	configListCommand = &ffcli.Command{
+		Name:       "list",
+		ShortUsage: "merde config list [--json]",
+		ShortHelp:  "list config values and where they come from; with --json, every key with its default and override",
+		FlagSet:    configListFlagSet,
+		Exec:       doConfigList,
+	}
+
+	configEncryptCommand = &ffcli.Command{
+		Name:       "encrypt",
+		ShortUsage: "merde config encrypt [--passphrase | --key-file file]",
+		ShortHelp:  "encrypt the tokens in the config file, for machines without a keychain",
+		LongHelp: `merde config encrypt encrypts the secrets in the config file, such as the
+merde and forge tokens, and encrypts any set later. By default the key is a
+new random key file next to the config file; keep it off shared storage.
+With --passphrase, the key comes from a passphrase instead, which merde asks
+for when it needs a secret, or reads from MERDE_PASSPHRASE.`,
+		FlagSet: configEncryptFlags,
+		Exec:    doConfigEncrypt,
 	}
 
 	authCommand = &ffcli.Command{
 		Name:       "auth",
-		ShortUsage: "merde auth [token]",
+		ShortUsage: "merde auth [--repo] [token]",
 		ShortHelp:  "(re-)authenticate",
-		Exec:       doAuth,
+		LongHelp: `merde auth stores token, if given, checks the stored token with the server,
+and shows its scopes, expiry, and the repositories it is limited to.
+It warns if the token grants more than merde needs.
+
+With --repo, it exchanges the token for one derived from it that is limited
+to this repository's forge remotes and to the scopes merde needs, and stores that.`,
+		FlagSet: authFlagSet,
+		Exec:    doAuth,
 	}
 
 	helpCommand = &ffcli.Command{
 		Name:       "help",
-		ShortUsage: "merde help",
-		ShortHelp:  "print detailed usage information",
-		Exec:       doHelp,
+		ShortUsage: "merde help [topic]",
+		ShortHelp:  "print long-form documentation from merde.ai; for usage and flags, use merde <subcommand> -h",
 	}
 
 	mergeCommand = &ffcli.Command{
 		Name:       "merge",
-		ShortUsage: "merde merge [topic]",
+		ShortUsage: "merde merge [flags] [topic]",
 		ShortHelp:  "merge <topic> into current branch; topic defaults to the current upstream",
+		FlagSet:    mergeFlagSet,
 		Exec:       doMerge,
 	}
 
 	rebaseCommand = &ffcli.Command{
 		Name:       "rebase",
-		ShortUsage: "merde rebase [main-branch [topic-branch]]",
+		ShortUsage: "merde rebase [flags] [main-branch [topic-branch]]",
 		ShortHelp:  "rebase <topic> atop <main>; topic defaults to the current branch and main defaults to its upstream",
+		FlagSet:    rebaseFlagSet,
 		Exec:       doRebase,
 	}
+
+	reviewCommand = &ffcli.Command{
+		Name:       "review",
+		ShortUsage: "merde review [operation-id]",
+		ShortHelp:  "review the result of the most recent (or given) operation",
+		FlagSet:    reviewFlagSet,
+		Exec:       doReview,
+	}
+
+	artifactsCommand = &ffcli.Command{
+		Name:       "artifacts",
+		ShortUsage: "merde artifacts [-o dir] [operation-id]",
+		ShortHelp:  "download what the server keeps for the most recent (or given) operation, such as its report, patches, and logs",
+		LongHelp: `merde artifacts downloads the artifacts the server keeps for an operation,
+such as its resolution report, raw patches, and logs, into a directory,
+for later review or compliance archiving. Alongside them it writes
+manifest.json, listing the artifacts and their checksums, and operation.json,
+the local record of the operation.`,
+		FlagSet: artifactsFlagSet,
+		Exec:    doArtifacts,
+	}
+
+	lspCommand = &ffcli.Command{
+		Name:       "lsp",
+		ShortUsage: "merde lsp [--stdio]",
+		ShortHelp:  "serve JSON-RPC on stdin/stdout, for editor integrations",
+		FlagSet:    lspFlagSet,
+		Exec:       doLSP,
+	}
+
+	mcpCommand = &ffcli.Command{
+		Name:       "mcp",
+		ShortUsage: "merde mcp",
+		ShortHelp:  "serve the Model Context Protocol on stdin/stdout, for AI agents",
+		Exec:       doMCP,
+	}
+
+	hookCommand = &ffcli.Command{
+		Name:        "hook",
+		ShortUsage:  "merde hook <install|uninstall|print>",
+		ShortHelp:   "offer merde when git merge, rebase, or cherry-pick stops on conflicts",
+		Subcommands: []*ffcli.Command{hookInstallCommand, hookUninstallCommand, hookPrintCommand},
+		Exec: func(ctx context.Context, args []string) error {
+			return usageErrorf("merde hook needs a subcommand: install, uninstall, or print")
+		},
+	}
+
+	hookInstallCommand = &ffcli.Command{
+		Name:       "install",
+		ShortUsage: "merde hook install [--auto]",
+		ShortHelp:  "install a git wrapper function in your shell's rc file",
+		FlagSet:    hookFlagSet,
+		Exec:       doHookInstall,
+	}
+
+	hookUninstallCommand = &ffcli.Command{
+		Name:       "uninstall",
+		ShortUsage: "merde hook uninstall",
+		ShortHelp:  "remove the git wrapper function",
+		Exec:       doHookUninstall,
+	}
+
+	hookPrintCommand = &ffcli.Command{
+		Name:       "print",
+		ShortUsage: "merde hook print [--auto]",
+		ShortHelp:  "print the git wrapper function, to install it yourself",
+		FlagSet:    hookFlagSet,
+		Exec:       doHookPrint,
+	}
+
+	continueCommand = &ffcli.Command{
+		Name:       "continue",
+		ShortUsage: "merde continue",
+		ShortHelp:  "resolve a conflicted, in-progress git merge, rebase, or cherry-pick with merde, keeping your resolutions",
+		Exec:       doContinue,
+	}
+
+	watchCommand = &ffcli.Command{
+		Name:       "watch",
+		ShortUsage: "merde watch [--auto] [--notify]",
+		ShortHelp:  "watch the repository and offer merde when a merge or rebase stops on conflicts",
+		FlagSet:    watchFlagSet,
+		Exec:       doWatch,
+	}
+
+	foreachCommand = &ffcli.Command{
+		Name:       "foreach",
+		ShortUsage: "merde foreach [--repos <list|glob>] [--manifest <file>] [-j n] <merge|rebase> [args...]",
+		ShortHelp:  "run the same merge or rebase across many repositories",
+		FlagSet:    foreachFlagSet,
+		Exec:       doForeach,
+	}
+
+	attachCommand = &ffcli.Command{
+		Name:       "attach",
+		ShortUsage: "merde attach [job-id]",
+		ShortHelp:  "watch the most recent (or given) merge or rebase started with --detach",
+		Exec:       doAttach,
+	}
+
+	adoptCommand = &ffcli.Command{
+		Name:       "adopt",
+		ShortUsage: "merde adopt [--accept-unexpected] [operation-id]",
+		ShortHelp:  "move the topic branch to a reviewed result",
+		LongHelp: `merde adopt moves the topic branch to the result of the most recent (or given) operation.
+If the result changes files that had no conflicts, merde lists them and asks
+before adopting it; --accept-unexpected adopts it without asking.`,
+		FlagSet: adoptFlagSet,
+		Exec:    doAdopt,
+	}
+
+	undoCommand = &ffcli.Command{
+		Name:       "undo",
+		ShortUsage: "merde undo [operation-id]",
+		ShortHelp:  "undo the most recent (or given) operation",
+		LongHelp: `merde undo reverses the last step of the most recent operation not yet undone.
+If the operation was adopted, its topic branch goes back to the value kept in
+its backup ref (refs/merde/backup/<id>). Adopting already deleted the refs the
+operation created, so running merde undo again only marks it undone.
+Otherwise, merde undo deletes those refs and marks it undone. Refs that have
+moved since the operation are left alone.`,
+		Exec: doUndo,
+	}
+
+	botCommand = &ffcli.Command{
+		Name:       "bot",
+		ShortUsage: "merde bot [--interval d] <jobs.json>",
+		ShortHelp:  "keep branch pairs reconciled headlessly, logging JSON to stdout",
+		LongHelp: `merde bot resolves each job in jobs.json, a file like:
+
+  {"jobs": [{"verb": "rebase", "main": "origin/main", "topic": "origin/feature",
+             "fetch": "origin", "push": "origin", "pull_request": true}]}
+
+Each result goes to the job's ref (default refs/heads/merde/<topic>), pushed to
+the job's push remote if set. Jobs whose topic already contains main are skipped.
+Without --interval, merde bot runs every job once, for cron or CI triggers.`,
+		FlagSet: botFlagSet,
+		Exec:    doBot,
+	}
+
+	queueCommand = &ffcli.Command{
+		Name:       "queue",
+		ShortUsage: "merde queue [--timeout d] [--ref ref] [--push remote] <base> <head>",
+		ShortHelp:  "resolve a stale merge queue entry, reporting JSON on stdout",
+		LongHelp: `merde queue resolves the conflicts between a merge queue's speculative base
+and a queued pull request's head, and stores the result in a ref (default
+refs/merde/queue/<head>), pushed to --push if set.
+
+It prints one JSON object to stdout, with "ok" telling whether it succeeded,
+and exits non-zero on failure. It gives up after --timeout.`,
+		FlagSet: queueFlagSet,
+		Exec:    doQueue,
+	}
+
+	retryCommand = &ffcli.Command{
+		Name:       "retry",
+		ShortUsage: "merde retry",
+		ShortHelp:  "redo the last merge or rebase that failed, reusing its cached pack",
+		Exec:       doRetry,
+	}
+
+	backportCommand = &ffcli.Command{
+		Name:       "backport",
+		ShortUsage: "merde backport --onto <branch> <commit|range>",
+		ShortHelp:  "cherry-pick commits onto a release branch, resolving conflicts with merde",
+		LongHelp: `merde backport cherry-picks a commit, or a range such as v1.3.0..abc123,
+onto a release branch, oldest first. Each picked commit keeps its author and
+message, plus a "(cherry picked from commit ...)" line. Conflicting picks are
+resolved with merde. The result goes to refs/merde/backport/<branch>,
+for review before fast-forwarding the branch to it.`,
+		FlagSet: backportFlagSet,
+		Exec:    doBackport,
+	}
+
+	cherryPickCommand = &ffcli.Command{
+		Name:       "cherry-pick",
+		ShortUsage: "merde cherry-pick <commit|range>...",
+		ShortHelp:  "cherry-pick commits onto the current branch, resolving conflicts with merde",
+		LongHelp: `merde cherry-pick picks the given commits, and the commits in any ranges
+such as main~3..main, onto the current branch, in the order given.
+Each picked commit keeps its author and message, plus a
+"(cherry picked from commit ...)" line. Conflicting picks are resolved with merde.
+The result goes to refs/merde/cherry-pick/<branch>, for review before
+fast-forwarding the branch to it. The working tree is left alone.`,
+		FlagSet: cherryPickFlagSet,
+		Exec:    doCherryPick,
+	}
+
+	revertCommand = &ffcli.Command{
+		Name:       "revert",
+		ShortUsage: "merde revert <commit|range>...",
+		ShortHelp:  "revert commits on the current branch, resolving conflicts with merde",
+		LongHelp: `merde revert makes a commit undoing each given commit, and each commit in
+any ranges such as main~3..main, newest first within a range, as git revert does.
+Conflicting reverts are resolved with merde. The result goes to
+refs/merde/revert/<branch>, for review before fast-forwarding the branch to it.
+The working tree is left alone.`,
+		FlagSet: revertFlagSet,
+		Exec:    doRevert,
+	}
+
+	forwardportCommand = &ffcli.Command{
+		Name:       "forwardport",
+		ShortUsage: "merde forwardport [--onto branch] <release-branch|range>",
+		ShortHelp:  "replay fixes from a release branch onto main, resolving conflicts with merde",
+		LongHelp: `merde forwardport replays the commits on a release branch that are not on
+main, or a range such as v1.3.0..release-1.3, onto main, oldest first.
+Commits whose changes already landed on main, judged by patch ID, are skipped.
+Conflicting picks are resolved with merde. The result goes to
+refs/merde/forwardport/<branch>, for review before fast-forwarding the branch to it.`,
+		FlagSet: forwardportFlagSet,
+		Exec:    doForwardport,
+	}
+
+	resolveFileCommand = &ffcli.Command{
+		Name:       "resolve-file",
+		ShortUsage: "merde resolve-file [-o file] [--path path] <base> <ours> <theirs>",
+		ShortHelp:  "three-way merge one file with merde",
+		LongHelp: `merde resolve-file merges the changes from base to theirs into ours and
+writes the result to stdout, or to the -o file. Each input is a file or,
+inside a git repository, a blob such as a hash or commit:path.
+It exits 1 if conflicts remain, as git merge-file does,
+so it works as a git merge driver:
+
+  git config merge.merde.driver 'merde resolve-file -o %A --path %P %O %A %B'`,
+		FlagSet: resolveFileFlagSet,
+		Exec:    doResolveFile,
+	}
+
+	resolveDirCommand = &ffcli.Command{
+		Name:       "resolve-dir",
+		ShortUsage: "merde resolve-dir <dir>",
+		ShortHelp:  "during a conflicted git merge or rebase, resolve only the conflicts under dir with merde",
+		LongHelp: `merde resolve-dir resolves the conflicted files under dir, in a git merge
+or rebase stopped on conflicts, and stages those it resolves completely.
+Conflicts elsewhere are left for you, so you can let merde handle, say,
+generated files or docs while resolving core code by hand.`,
+		Exec: doResolveDir,
+	}
+
+	forkCommand = &ffcli.Command{
+		Name:       "fork",
+		ShortUsage: "merde fork [--rebase] [--no-push] [branch]",
+		ShortHelp:  "update a branch of your fork with upstream's default branch, and push it back to your fork",
+		LongHelp: `merde fork merges upstream's default branch into branch (default: the current
+branch), or with --rebase rebases branch onto it, resolving conflicts with merde.
+It then adopts the result and pushes branch back to your fork, unless --no-push.
+Upstream is the remote named by --remote or preferred_remote, or "upstream".
+If there is no such remote and your fork is on GitHub or Bitbucket, merde adds the repository
+it was forked from. Either way, it fetches upstream first.`,
+		FlagSet: forkFlagSet,
+		Exec:    doFork,
+	}
+
+	privacyCommand = &ffcli.Command{
+		Name:       "privacy",
+		ShortUsage: "merde privacy",
+		ShortHelp:  "show the metadata merde sends with requests, and how to suppress the optional parts",
+		Exec:       doPrivacy,
+	}
+
+	driftCommand = &ffcli.Command{
+		Name:       "drift",
+		ShortUsage: "merde drift [--main ref] [--json] [branch...]",
+		ShortHelp:  "report how far branches have diverged from main, and how many files would conflict",
+		FlagSet:    driftFlagSet,
+		Exec:       doDrift,
+	}
+
+	estimateCommand = &ffcli.Command{
+		Name:       "estimate",
+		ShortUsage: "merde estimate <merge|rebase> [args...]",
+		ShortHelp:  "judge how hard a merge or rebase will be, locally, without uploading anything",
+		Exec:       doEstimate,
+	}
+
+	splitCommand = &ffcli.Command{
+		Name:       "split",
+		ShortUsage: "merde split [--by dir|owner] [--depth n] [topic]",
+		ShortHelp:  "merge like merde merge, but resolve each directory's or owner's conflicts in a separate commit, for review",
+		LongHelp: `merde split merges like merde merge, then rewrites the result as a chain:
+first the merge commit with every conflicting path left as on the current
+branch, then one commit per group applying merde's resolutions. Groups are by
+directory (--by dir, the default) or by CODEOWNERS owner (--by owner).
+The chain ends in the same tree as merde merge would produce, and can be
+reviewed commit by commit, then adopted with merde adopt.`,
+		FlagSet: splitFlagSet,
+		Exec:    doSplit,
+	}
+
+	memoryCommand = &ffcli.Command{
+		Name:        "memory",
+		ShortUsage:  "merde memory <list|forget>",
+		ShortHelp:   "manage the resolutions shared with your team (see the team_memory config key)",
+		Subcommands: []*ffcli.Command{memoryListCommand, memoryForgetCommand},
+		Exec: func(ctx context.Context, args []string) error {
+			return usageErrorf("merde memory needs a subcommand: list or forget")
+		},
+	}
+
+	memoryListCommand = &ffcli.Command{
+		Name:       "list",
+		ShortUsage: "merde memory list",
+		ShortHelp:  "list the resolutions shared with your team",
+		Exec:       doMemoryList,
+	}
+
+	memoryForgetCommand = &ffcli.Command{
+		Name:       "forget",
+		ShortUsage: "merde memory forget <fingerprint>",
+		ShortHelp:  "stop reusing a shared resolution",
+		Exec:       doMemoryForget,
+	}
+
+	reposCommand = &ffcli.Command{
+		Name:       "repos",
+		ShortUsage: "merde repos <list|rename|detach|delete>",
+		ShortHelp:  "manage the repositories merde.ai associates with your account",
+		LongHelp: `merde repos manages the repositories the server associates with your account,
+which it derives from the forge remotes sent with each operation (see merde privacy).
+A repository is named by its ID or name, as shown by merde repos list.`,
+		Subcommands: []*ffcli.Command{reposListCommand, reposRenameCommand, reposDetachCommand, reposDeleteCommand},
+		Exec: func(ctx context.Context, args []string) error {
+			return usageErrorf("merde repos needs a subcommand: list, rename, detach, or delete")
+		},
+	}
+
+	reposListCommand = &ffcli.Command{
+		Name:       "list",
+		ShortUsage: "merde repos list",
+		ShortHelp:  "list the repositories associated with your account, and what the server stores for each",
+		Exec:       doReposList,
+	}
+
+	reposRenameCommand = &ffcli.Command{
+		Name:       "rename",
+		ShortUsage: "merde repos rename <repo> <name>",
+		ShortHelp:  "rename a repository on the server",
+		Exec:       doReposRename,
+	}
+
+	reposDetachCommand = &ffcli.Command{
+		Name:       "detach",
+		ShortUsage: "merde repos detach <repo>",
+		ShortHelp:  "stop associating a repository with your account, keeping its data",
+		Exec:       doReposDetach,
+	}
+
+	reposDeleteCommand = &ffcli.Command{
+		Name:       "delete",
+		ShortUsage: "merde repos delete <repo>",
+		ShortHelp:  "delete everything the server stores for a repository",
+		Exec:       doReposDelete,
+	}
+
+	telemetryCommand = &ffcli.Command{
+		Name:       "telemetry",
+		ShortUsage: "merde telemetry status|on|off",
+		ShortHelp:  "show or change whether merde sends anonymous usage metrics (off unless you opt in)",
+		Exec:       doTelemetry,
+	}
+
+	envCommand = &ffcli.Command{
+		Name:       "env",
+		ShortUsage: "merde env [--json]",
+		ShortHelp:  "print the environment merde runs in, for bug reports and support",
+		FlagSet:    envFlagSet,
+		Exec:       doEnv,
+	}
+
+	docsCommand = &ffcli.Command{
+		Name:       "docs",
+		ShortUsage: "merde docs --man|--markdown <dir>",
+		ShortHelp:  "generate man pages or markdown for all subcommands and config keys",
+		FlagSet:    docsFlagSet,
+	}
+
+	cleanupCommand = &ffcli.Command{
+		Name:       "cleanup",
+		ShortUsage: "merde cleanup [--older-than 30d] [--dry-run]",
+		ShortHelp:  "delete result and backup refs left by old operations",
+		LongHelp: `merde cleanup deletes the refs that operations older than --older-than left under the ref namespace.
+
+The backup ref of an adopted operation is kept until the operation is undone,
+since merde undo restores the topic branch from it.`,
+		FlagSet: cleanupFlagSet,
+		Exec:    doCleanup,
+	}
 )
+
+// A usageError reports that a command was run with bad arguments.
+// It matches flag.ErrHelp, so that ffcli prints the command's usage, without asking the server.
+type usageError struct {
+	msg string
+}
+
+func usageErrorf(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+func (e *usageError) Error() string { return e.msg }
+
+func (e *usageError) Is(target error) bool { return target == flag.ErrHelp }
+
+func init() {
+	// Set here rather than above, because these document rootCommand.
+	helpCommand.Exec = doHelp
+	docsCommand.Exec = doDocs
+
+	mergeFlagSet.StringVar(&flagMessage, "m", "", "use `message` for the merge commit, committed as you")
+	mergeFlagSet.BoolVar(&flagEdit, "edit", false, "edit the merge commit message before creating the result, committed as you")
+	rebaseFlagSet.BoolVar(&flagInteractive, "i", false, "edit the plan of commits to pick, drop, squash, or reword before resolving")
+	rebaseFlagSet.BoolVar(&flagSplitCommits, "split-commits", false, "let merde split a commit that causes most of the conflicts into smaller commits, shown for approval")
+	mergeFlagSet.BoolVar(&flagNoCommit, "no-commit", false, "stage the resolved merge in the index and working tree without committing it")
+	rebaseFlagSet.BoolVar(&flagRebaseContinue, "continue", false, "resolve the conflicted step of the git rebase in progress and stage the result, for you to git rebase --continue")
+	mergeFlagSet.BoolVar(&flagMergeContinue, "continue", false, "resolve the conflicted git merge in progress and stage the result in it, for you to git commit")
+	rootFlagSet.StringVar(&flagChdir, "C", "", "run as if merde was started in `path`")
+	rootFlagSet.DurationVar(&flagLockWait, "lock-wait", 0, "wait up to `duration` for another merde operation in the same repository to finish")
+	rootFlagSet.StringVar(&flagRemote, "remote", "", "use `name` as the remote to merge with and report, as with the preferred_remote config")
+	rootFlagSet.BoolVar(&flagVerbose, "verbose", false, "show each git command merde runs")
+	rootFlagSet.BoolVar(&flagGitTimings, "git-timings", false, "when the command finishes, list the slowest git commands merde ran and the time spent in each kind")
+	rootFlagSet.BoolVar(&flagPlain, "plain", false, "accessibility mode: plain status lines without color or animation, for screen readers and dumb terminals")
+	rootFlagSet.BoolVar(&flagForceUnlock, "force-unlock", false, "remove the repository's merde lock, even if its holder may still be running")
+	artifactsFlagSet.StringVar(&flagArtifactsDir, "o", "", "`dir`ectory to download into (default merde-artifacts-<operation-id>)")
+	authFlagSet.BoolVar(&flagAuthRepo, "repo", false, "replace the token with one limited to this repository and the scopes merde needs")
+	versionFlagSet.BoolVar(&flagVersionCheck, "check", false, "check for a newer release and verify this binary against its release")
+	adoptFlagSet.BoolVar(&flagAdoptUnexpected, "accept-unexpected", false, "adopt a result that changes files that had no conflicts without asking")
+	backportFlagSet.StringVar(&flagBackportOnto, "onto", "", "cherry-pick onto `branch`")
+	forwardportFlagSet.StringVar(&flagForwardportOnto, "onto", "", "replay onto `branch` (default origin/HEAD, or that of --remote, then main or master)")
+	resolveFileFlagSet.StringVar(&flagResolveFileOutput, "o", "", "write the result to `file` instead of stdout")
+	resolveFileFlagSet.StringVar(&flagResolveFilePath, "path", "", "the file's `path` in the repository, to tell merde its language")
+	forkFlagSet.StringVar(&flagForkRemote, "fork", "origin", "the `remote` of your fork")
+	forkFlagSet.BoolVar(&flagForkRebase, "rebase", false, "rebase onto upstream instead of merging it")
+	forkFlagSet.BoolVar(&flagForkNoPush, "no-push", false, "leave the result for review instead of adopting and pushing it")
+	driftFlagSet.StringVar(&flagDriftMain, "main", "", "measure drift from `ref` (default origin/HEAD, or that of --remote, then main or master)")
+	driftFlagSet.BoolVar(&flagDriftJSON, "json", false, "print JSON, for dashboards")
+	splitFlagSet.StringVar(&flagSplitBy, "by", "dir", "group conflicting paths by `dir` or owner")
+	splitFlagSet.IntVar(&flagSplitDepth, "depth", 1, "group conflicting paths by their first `n` directories")
+	configEncryptFlags.BoolVar(&flagConfigPassphrase, "passphrase", false, "derive the key from a passphrase instead of a key file")
+	configEncryptFlags.StringVar(&flagConfigKeyFile, "key-file", "", "use or create the key `file` (default: key, next to the config file)")
+	for _, fs := range []*flag.FlagSet{configGetFlagSet, configListFlagSet} {
+		fs.BoolVar(&flagConfigJSON, "json", false, "print JSON, for scripts and editor plugins")
+	}
+	envFlagSet.BoolVar(&flagEnvJSON, "json", false, "print JSON")
+	docsFlagSet.BoolVar(&flagDocsMan, "man", false, "generate man pages")
+	docsFlagSet.BoolVar(&flagDocsMarkdown, "markdown", false, "generate markdown")
+	lspFlagSet.Bool("stdio", true, "communicate over stdin/stdout (the only supported transport)")
+	queueFlagSet.DurationVar(&flagQueueTimeout, "timeout", 2*time.Minute, "give up after `duration`")
+	queueFlagSet.StringVar(&flagQueueRef, "ref", "", "store the result in `ref` (default refs/merde/queue/<head>)")
+	queueFlagSet.StringVar(&flagQueuePush, "push", "", "push the result ref to `remote`")
+	queueFlagSet.StringVar(&flagQueueVerb, "verb", "merge", "how to combine head with base: merge or rebase")
+	botFlagSet.DurationVar(&flagBotInterval, "interval", 0, "run the jobs every `interval` instead of once")
+	cleanupFlagSet.StringVar(&flagCleanupOlderThan, "older-than", "30d", "delete refs of operations older than this `age`, such as 30d or 12h")
+	cleanupFlagSet.BoolVar(&flagCleanupDryRun, "dry-run", false, "list the refs that would be deleted without deleting them")
+	foreachFlagSet.StringVar(&flagForeachRepos, "repos", "", "comma-separated repository paths or globs")
+	foreachFlagSet.StringVar(&flagForeachManifest, "manifest", "", "`file` listing repository paths, one per line")
+	foreachFlagSet.IntVar(&flagForeachJobs, "j", 4, "number of repositories to process in parallel")
+	watchFlagSet.BoolVar(&flagWatchAuto, "auto", false, "resolve conflicts with merde without asking")
+	watchFlagSet.BoolVar(&flagWatchNotify, "notify", false, "show a desktop notification when conflicts appear")
+	hookFlagSet.BoolVar(&flagHookAuto, "auto", false, "run merde continue automatically instead of asking (override with MERDE_HOOK_AUTO=0)")
+
+	// Commands that report as they go take --output after their name, too.
+	for _, fs := range []*flag.FlagSet{rootFlagSet, mergeFlagSet, rebaseFlagSet, reviewFlagSet, adoptFlagSet, backportFlagSet, cherryPickFlagSet, revertFlagSet, forwardportFlagSet, forkFlagSet, queueFlagSet, botFlagSet} {
+		fs.StringVar(&flagOutput, "output", "", "how to report progress and warnings: plain, rich, json, or quiet (default rich on a terminal, otherwise plain)")
+	}
+	for _, fs := range []*flag.FlagSet{rootFlagSet, mergeFlagSet, rebaseFlagSet} {
+		fs.BoolVar(&flagYes, "y", false, "accept every confirmation without asking, except deleting a repository's data")
+		fs.BoolVar(&flagYes, "yes", false, "accept every confirmation without asking, except deleting a repository's data")
+		fs.BoolVar(&flagNo, "no", false, "decline every confirmation without asking, to check what an operation would ask")
+		fs.BoolVar(&flagEphemeral, "ephemeral", false, "ask the server to delete uploaded objects as soon as it has resolved the conflicts, as with retention=ephemeral")
+		fs.StringVar(&flagLimitRate, "limit-rate", "", "limit uploads to `rate` bytes per second, such as 2m or 500k, as with the limit_rate config")
+	}
+	for _, fs := range []*flag.FlagSet{mergeFlagSet, rebaseFlagSet} {
+		fs.BoolVar(&flagAdopt, "adopt", false, "move the topic branch to the result right away, as merde adopt does, as with auto_adopt=on")
+		fs.BoolVar(&flagDryRun, "dry-run", false, "report the conflicting paths and the size of the pack, without uploading anything")
+		fs.BoolVar(&flagDetach, "detach", false, "once the pack is built, upload it and wait for the result in the background; watch with merde attach")
+		fs.StringVar(&flagReport, "report", "", "write a report of the operation to `file` (.md or .json)")
+		fs.StringVar(&flagTag, "tag", "", "create an annotated tag `name` on the resolved commit")
+		fs.BoolVar(&flagSign, "sign", false, "sign the tag created by --tag")
+		fs.IntVar(&flagPR, "pr", 0, "post the report as a comment on pull request `number` (default: the topic branch's open pull request, if a token for the repository's forge is configured)")
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/josharian/xc"
+)
+
+// A foreachResult is the outcome of running a merde command in one repository.
+type foreachResult struct {
+	repo     string
+	output   string
+	err      error
+	duration time.Duration
+}
+
+// foreachRepos returns the repositories named by the --repos and --manifest flags.
+// Entries in --repos may be globs.
+func foreachRepos() ([]string, error) {
+	var entries []string
+	for _, e := range strings.Split(flagForeachRepos, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			entries = append(entries, e)
+		}
+	}
+	if flagForeachManifest != "" {
+		data, err := os.ReadFile(flagForeachManifest)
+		if err != nil {
+			return nil, err
+		}
+		dir := filepath.Dir(flagForeachManifest)
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if !filepath.IsAbs(line) {
+				line = filepath.Join(dir, line) // manifest entries are relative to the manifest
+			}
+			entries = append(entries, line)
+		}
+	}
+	var repos []string
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		matches, err := filepath.Glob(e)
+		if err != nil {
+			return nil, err
+		}
+		if matches == nil {
+			return nil, fmt.Errorf("no repository matches %s", e)
+		}
+		for _, m := range matches {
+			if fi, err := os.Stat(m); err != nil || !fi.IsDir() || seen[m] {
+				continue
+			}
+			seen[m] = true
+			repos = append(repos, m)
+		}
+	}
+	if len(repos) == 0 {
+		return nil, fmt.Errorf("no repositories given; use --repos or --manifest")
+	}
+	return repos, nil
+}
+
+func doForeach(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return usageErrorf("merde foreach needs a merge or rebase command to run")
+	}
+	switch args[0] {
+	case "merge", "rebase":
+	default:
+		return fmt.Errorf("merde foreach runs merge or rebase, not %s", args[0])
+	}
+	repos, err := foreachRepos()
+	if err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	jobs := max(flagForeachJobs, 1)
+	ui.Status("running merde %s in %d repositories, %d at a time", strings.Join(args, " "), len(repos), jobs)
+
+	results := make([]*foreachResult, len(repos))
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	var printMu sync.Mutex
+	for i, repo := range repos {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			start := time.Now()
+			// Each repository gets its own process: operations are not safe to run concurrently in one.
+			out := new(bytes.Buffer)
+			err := xc.Command(ctx, exe, args...).
+				Dir(repo).
+				Stdout(out).
+				Stderr(out).
+				Describef("merde %s in %s", args[0], repo).
+				Run().
+				Wait()
+			r := &foreachResult{repo: repo, output: out.String(), err: err, duration: time.Since(start)}
+			results[i] = r
+			printMu.Lock()
+			defer printMu.Unlock()
+			status := "ok"
+			if err != nil {
+				status = "FAILED"
+			}
+			ui.Status("[%d/%d] %s: %s (%v)", i+1, len(repos), repo, status, r.duration.Round(time.Millisecond))
+		}()
+	}
+	wg.Wait()
+
+	var failed []*foreachResult
+	for _, r := range results {
+		if r.err != nil {
+			failed = append(failed, r)
+		}
+	}
+	ui.Status("summary: %d succeeded, %d failed", len(results)-len(failed), len(failed))
+	for _, r := range failed {
+		ui.Warn("%s failed:\n%s", r.repo, indent(strings.Split(strings.TrimSpace(r.output), "\n")))
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d repositories failed", len(failed), len(results))
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// A forge is a repository on a hosting service, such as GitHub or Bitbucket, with its pull requests.
+// Each kind of forge speaks its own API, so the deconflict flow need not know where a repository lives.
+type forge interface {
+	String() string   // names the repository, for messages
+	tokenKey() string // config key of the token for the forge's API; "" if none is needed
+
+	// findPullRequest returns the number of the open pull request whose head is branch, or 0 if there is none.
+	findPullRequest(ctx context.Context, branch string) (int, error)
+	// comment posts body, in Markdown, on pull request pr.
+	comment(ctx context.Context, pr int, body string) error
+	// openPullRequest opens a pull request from head into base and returns its number.
+	openPullRequest(ctx context.Context, title, head, base, body string) (int, error)
+	// parentURL returns the clone URL of the repository this one was forked from, or "" if it is not a fork.
+	parentURL(ctx context.Context) (string, error)
+}
+
+// forgeProviders recognize remote URLs, each for one kind of forge.
+// A provider returns nil for URLs that are not on its forge.
+// To support another forge, implement forge and add its provider here.
+var forgeProviders = []func(cfg *Config, url string) forge{
+	matchGitHub,
+	matchBitbucket,
+	matchBitbucketServer,
+	matchSourcehut,
+	matchGitLab,
+}
+
+// forgeFor returns the forge that url is on, or nil if it is not on a known forge.
+func forgeFor(cfg *Config, url string) forge {
+	for _, p := range forgeProviders {
+		if f := p(cfg, url); f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+// parseRemote parses a remote URL, including scp-like SSH remotes, user@host:path, which are not URLs.
+func parseRemote(remote string) (*url.URL, error) {
+	if !strings.Contains(remote, "://") {
+		userHost, path, ok := strings.Cut(remote, ":")
+		if !ok {
+			return nil, fmt.Errorf("remote %q is not a URL", remote)
+		}
+		remote = "ssh://" + userHost + "/" + path
+	}
+	return url.Parse(remote)
+}
+
+// forgeRemotes returns the urls of the repository's remotes that are on known forges,
+// those of the preferred remote first.
+func forgeRemotes(ctx context.Context, cfg *Config) ([]string, error) {
+	urls, err := cfg.Git.Remotes(ctx, preferredRemote(cfg))
+	if err != nil {
+		return nil, err
+	}
+	var known []string
+	for _, u := range urls {
+		if forgeFor(cfg, u) != nil {
+			known = append(known, u)
+		}
+	}
+	return known, nil
+}
+
+// repoForge returns the forge of the current repository: that of its first remote on a known forge.
+func repoForge(ctx context.Context, cfg *Config) (forge, error) {
+	urls, err := forgeRemotes(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("no remote on GitHub, Bitbucket, sourcehut, or GitLab found")
+	}
+	return forgeFor(cfg, urls[0]), nil
+}
+
+// hasToken reports whether the token f needs is configured.
+func hasToken(cfg *Config, f forge) bool {
+	return f.tokenKey() == "" || cfg.Get(f.tokenKey()) != ""
+}
+
+// maxCommentHunks bounds the resolved hunks quoted in a pull request comment,
+// which forges limit to about 64 KiB in all.
+const maxCommentHunks = 48 << 10
+
+// commentOnPullRequest posts the report of info as a collapsible comment on the pull request for info's topic branch,
+// with the hunks the server resolved quoted as a diff.
+// It does nothing unless the forge's token is configured.
+// The pull request is pr if non-zero, otherwise the open pull request whose head is the topic branch;
+// failing to find or comment on that one is only a warning, since nobody asked for the comment.
+func commentOnPullRequest(ctx context.Context, cfg *Config, info *deconflictRequestInfo, pr int) error {
+	f, err := repoForge(ctx, cfg)
+	if err != nil {
+		if pr != 0 {
+			return err
+		}
+		return nil
+	}
+	if !hasToken(cfg, f) {
+		if pr != 0 {
+			return fmt.Errorf("cannot comment on pull request #%d: no token configured for %s; run: merde config %s <token>", pr, f, f.tokenKey())
+		}
+		return nil
+	}
+	asked := pr != 0
+	if !asked {
+		branch := strings.TrimPrefix(info.topicRef, "refs/heads/")
+		pr, err = f.findPullRequest(ctx, branch)
+		if err != nil {
+			ui.Warn("not commenting on a pull request: looking up %s's pull request on %s: %v", branch, f, err)
+			return nil
+		}
+		if pr == 0 {
+			return nil
+		}
+	}
+	hunks, err := resolvedHunks(ctx, cfg, info)
+	if err != nil {
+		return err
+	}
+	r := makeReport(info)
+	body := fmt.Sprintf("<details>\n<summary>merde %s: %d conflicts, %d resolutions</summary>\n\n%s\n</details>\n",
+		r.Verb, len(r.Conflicts), len(r.Resolutions), r.markdown())
+	if hunks != "" {
+		if len(hunks) > maxCommentHunks {
+			hunks = hunks[:maxCommentHunks] + "\n... (truncated; see the full diff in the branch)\n"
+		}
+		body += fmt.Sprintf("\n<details>\n<summary>hunks resolved by the server</summary>\n\n%s\n</details>\n", fenced("diff", hunks))
+	}
+	err = f.comment(ctx, pr, body)
+	if err != nil {
+		if !asked {
+			ui.Warn("commenting on pull request #%d: %v", pr, err)
+			return nil
+		}
+		return fmt.Errorf("commenting on pull request #%d: %w", pr, err)
+	}
+	ui.Status("posted report to %s#%d", f, pr)
+	return nil
+}
+
+// resolvedHunks returns the diff of the paths the server resolved, from git's own merge of main and topic,
+// conflict markers and all, to the result: each hunk is one the server wrote.
+// For a rebase, it compares the branch tips. It returns "" if the server resolved nothing.
+func resolvedHunks(ctx context.Context, cfg *Config, info *deconflictRequestInfo) (string, error) {
+	if len(info.serverResolutions) == 0 || len(info.createdRefs) == 0 {
+		return "", nil
+	}
+	merged, _, err := cfg.Git.MergeTree(ctx, info.mainSHA, info.topicSHA)
+	if err != nil {
+		return "", err
+	}
+	var paths []string
+	for _, r := range info.serverResolutions {
+		paths = append(paths, r.Path)
+	}
+	return cfg.Git.Diff(ctx, merged, info.createdRefs[len(info.createdRefs)-1].sha, paths)
+}
+
+// fenced returns s as a Markdown code block in language lang,
+// fenced with more backticks than any run in s so that s cannot end the block early.
+func fenced(lang, s string) string {
+	fence := "```"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + strings.TrimSuffix(s, "\n") + "\n" + fence + "\n"
+}
+
+// openPullRequest opens a pull request proposing job's pushed result for job's topic branch,
+// unless one is already open, and returns its number.
+func openPullRequest(ctx context.Context, cfg *Config, job *botJob, op *operation) (int, error) {
+	f, err := repoForge(ctx, cfg)
+	if err != nil {
+		return 0, err
+	}
+	if !hasToken(cfg, f) {
+		return 0, fmt.Errorf("cannot open a pull request: no token configured for %s; run: merde config %s <token>", f, f.tokenKey())
+	}
+	head := strings.TrimPrefix(job.Ref, "refs/heads/")
+	pr, err := f.findPullRequest(ctx, head)
+	if err != nil || pr != 0 {
+		return pr, err
+	}
+	r := op.Report
+	title := fmt.Sprintf("merde %s of %s and %s", r.Verb, r.MainRef, r.TopicRef)
+	pr, err = f.openPullRequest(ctx, title, head, job.topicBranch(), string(r.markdown()))
+	if err != nil {
+		return 0, fmt.Errorf("opening pull request: %w", err)
+	}
+	return pr, nil
+}
+
+// noPullRequests implements the pull request methods of a forge whose pull requests merde cannot use.
+type noPullRequests struct {
+	why string
+}
+
+func (n noPullRequests) findPullRequest(ctx context.Context, branch string) (int, error) {
+	return 0, nil
+}
+
+func (n noPullRequests) comment(ctx context.Context, pr int, body string) error {
+	return fmt.Errorf("%s", n.why)
+}
+
+func (n noPullRequests) openPullRequest(ctx context.Context, title, head, base, body string) (int, error) {
+	return 0, fmt.Errorf("%s", n.why)
+}
+
+func (n noPullRequests) parentURL(ctx context.Context) (string, error) {
+	return "", fmt.Errorf("%s", n.why)
+}
+
+func (n noPullRequests) tokenKey() string {
+	return ""
+}
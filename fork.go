@@ -0,0 +1,105 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+)
+
+// ensureUpstream makes sure the upstream remote exists, adding it if fork is a fork on a known forge.
+func ensureUpstream(ctx context.Context, cfg *Config, upstream, fork string) error {
+	url, err := cfg.Git.RemoteURL(ctx, upstream)
+	if err != nil || url != "" {
+		return err
+	}
+	forkURL, err := cfg.Git.RemoteURL(ctx, fork)
+	if err != nil {
+		return err
+	}
+	f := forgeFor(cfg, forkURL)
+	if f == nil {
+		return fmt.Errorf("no remote %s, and %s is not on a known forge, so merde cannot find what it was forked from; add it with: git remote add %s <url>", upstream, fork, upstream)
+	}
+	parent, err := f.parentURL(ctx)
+	if err != nil {
+		return fmt.Errorf("looking up the repository %s was forked from: %w", fork, err)
+	}
+	if parent == "" {
+		return fmt.Errorf("no remote %s, and %s is not a fork; add it with: git remote add %s <url>", upstream, f, upstream)
+	}
+	ui.Status("adding remote %s for %s", upstream, parent)
+	return cfg.Git.AddRemote(ctx, upstream, parent)
+}
+
+// doFork brings a branch of the user's fork up to date with upstream's default branch and pushes it back to the fork.
+func doFork(ctx context.Context, args []string) error {
+	if len(args) > 1 {
+		return usageErrorf("merde fork takes at most one branch")
+	}
+	cfg, err := LoadDefault(ctx)
+	if err != nil {
+		return err
+	}
+	err = requireCleanGitStatus(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	branch := ""
+	if len(args) == 1 {
+		branch = args[0]
+	} else {
+		branch, err = cfg.Git.AbbrevRef(ctx, "HEAD")
+		if err != nil {
+			return err
+		}
+	}
+	upstream := cmp.Or(preferredRemote(cfg), "upstream")
+	err = ensureUpstream(ctx, cfg, upstream, flagForkRemote)
+	if err != nil {
+		return err
+	}
+	ui.Status("fetching %s", upstream)
+	err = cfg.Git.Fetch(ctx, upstream)
+	if err != nil {
+		return err
+	}
+	mainRef, err := cfg.Git.AbbrevRef(ctx, upstream+"/HEAD")
+	if err != nil {
+		err = cfg.Git.SetRemoteHead(ctx, upstream)
+		if err != nil {
+			return err
+		}
+		mainRef, err = cfg.Git.AbbrevRef(ctx, upstream+"/HEAD")
+		if err != nil {
+			return err
+		}
+	}
+	verb := "merge"
+	if flagForkRebase {
+		verb = "rebase"
+	}
+	ui.Status("plan: %s %s with %s, then push it to %s", verb, branch, mainRef, flagForkRemote)
+	op, err := deconflict(ctx, cfg, verb, mainRef, branch)
+	if err != nil {
+		return err
+	}
+	if flagForkNoPush {
+		return nil
+	}
+	// Only replace the fork's branch if it has not moved since it was last fetched.
+	expect, _ := cfg.Git.ResolveRef(ctx, "refs/remotes/"+flagForkRemote+"/"+branch)
+	err = adoptOperation(ctx, cfg, op)
+	if err != nil {
+		return err
+	}
+	err = cfg.Git.PushWithLease(ctx, flagForkRemote, op.result(), "refs/heads/"+branch, expect)
+	if err != nil {
+		return err
+	}
+	ui.Status("pushed %s to %s", branch, flagForkRemote)
+	return nil
+}
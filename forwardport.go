@@ -0,0 +1,51 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"strings"
+)
+
+func doForwardport(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return usageErrorf("merde forwardport takes exactly one release branch or commit range")
+	}
+	cfg, err := LoadDefault(ctx)
+	if err != nil {
+		return err
+	}
+	onto := flagForwardportOnto
+	if onto == "" {
+		onto, err = defaultMain(ctx, cfg)
+		if err != nil {
+			return err
+		}
+	}
+	// A release branch means every commit on it that is not on onto.
+	rangeSpec := args[0]
+	_, source, isRange := strings.Cut(rangeSpec, "..")
+	if isRange {
+		source = cmp.Or(strings.TrimPrefix(source, "."), "HEAD")
+	} else {
+		source = rangeSpec
+		rangeSpec = onto + ".." + source
+	}
+	commits, err := cfg.Git.CommitsInRange(ctx, rangeSpec)
+	if err != nil {
+		return err
+	}
+	// Commits that were already forward-ported, or landed on both branches,
+	// make the same change as a commit on onto that is not on the release branch.
+	landed, err := cfg.Git.CherryMarks(ctx, onto, source)
+	if err != nil {
+		return err
+	}
+	_, err = replayCommits(ctx, cfg, "forwardport", commits, onto, func(commit string) bool {
+		return landed[commit]
+	})
+	return err
+}
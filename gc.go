@@ -0,0 +1,120 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gcRunning reports whether git gc or git maintenance is running in the repository at commonDir,
+// and if so, what is running.
+// It follows git's own rules: gc.pid counts for 12 hours, and only processes on this host can be checked.
+func gcRunning(commonDir string) (string, bool) {
+	path := filepath.Join(commonDir, "gc.pid")
+	if fi, err := os.Stat(path); err == nil && time.Since(fi.ModTime()) < 12*time.Hour {
+		data, _ := os.ReadFile(path)
+		pidStr, host, _ := strings.Cut(strings.TrimSpace(string(data)), " ")
+		pid, err := strconv.Atoi(pidStr)
+		myHost, _ := os.Hostname()
+		if err == nil && pid != os.Getpid() && (host != myHost || processAlive(pid)) {
+			return fmt.Sprintf("git gc (pid %d on %s)", pid, host), true
+		}
+	}
+	if fi, err := os.Stat(filepath.Join(commonDir, "objects", "maintenance.lock")); err == nil && time.Since(fi.ModTime()) < time.Hour {
+		return "git maintenance", true
+	}
+	return "", false
+}
+
+// holdGC waits up to --lock-wait for any running git gc or git maintenance to finish,
+// then keeps git gc from starting until the returned function is called,
+// so that gc never prunes or repacks objects while merde is writing them.
+// It takes gc.pid the way git gc itself does.
+func holdGC(ctx context.Context, cfg *Config) (func(), error) {
+	commonDir, err := cfg.Git.CommonDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	host, _ := os.Hostname()
+	mine := fmt.Sprintf("%d %s", os.Getpid(), host)
+	path := filepath.Join(commonDir, "gc.pid")
+	deadline := time.Now().Add(flagLockWait)
+	waiting := false
+	for {
+		running, ok := gcRunning(commonDir)
+		if !ok {
+			f, err := os.OpenFile(path+".lock", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+			if err == nil {
+				_, err = f.WriteString(mine)
+				f.Close()
+				if err == nil {
+					err = os.Rename(path+".lock", path)
+				}
+				if err != nil {
+					os.Remove(path + ".lock")
+					return nil, err
+				}
+				return func() {
+					if data, _ := os.ReadFile(path); string(data) == mine {
+						os.Remove(path)
+					}
+				}, nil
+			}
+			if !errors.Is(err, os.ErrExist) {
+				return nil, err
+			}
+			running = "git gc"
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is running in this repository; wait for it, or retry with --lock-wait", running)
+		}
+		if !waiting {
+			ui.Status("waiting for %s to finish...", running)
+			waiting = true
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+const (
+	objectsLoose = "loose"
+	objectsPack  = "pack"
+)
+
+// storeObjects writes the objects in pack, received from the server, to the repository as configured.
+func storeObjects(ctx context.Context, cfg *Config, pack *bytes.Buffer) error {
+	switch mode := cfg.Get(objectsKey); mode {
+	case objectsLoose:
+		return cfg.Git.UnpackObjects(ctx, pack)
+	case objectsPack:
+		return cfg.Git.IndexPack(ctx, pack)
+	default:
+		return fmt.Errorf("invalid %s %q: want %s or %s", objectsKey, mode, objectsLoose, objectsPack)
+	}
+}
+
+// tidyObjects packs the loose objects an operation wrote, if the user asked for it.
+// A failure is only a warning: the objects are all there, just loose.
+func tidyObjects(ctx context.Context, cfg *Config) {
+	if cfg.Get(maintenanceKey) != "on" {
+		return
+	}
+	err := cfg.Git.RunMaintenance(ctx, "loose-objects")
+	if err != nil {
+		ui.Warn("packing loose objects: %v", err)
+	}
+}
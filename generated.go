@@ -0,0 +1,134 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"merde.ai/git"
+)
+
+// Generated-file policies.
+const (
+	generatedMain  = "main"  // take main's version
+	generatedTopic = "topic" // take topic's version
+	generatedLeave = "leave" // leave the path conflicted
+)
+
+// A generatedPath is a generated path modified on both sides, and what to do about it.
+type generatedPath struct {
+	path   string
+	policy string
+}
+
+// String formats gp for the server as "policy:path".
+func (gp *generatedPath) String() string {
+	return gp.policy + ":" + gp.path
+}
+
+// isGenerated reports whether p matches one of the configured generated-file patterns.
+// Patterns without a slash match the file name in any directory.
+func isGenerated(cfg *Config, p string) bool {
+	for _, pattern := range strings.Split(cfg.Get(generatedKey), ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		name := p
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(p)
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveGenerated applies the generated-file policy to generated paths modified on both sides.
+// Generated files should be regenerated, not merged, so they never go to the server for resolution.
+func resolveGenerated(ctx context.Context, cfg *Config, info *deconflictRequestInfo) error {
+	policy := cfg.Get(generatedPolicyKey)
+	switch policy {
+	case generatedMain, generatedTopic, generatedLeave:
+	default:
+		return fmt.Errorf("invalid %s %q, want one of %s, %s, %s", generatedPolicyKey, policy, generatedMain, generatedTopic, generatedLeave)
+	}
+	for _, p := range unresolved(info) {
+		if !isGenerated(cfg, p) {
+			continue
+		}
+		info.generated = append(info.generated, &generatedPath{path: p, policy: policy})
+		if policy == generatedLeave {
+			ui.Status("generated file %s will be left conflicted", p)
+			continue
+		}
+		sha := info.mainSHA
+		if policy == generatedTopic {
+			sha = info.topicSHA
+		}
+		data, err := cfg.Git.ReadBlob(ctx, sha, p)
+		if err != nil {
+			return err
+		}
+		err = resolveLocally(ctx, cfg, info, p, data, "generated, took "+policy)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// regenerate runs the configured regeneration command against the resolved commit sha in a temporary worktree.
+// If that changes anything, it commits the changes and moves ref to the new commit.
+func regenerate(ctx context.Context, cfg *Config, info *deconflictRequestInfo, ref, sha string) error {
+	command := cfg.Get(regenerateKey)
+	if command == "" || len(info.generated) == 0 {
+		return nil
+	}
+	dir, err := os.MkdirTemp("", "merde-verify-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(git.LongPath(dir))
+	err = cfg.Git.AddWorktree(ctx, dir, sha)
+	if err != nil {
+		return err
+	}
+	defer cfg.Git.RemoveWorktree(ctx, dir)
+	ui.Status("regenerating: %s", command)
+	out, err := cfg.Git.Shell(ctx, command).
+		Dir(dir).
+		CombineOutput().
+		Describef("regenerate generated files").
+		Run().
+		String()
+	if err != nil {
+		// The command's output explains the failure, whatever the output mode.
+		fmt.Fprint(os.Stderr, out)
+		return err
+	}
+	fmt.Fprint(ui.Output(), out)
+	wt := cfg.Git.InDir(dir)
+	regenerated, err := wt.CommitAll(ctx, "Regenerate generated files\n\nRan: "+command)
+	if err != nil {
+		return err
+	}
+	if regenerated == "" {
+		ui.Status("generated files are up to date")
+		return nil
+	}
+	ui.Status("committed regenerated files as %s", regenerated)
+	err = cfg.Git.UpdateRef(ctx, ref, regenerated, sha)
+	if err != nil {
+		return err
+	}
+	info.createdRefs = append(info.createdRefs, createdRef{ref: ref, sha: regenerated, old: sha})
+	return nil
+}
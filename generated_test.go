@@ -0,0 +1,72 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"merde.ai/git/gittest"
+)
+
+// captureOutput points os.Stdout and os.Stderr at files for the rest of the test,
+// along with ui and prompting, which modes such as lsp change, and returns functions that read the files.
+func captureOutput(t *testing.T) (stdout, stderr func() string) {
+	t.Helper()
+	savedStdout, savedStderr, savedUI, savedInteractive := os.Stdout, os.Stderr, ui, interactive
+	t.Cleanup(func() {
+		os.Stdout, os.Stderr, ui, interactive = savedStdout, savedStderr, savedUI, savedInteractive
+	})
+	capture := func(name string) (*os.File, func() string) {
+		f, err := os.Create(filepath.Join(t.TempDir(), name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { f.Close() })
+		return f, func() string {
+			data, err := os.ReadFile(f.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return string(data)
+		}
+	}
+	os.Stdout, stdout = capture("stdout")
+	os.Stderr, stderr = capture("stderr")
+	ui = plainRenderer{out: os.Stdout}
+	return stdout, stderr
+}
+
+func TestRegenerateWithStdoutTakenOver(t *testing.T) {
+	cfg, dir, run := testRepo(t)
+	ctx := context.Background()
+	sha := gittest.Commit(t, dir, run, "resolved", map[string]string{"gen.txt": "stale\n"})
+	const ref = "refs/merde/result"
+	run("update-ref", ref, sha)
+	t.Setenv(configEnv(regenerateKey), "echo output from the generator && echo fresh > gen.txt")
+	stdout, stderr := captureOutput(t)
+	err := takeOverStdout()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := &deconflictRequestInfo{generated: []*generatedPath{{path: "gen.txt", policy: generatedMain}}}
+	err = regenerate(ctx, cfg, info, ref, sha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := stdout(); out != "" {
+		t.Errorf("regenerate wrote %q to stdout, which the protocol owns", out)
+	}
+	if out := stderr(); !strings.Contains(out, "output from the generator") {
+		t.Errorf("regenerate's stderr = %q; want the generator's output", out)
+	}
+	if got := run("show", ref+":gen.txt"); got != "fresh" {
+		t.Errorf("%s:gen.txt = %q after regenerating; want fresh", ref, got)
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/josharian/xc"
+)
+
+// A command is a git command being built, as with xc.Builder,
+// that lets its Git trace it and report on it while it runs.
+type command struct {
+	g    *Git
+	b    *xc.Builder
+	args []string
+	desc string
+}
+
+func (g *Git) newCommand(ctx context.Context) *command {
+	return &command{g: g, b: xc.Command(ctx, g.bin).Dir(g.root)}
+}
+
+func (c *command) AppendArgs(args ...string) *command {
+	c.b.AppendArgs(args...)
+	c.args = append(c.args, args...)
+	return c
+}
+
+func (c *command) AppendEnv(env ...string) *command {
+	c.b.AppendEnv(env...)
+	return c
+}
+
+func (c *command) Dir(dir string) *command {
+	c.b.Dir(dir)
+	return c
+}
+
+func (c *command) Stdin(r io.Reader) *command {
+	c.b.Stdin(r)
+	return c
+}
+
+func (c *command) StdinString(s string) *command {
+	c.b.StdinString(s)
+	return c
+}
+
+func (c *command) StdinBytes(buf []byte) *command {
+	c.b.StdinBytes(buf)
+	return c
+}
+
+func (c *command) Stdout(w io.Writer) *command {
+	c.b.Stdout(w)
+	return c
+}
+
+func (c *command) Stderr(w io.Writer) *command {
+	c.b.Stderr(w)
+	return c
+}
+
+func (c *command) Describe(description string) *command {
+	c.b.Describe(description)
+	c.desc = description
+	return c
+}
+
+func (c *command) Describef(format string, args ...any) *command {
+	return c.Describe(fmt.Sprintf(format, args...))
+}
+
+// Run starts the command, as xc.Builder.Run does.
+func (c *command) Run() *result {
+	// Errors should point at the code that ran the command, as xc's do, not at this wrapper.
+	var pc [1]uintptr
+	runtime.Callers(2, pc[:])
+	frame, _ := runtime.CallersFrames(pc[:]).Next()
+	if c.g.trace != nil {
+		c.g.trace(c.args)
+	}
+	desc := c.desc
+	if desc == "" && len(c.args) > 0 {
+		desc = "run " + c.args[0]
+	}
+	start := time.Now()
+	r := &result{r: c.b.Run(), frame: frame}
+	stopWatching := c.g.watch(desc)
+	var once sync.Once
+	r.stop = func() {
+		once.Do(func() {
+			stopWatching()
+			if c.g.timing != nil {
+				c.g.timing(desc, c.args, time.Since(start))
+			}
+		})
+	}
+	return r
+}
+
+// A result is the result of a command, as with xc.Result.
+type result struct {
+	r     *xc.Result
+	frame runtime.Frame
+	stop  func()
+}
+
+func (r *result) AllowExitCodes(codes ...int) *result {
+	r.r.AllowExitCodes(codes...)
+	return r
+}
+
+func (r *result) TrimSpace() *result {
+	r.r.TrimSpace()
+	return r
+}
+
+func (r *result) Wait() error {
+	return r.finish(r.r.Wait())
+}
+
+func (r *result) String() (string, error) {
+	s, err := r.r.String()
+	return s, r.finish(err)
+}
+
+func (r *result) Bytes() ([]byte, error) {
+	b, err := r.r.Bytes()
+	return b, r.finish(err)
+}
+
+func (r *result) Split(sep string) ([]string, error) {
+	s, err := r.r.Split(sep)
+	return s, r.finish(err)
+}
+
+func (r *result) ExitCode() int {
+	code := r.r.ExitCode()
+	r.finish(nil)
+	return code
+}
+
+// finish notes that the command has finished, and passes err through.
+func (r *result) finish(err error) error {
+	r.stop()
+	var xe *xc.Error
+	if errors.As(err, &xe) {
+		xe.Frame = r.frame
+	}
+	return err
+}
+
+// SetTrace arranges for f to be called with the arguments of each git command as it starts.
+func (g *Git) SetTrace(f func(args []string)) {
+	g.trace = f
+}
+
+// SetTiming arranges for f to be called as each git command finishes,
+// with what it did, its arguments, and how long it took.
+func (g *Git) SetTiming(f func(desc string, args []string, elapsed time.Duration)) {
+	g.timing = f
+}
+
+// SetHeartbeat arranges for f to be called every interval while a git command runs,
+// beginning once it has run for interval, with what the command is doing and how long it has run.
+func (g *Git) SetHeartbeat(interval time.Duration, f func(desc string, elapsed time.Duration)) {
+	g.heartbeatInterval = interval
+	g.heartbeat = f
+}
+
+// watch calls the heartbeat function for the command described by desc until the returned function is called.
+func (g *Git) watch(desc string) (stop func()) {
+	if g.heartbeat == nil {
+		return func() {}
+	}
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(g.heartbeatInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				g.heartbeat(desc, time.Since(start))
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,358 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ReplaceBlobs creates a copy of commit whose tree has the given paths replaced by new blobs.
+// The copy has the same parents, author, and message as commit.
+// It returns the new commit's hash.
+func (g *Git) ReplaceBlobs(ctx context.Context, commit string, blobs map[string]string) (string, error) {
+	tree, err := g.ReplaceBlobsTree(ctx, commit, blobs)
+	if err != nil {
+		return "", err
+	}
+	return g.CopyCommit(ctx, commit, tree, "")
+}
+
+// ReplaceBlobsTree writes a copy of commit's tree with the given paths replaced by new blobs,
+// and returns the new tree's hash.
+func (g *Git) ReplaceBlobsTree(ctx context.Context, commit string, blobs map[string]string) (string, error) {
+	dir, err := os.MkdirTemp("", "merde-index-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	index := "GIT_INDEX_FILE=" + filepath.Join(dir, "index")
+
+	err = g.envCommand(ctx, index).
+		AppendArgs("read-tree", commit).
+		Describef("read tree of %s", commit).
+		Run().
+		Wait()
+	if err != nil {
+		return "", err
+	}
+	var paths []string
+	for p := range blobs {
+		paths = append(paths, p)
+	}
+	modes, err := g.PathModes(ctx, commit, paths)
+	if err != nil {
+		return "", err
+	}
+	for p, sha := range blobs {
+		mode, ok := modes[p]
+		if !ok {
+			return "", fmt.Errorf("%s not found in %s", p, commit)
+		}
+		err = g.envCommand(ctx, index).
+			AppendArgs("update-index", "--cacheinfo", mode+","+sha+","+p).
+			Describef("replace %s", p).
+			Run().
+			Wait()
+		if err != nil {
+			return "", err
+		}
+	}
+	return g.envCommand(ctx, index).
+		AppendArgs("write-tree").
+		Describe("write tree").
+		Run().
+		TrimSpace().
+		String()
+}
+
+// CommitTree creates a commit of tree with the given message and parents,
+// with the current user as author and committer.
+// It returns the new commit's hash.
+func (g *Git) CommitTree(ctx context.Context, tree, message string, parents ...string) (string, error) {
+	if !strings.HasSuffix(message, "\n") {
+		message += "\n"
+	}
+	cmd := g.baseCommand(ctx).AppendArgs("commit-tree", tree)
+	for _, p := range parents {
+		cmd = cmd.AppendArgs("-p", p)
+	}
+	return cmd.
+		StdinString(message).
+		Describe("create commit").
+		Run().
+		TrimSpace().
+		String()
+}
+
+// Tree returns the hash of commit's tree.
+func (g *Git) Tree(ctx context.Context, commit string) (string, error) {
+	return g.baseCommand(ctx).
+		AppendArgs("rev-parse", "--verify", "--end-of-options", commit+"^{tree}").
+		Describef("read tree of %s", commit).
+		Run().
+		TrimSpace().
+		String()
+}
+
+// CopyCommit creates a commit with the same parents and author as commit, but with the given tree.
+// If message is empty, commit's message is reused.
+// It returns the new commit's hash.
+func (g *Git) CopyCommit(ctx context.Context, commit, tree, message string) (string, error) {
+	return g.copyCommit(ctx, commit, tree, message, nil)
+}
+
+// CopyCommitOnto creates a commit with the same author and message as commit, but with the given tree and parent,
+// as when replaying commit after rewriting its parent.
+// It returns the new commit's hash.
+func (g *Git) CopyCommitOnto(ctx context.Context, commit, tree, parent string) (string, error) {
+	return g.copyCommit(ctx, commit, tree, "", []string{parent})
+}
+
+// copyCommit copies commit with the given tree, message, and parents.
+// An empty message means commit's own, and nil parents commit's own.
+func (g *Git) copyCommit(ctx context.Context, commit, tree, message string, parents []string) (string, error) {
+	meta, err := g.baseCommand(ctx).
+		AppendArgs("log", "-1", "--format=%P%x00%an%x00%ae%x00%ad%x00%B", "--date=raw", commit).
+		Describef("read metadata of %s", commit).
+		Run().
+		String()
+	if err != nil {
+		return "", err
+	}
+	fields := strings.SplitN(meta, "\x00", 5)
+	if len(fields) != 5 {
+		return "", fmt.Errorf("unexpected commit metadata for %s", commit)
+	}
+	name, email, date := fields[1], fields[2], fields[3]
+	if parents == nil {
+		parents = strings.Fields(fields[0])
+	}
+	if message == "" {
+		message = fields[4]
+	}
+	cmd := g.envCommand(ctx, "GIT_AUTHOR_NAME="+name, "GIT_AUTHOR_EMAIL="+email, "GIT_AUTHOR_DATE="+date).
+		AppendArgs("commit-tree", tree)
+	for _, p := range parents {
+		cmd = cmd.AppendArgs("-p", p)
+	}
+	return cmd.
+		StdinString(message).
+		Describef("copy commit %s", commit).
+		Run().
+		TrimSpace().
+		String()
+}
+
+// Recommit creates a commit with the same tree and parents as commit,
+// but with the given message and the current user as author and committer.
+// It returns the new commit's hash.
+func (g *Git) Recommit(ctx context.Context, commit, message string) (string, error) {
+	meta, err := g.baseCommand(ctx).
+		AppendArgs("log", "-1", "--format=%T %P", commit).
+		Describef("read metadata of %s", commit).
+		Run().
+		TrimSpace().
+		String()
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(meta)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("unexpected commit metadata for %s", commit)
+	}
+	if !strings.HasSuffix(message, "\n") {
+		message += "\n"
+	}
+	cmd := g.baseCommand(ctx).AppendArgs("commit-tree", fields[0])
+	for _, p := range fields[1:] {
+		cmd = cmd.AppendArgs("-p", p)
+	}
+	return cmd.
+		StdinString(message).
+		Describef("recommit %s", commit).
+		Run().
+		TrimSpace().
+		String()
+}
+
+// Editor returns the command the user has configured for editing commit messages.
+func (g *Git) Editor(ctx context.Context) (string, error) {
+	return g.baseCommand(ctx).
+		AppendArgs("var", "GIT_EDITOR").
+		Run().
+		TrimSpace().
+		String()
+}
+
+// Parents returns the parents of commit.
+func (g *Git) Parents(ctx context.Context, commit string) ([]string, error) {
+	out, err := g.baseCommand(ctx).
+		AppendArgs("log", "-1", "--format=%P", commit).
+		Describef("read parents of %s", commit).
+		Run().
+		TrimSpace().
+		String()
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(out), nil
+}
+
+// CommitsWithParents returns the commits reachable from tip but not from exclude, newest first.
+// Each is given as its hash followed by its parents' hashes.
+func (g *Git) CommitsWithParents(ctx context.Context, exclude, tip string) ([][]string, error) {
+	lines, err := splitLines(g.baseCommand(ctx).
+		AppendArgs("rev-list", "--parents", tip, "--not", exclude, "--").
+		Describef("list commits in %s..%s", exclude, tip).
+		Run().
+		TrimSpace().
+		String())
+	if err != nil {
+		return nil, err
+	}
+	var commits [][]string
+	for _, line := range lines {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			commits = append(commits, fields)
+		}
+	}
+	return commits, nil
+}
+
+// CherryPickCommit creates a copy of orig with the given tree and parent, as git cherry-pick -x would:
+// orig's author and message, with a "(cherry picked from commit ...)" line appended,
+// and the current user as committer.
+// It returns the new commit's hash.
+func (g *Git) CherryPickCommit(ctx context.Context, orig, tree, parent string) (string, error) {
+	meta, err := g.baseCommand(ctx).
+		AppendArgs("log", "-1", "--format=%an%x00%ae%x00%ad%x00%B", "--date=raw", orig).
+		Describef("read metadata of %s", orig).
+		Run().
+		String()
+	if err != nil {
+		return "", err
+	}
+	fields := strings.SplitN(meta, "\x00", 4)
+	if len(fields) != 4 {
+		return "", fmt.Errorf("unexpected commit metadata for %s", orig)
+	}
+	name, email, date, message := fields[0], fields[1], fields[2], strings.TrimRight(fields[3], "\n")
+	message += "\n\n(cherry picked from commit " + orig + ")\n"
+	return g.envCommand(ctx, "GIT_AUTHOR_NAME="+name, "GIT_AUTHOR_EMAIL="+email, "GIT_AUTHOR_DATE="+date).
+		AppendArgs("commit-tree", tree, "-p", parent).
+		StdinString(message).
+		Describef("cherry-pick %s", orig).
+		Run().
+		TrimSpace().
+		String()
+}
+
+// CommitsInRange returns the non-merge commits in rangeSpec, oldest first.
+// A rangeSpec without ".." is a single commit.
+func (g *Git) CommitsInRange(ctx context.Context, rangeSpec string) ([]string, error) {
+	if !strings.Contains(rangeSpec, "..") {
+		rangeSpec += "^!"
+	}
+	lines, err := splitLines(g.baseCommand(ctx).
+		AppendArgs("rev-list", "--reverse", "--no-merges", rangeSpec, "--").
+		Describef("list commits in %s", rangeSpec).
+		Run().
+		TrimSpace().
+		String())
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(lines, func(s string) bool { return s == "" }), nil
+}
+
+// CherryMarks returns the non-merge commits reachable from head but not from upstream,
+// each mapped to whether a commit reachable from upstream but not from head makes the same change,
+// as git cherry reports. Git compares the changes itself, without external diff drivers or textconv filters.
+func (g *Git) CherryMarks(ctx context.Context, upstream, head string) (map[string]bool, error) {
+	lines, err := splitLines(g.baseCommand(ctx).
+		AppendArgs("rev-list", "--cherry-mark", "--right-only", "--no-merges", upstream+"..."+head, "--").
+		Describef("find commits of %s..%s already in %s", upstream, head, upstream).
+		Run().
+		TrimSpace().
+		String())
+	if err != nil {
+		return nil, err
+	}
+	marks := make(map[string]bool)
+	for _, line := range lines {
+		if len(line) > 1 {
+			marks[line[1:]] = line[0] == '='
+		}
+	}
+	return marks, nil
+}
+
+// ApplyToTree applies diff, as from git diff --binary, to treeish and returns the resulting tree.
+// It touches neither the index nor the working tree.
+func (g *Git) ApplyToTree(ctx context.Context, treeish, diff string) (string, error) {
+	dir, err := os.MkdirTemp("", "merde-index-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	index := "GIT_INDEX_FILE=" + filepath.Join(dir, "index")
+
+	err = g.envCommand(ctx, index).
+		AppendArgs("read-tree", treeish).
+		Describef("read tree of %s", treeish).
+		Run().
+		Wait()
+	if err != nil {
+		return "", err
+	}
+	if diff != "" {
+		err = g.envCommand(ctx, index).
+			AppendArgs("apply", "--cached", "--whitespace=nowarn").
+			StdinString(diff).
+			Describef("apply patch to %s", treeish).
+			Run().
+			Wait()
+		if err != nil {
+			return "", err
+		}
+	}
+	return g.envCommand(ctx, index).
+		AppendArgs("write-tree").
+		Describe("write tree").
+		Run().
+		TrimSpace().
+		String()
+}
+
+// WriteCommit writes the raw commit object data and returns its hash.
+func (g *Git) WriteCommit(ctx context.Context, data []byte) (string, error) {
+	return g.baseCommand(ctx).
+		AppendArgs("hash-object", "-t", "commit", "-w", "--stdin").
+		StdinBytes(data).
+		Describe("write commit").
+		Run().
+		TrimSpace().
+		String()
+}
+
+// VerifyConnected checks that every object reachable from tip but not from exclude is in the repository,
+// so that nothing a result refers to is missing. The objects' contents were checked as they arrived:
+// UnpackObjects and IndexPack hash every incoming object and reject malformed ones.
+func (g *Git) VerifyConnected(ctx context.Context, tip string, exclude ...string) error {
+	args := []string{"rev-list", "--objects", "--quiet", tip}
+	for _, e := range exclude {
+		args = append(args, "^"+e)
+	}
+	return g.baseCommand(ctx).
+		AppendArgs(args...).
+		Describef("check that the objects of %s are all present", tip).
+		Run().
+		Wait()
+}
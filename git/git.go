@@ -7,34 +7,113 @@ package git
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
-
-	"github.com/josharian/xc"
+	"time"
 )
 
 type Git struct {
 	bin  string
 	root string
+	env  []string // environment for git commands; nil means the current environment
+
+	trace             func(args []string)                      // called as each command starts; see SetTrace
+	heartbeat         func(desc string, elapsed time.Duration) // called while a command runs; see SetHeartbeat
+	heartbeatInterval time.Duration
+	timing            func(desc string, args []string, elapsed time.Duration) // called as each command finishes; see SetTiming
 }
 
+// repoEnv lists the environment variables that locate a repository.
+// Git resolves relative values against its working directory, which may not be ours.
+var repoEnv = []string{"GIT_DIR", "GIT_WORK_TREE", "GIT_COMMON_DIR", "GIT_INDEX_FILE"}
+
 func NewGit(ctx context.Context, bin string) (*Git, error) {
-	bin, err := gitExe(bin)
+	bin, err := Exe(bin)
 	if err != nil {
 		return nil, err
 	}
 	git := &Git{bin: bin}
+	for _, key := range repoEnv {
+		val := os.Getenv(key)
+		if val == "" || filepath.IsAbs(val) {
+			continue
+		}
+		abs, err := filepath.Abs(val)
+		if err != nil {
+			return nil, err
+		}
+		git.env = append(git.environ(), key+"="+abs)
+	}
 	root, err := git.RootDir(ctx)
+	if dir, ok := dubiousOwnership(err); ok {
+		return nil, &UnsafeRepoError{Dir: dir}
+	}
 	if err != nil {
-		return nil, err
+		// Bare repositories (for example GIT_DIR without GIT_WORK_TREE) have no root;
+		// run commands where we are.
+		_, gitDirErr := git.GitDir(ctx)
+		if gitDirErr != nil {
+			return nil, err
+		}
+		root = ""
 	}
 	git.root = root
 	return git, nil
 }
 
-func gitExe(bin string) (string, error) {
+// UnsafeRepoError reports that git refuses to use a repository owned by another user
+// because it is not listed in safe.directory.
+type UnsafeRepoError struct {
+	Dir string
+}
+
+func (e *UnsafeRepoError) Error() string {
+	return fmt.Sprintf("git refuses to use the repository at %s because another user owns it; if you trust it, run:\n\tgit config --global --add safe.directory %s", e.Dir, e.Dir)
+}
+
+// dubiousOwnership reports whether err is git's safe.directory refusal, and the repository it names.
+func dubiousOwnership(err error) (string, bool) {
+	if err == nil {
+		return "", false
+	}
+	_, rest, ok := strings.Cut(err.Error(), "detected dubious ownership in repository at '")
+	if !ok {
+		return "", false
+	}
+	dir, _, _ := strings.Cut(rest, "'")
+	return dir, true
+}
+
+// splitLines splits git's output into lines, tolerating CRLF line endings.
+// It passes err through, so that it can wrap a command's output directly.
+func splitLines(out string, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(out, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines, nil
+}
+
+// environ returns the environment for git commands.
+func (g *Git) environ() []string {
+	if g.env != nil {
+		return g.env
+	}
+	return os.Environ()
+}
+
+// Exe returns the git binary to use: bin if set, otherwise git from PATH or a standard install location.
+func Exe(bin string) (string, error) {
 	if bin != "" {
 		return bin, nil
 	}
@@ -44,12 +123,27 @@ func gitExe(bin string) (string, error) {
 			return bin, nil
 		}
 	}
+	for _, bin := range gitInstallPaths() {
+		_, err := os.Stat(bin)
+		if err == nil {
+			return bin, nil
+		}
+	}
 	return "", fmt.Errorf("git[.exe] not found in PATH")
 }
 
-// baseCommand constructs an xc git command.
-func (g *Git) baseCommand(ctx context.Context) *xc.Builder {
-	return xc.Command(ctx, g.bin).Dir(g.root)
+// baseCommand constructs a git command.
+func (g *Git) baseCommand(ctx context.Context) *command {
+	cmd := g.newCommand(ctx)
+	if g.env != nil {
+		cmd = cmd.AppendEnv(g.env...)
+	}
+	return cmd
+}
+
+// envCommand constructs a git command with env added to the current environment.
+func (g *Git) envCommand(ctx context.Context, env ...string) *command {
+	return g.newCommand(ctx).AppendEnv(g.environ()...).AppendEnv(env...)
 }
 
 func (g *Git) Version(ctx context.Context) (string, error) {
@@ -72,7 +166,7 @@ func (g *Git) RootDir(ctx context.Context) (string, error) {
 
 func (g *Git) GitDir(ctx context.Context) (string, error) {
 	return g.baseCommand(ctx).
-		AppendArgs("rev-parse", "--git-dir").
+		AppendArgs("rev-parse", "--absolute-git-dir").
 		Describe("get git dir").
 		Run().
 		TrimSpace().
@@ -80,31 +174,34 @@ func (g *Git) GitDir(ctx context.Context) (string, error) {
 }
 
 // Remotes returns all remote urls.
-func (g *Git) Remotes(ctx context.Context) ([]string, error) {
-	remotes, err := g.baseCommand(ctx).
-		AppendArgs("remote").
-		Describef("list remotes").
-		Run().
-		TrimSpace().
-		Split("\n")
+// If preferred names a remote, its urls come first.
+func (g *Git) Remotes(ctx context.Context, preferred string) ([]string, error) {
+	remotes, err := splitLines(
+		g.baseCommand(ctx).
+			AppendArgs("remote").
+			Describef("list remotes").
+			Run().
+			TrimSpace().
+			String())
 	if err != nil {
 		return nil, err
 	}
+	if i := slices.Index(remotes, preferred); i > 0 {
+		remotes = slices.Insert(slices.Delete(remotes, i, i+1), 0, preferred)
+	}
 	var all []string
 	for _, remote := range remotes {
-		urls, err := g.baseCommand(ctx).
-			AppendArgs("remote", "get-url", "--all", remote).
-			Describef("get URLs for remote %s", remote).
-			Run().
-			TrimSpace().
-			Split("\n")
+		urls, err := splitLines(
+			g.baseCommand(ctx).
+				AppendArgs("remote", "get-url", "--all", remote).
+				Describef("get URLs for remote %s", remote).
+				Run().
+				TrimSpace().
+				String())
 		if err != nil {
 			continue
 		}
 		for _, u := range urls {
-			if !strings.Contains(u, "github.com") && !strings.Contains(u, "gitlab.com") {
-				continue
-			}
 			// Quadratic but simpler, and nobody has _that_ many remotes. Right?
 			if !slices.Contains(all, u) {
 				all = append(all, u)
@@ -116,13 +213,14 @@ func (g *Git) Remotes(ctx context.Context) ([]string, error) {
 
 // MergeBases returns the merge bases of the given commits.
 func (g *Git) MergeBases(ctx context.Context, commits []string) ([]string, error) {
-	return g.baseCommand(ctx).
-		AppendArgs("merge-base", "--all").
-		AppendArgs(commits...).
-		Describef("get merge bases for %v", commits).
-		Run().
-		TrimSpace().
-		Split("\n")
+	return splitLines(
+		g.baseCommand(ctx).
+			AppendArgs("merge-base", "--all").
+			AppendArgs(commits...).
+			Describef("get merge bases for %v", commits).
+			Run().
+			TrimSpace().
+			String())
 }
 
 // UniqueAncestorMergeBase recursively finds merge bases of the given commits until there is only one.
@@ -144,10 +242,11 @@ func (g *Git) UniqueAncestorMergeBase(ctx context.Context, commits []string) (st
 }
 
 // ResolveRef resolves a refName to a commit hash.
+// Annotated tags are peeled to the commit they point to.
 // If the refName is not found, it returns an error.
 func (g *Git) ResolveRef(ctx context.Context, refName string) (string, error) {
 	return g.baseCommand(ctx).
-		AppendArgs("rev-parse", refName).
+		AppendArgs("rev-parse", "--verify", "--end-of-options", refName+"^{commit}").
 		Run().
 		TrimSpace().
 		String()
@@ -163,6 +262,20 @@ func (g *Git) CreateRef(ctx context.Context, refName, sha string) error {
 		Wait()
 }
 
+// CreateTag creates an annotated tag name pointing to commit, signed if sign is set.
+// If the tag already exists, it returns an error.
+func (g *Git) CreateTag(ctx context.Context, name, commit, message string, sign bool) error {
+	cmd := g.baseCommand(ctx).AppendArgs("tag", "--annotate", "--file=-")
+	if sign {
+		cmd = cmd.AppendArgs("--sign")
+	}
+	return cmd.
+		AppendArgs("--", name, commit).
+		StdinString(message).
+		Run().
+		Wait()
+}
+
 // Upstream returns the upstream of the given ref.
 // If the ref has no upstream, it returns an "", nil.
 // A non-nil error only occurs if git fails in an unexpected way.
@@ -200,14 +313,15 @@ func (g *Git) AbbrevRef(ctx context.Context, refName string) (string, error) {
 // commitsBetween returns the commits contained in tips but not in base.
 // It includes base.
 func (g *Git) commitsBetween(ctx context.Context, base string, tips []string) ([]string, error) {
-	commits, err := g.baseCommand(ctx).
-		AppendArgs("rev-list").
-		AppendArgs(tips...).
-		AppendArgs("--not", base).
-		Describef("get commits between %v and %s", tips, base).
-		Run().
-		TrimSpace().
-		Split("\n")
+	commits, err := splitLines(
+		g.baseCommand(ctx).
+			AppendArgs("rev-list").
+			AppendArgs(tips...).
+			AppendArgs("--not", base).
+			Describef("get commits between %v and %s", tips, base).
+			Run().
+			TrimSpace().
+			String())
 	if err != nil {
 		return nil, err
 	}
@@ -220,79 +334,243 @@ func (g *Git) treesReferenced(ctx context.Context, commits []string) ([]string,
 	for _, commit := range commits {
 		fmt.Fprintf(batch, "%s^{tree}\n", commit)
 	}
-	return g.baseCommand(ctx).
-		AppendArgs("cat-file", "--buffer", "--batch-check=%(objectname)").
-		Describef("get trees referenced by %v", commits).
-		Stdin(batch).
-		Run().
-		TrimSpace().
-		Split("\n")
+	return splitLines(
+		g.baseCommand(ctx).
+			AppendArgs("cat-file", "--buffer", "--batch-check=%(objectname)").
+			Describef("get trees referenced by %v", commits).
+			Stdin(batch).
+			Run().
+			TrimSpace().
+			String())
 }
 
 // varyingPaths returns the objects that correspond to different contents at the same path between the given trees.
-func (g *Git) varyingPaths(ctx context.Context, trees []string) ([]string, error) {
+// It also returns the paths whose mode varies between the given trees, mapped to the distinct modes seen.
+// Paths in exclude are skipped.
+func (g *Git) varyingPaths(ctx context.Context, trees []string, exclude []string) ([]string, map[string][]string, error) {
 	type contents struct {
-		typ    string // blob or tree or commit
-		sha    string // sha of the object
-		varies bool   // known to vary?
+		typ    string   // blob or tree or commit
+		sha    string   // sha of the object
+		modes  []string // distinct modes of the object
+		varies bool     // known to vary?
 	}
 	pathContents := make(map[string]contents)
 	var varying []string
+	modes := make(map[string][]string)
+	if len(trees) == 0 {
+		return nil, modes, nil
+	}
+	visit := func(mode, typ, sha, path string) error {
+		if slices.Contains(exclude, path) {
+			return nil
+		}
+		c := pathContents[path]
+		// first object for any path is a freebie
+		if c.typ == "" {
+			c.typ = typ
+			c.sha = sha
+			c.modes = []string{mode}
+			pathContents[path] = c
+			return nil
+		}
+		if !slices.Contains(c.modes, mode) {
+			c.modes = append(c.modes, mode)
+			modes[path] = c.modes
+			pathContents[path] = c
+		}
+		if c.varies {
+			varying = append(varying, sha)
+			return nil
+		}
+		// if there are any mismatches, it varies
+		if c.typ != typ || c.sha != sha || len(c.modes) > 1 {
+			if c.typ == "commit" || typ == "commit" {
+				return fmt.Errorf("changes involving submodules are not supported")
+			}
+			c.varies = true
+			varying = append(varying, c.sha, sha)
+			pathContents[path] = c
+			return nil
+		}
+		// otherwise, it's the same
+		return nil
+	}
+	// Compare the trees a directory at a time, reading each level's trees with one cat-file.
+	// A directory that has the same tree wherever it appears cannot vary, so it is not read.
+	type dir struct {
+		prefix string
+		trees  []string // the distinct trees at prefix, in order of appearance
+	}
+	root := dir{}
 	for _, tree := range trees {
-		lines, err := g.baseCommand(ctx).
-			AppendArgs("ls-tree", "-r", "-t", "-z", "--format=%(objecttype) %(objectname) %(path)", tree).
-			Describef("getting paths in %s", tree).
-			Run().
-			Split("\x00")
+		if !slices.Contains(root.trees, tree) {
+			root.trees = append(root.trees, tree)
+		}
+	}
+	for level := []dir{root}; len(level) > 0; {
+		var shas []string
+		for _, d := range level {
+			shas = append(shas, d.trees...)
+		}
+		entries, err := g.readTrees(ctx, shas)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		for _, line := range lines {
-			if line == "" {
-				continue
-			}
-			parts := strings.SplitN(line, " ", 3)
-			if len(parts) != 3 {
-				return nil, fmt.Errorf("unexpected line: %s", line)
-			}
-			typ, sha, path := parts[0], parts[1], parts[2]
-			switch typ {
-			case "blob", "tree", "commit":
-			default:
-				return nil, fmt.Errorf("unexpected object type: %s", typ)
-			}
-			if path == "" {
-				return nil, fmt.Errorf("unexpected empty path")
-			}
-			if len(sha) != 40 {
-				return nil, fmt.Errorf("unexpected sha length: %d", len(sha))
-			}
-			c := pathContents[path]
-			// first object for any path is a freebie
-			if c.typ == "" {
-				c.typ = typ
-				c.sha = sha
-				pathContents[path] = c
-				continue
-			}
-			if c.varies {
-				varying = append(varying, sha)
-				continue
-			}
-			// if there are any mismatches, it varies
-			if c.typ != typ || c.sha != sha {
-				if c.typ == "commit" || typ == "commit" {
-					return nil, fmt.Errorf("changes involving submodules are not supported")
+		var next []dir
+		for _, d := range level {
+			subdirs := make(map[string]int) // path -> index in next
+			for _, tree := range d.trees {
+				for _, e := range entries[tree] {
+					path := d.prefix + e.name
+					err := visit(e.mode, e.typ, e.sha, path)
+					if err != nil {
+						return nil, nil, err
+					}
+					if e.typ != "tree" {
+						continue
+					}
+					i, ok := subdirs[path]
+					if !ok {
+						i = len(next)
+						subdirs[path] = i
+						next = append(next, dir{prefix: path + "/"})
+					}
+					if !slices.Contains(next[i].trees, e.sha) {
+						next[i].trees = append(next[i].trees, e.sha)
+					}
 				}
-				c.varies = true
-				varying = append(varying, c.sha, sha)
-				pathContents[path] = c
-				continue
 			}
-			// otherwise, it's the same
 		}
+		level = slices.DeleteFunc(next, func(d dir) bool { return len(d.trees) < 2 })
+	}
+	return varying, modes, nil
+}
+
+// A treeEntry is one entry of a tree object.
+type treeEntry struct {
+	mode string // as ls-tree prints it, e.g. 040000
+	typ  string // blob or tree or commit
+	sha  string
+	name string
+}
+
+// readTrees reads the entries of the given trees with one cat-file, keyed by tree.
+func (g *Git) readTrees(ctx context.Context, trees []string) (map[string][]treeEntry, error) {
+	out, err := g.baseCommand(ctx).
+		AppendArgs("cat-file", "--buffer", "--batch").
+		StdinString(strings.Join(trees, "\n")+"\n").
+		Describef("read %d trees", len(trees)).
+		Run().
+		Bytes()
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string][]treeEntry)
+	for len(out) > 0 {
+		header, rest, ok := bytes.Cut(out, []byte("\n"))
+		if !ok {
+			return nil, fmt.Errorf("truncated cat-file output")
+		}
+		fields := strings.Fields(string(header))
+		if len(fields) != 3 || fields[1] != "tree" {
+			return nil, fmt.Errorf("unexpected cat-file header: %s", header)
+		}
+		size, err := strconv.Atoi(fields[2])
+		if err != nil || size+1 > len(rest) {
+			return nil, fmt.Errorf("unexpected cat-file header: %s", header)
+		}
+		sha := fields[0]
+		entries[sha], err = parseTree(rest[:size], len(sha)/2)
+		if err != nil {
+			return nil, fmt.Errorf("reading tree %s: %w", sha, err)
+		}
+		out = rest[size+1:]
 	}
-	return varying, nil
+	return entries, nil
+}
+
+// parseTree parses the raw contents of a tree object whose object ids are hashLen bytes.
+func parseTree(data []byte, hashLen int) ([]treeEntry, error) {
+	var entries []treeEntry
+	for len(data) > 0 {
+		mode, rest, ok := bytes.Cut(data, []byte(" "))
+		if !ok {
+			return nil, fmt.Errorf("malformed tree entry")
+		}
+		name, rest, ok := bytes.Cut(rest, []byte{0})
+		if !ok || len(rest) < hashLen {
+			return nil, fmt.Errorf("malformed tree entry")
+		}
+		e := treeEntry{
+			mode: strings.Repeat("0", max(0, 6-len(mode))) + string(mode),
+			sha:  hex.EncodeToString(rest[:hashLen]),
+			name: string(name),
+		}
+		switch e.mode {
+		case "040000":
+			e.typ = "tree"
+		case "160000":
+			e.typ = "commit"
+		default:
+			e.typ = "blob"
+		}
+		entries = append(entries, e)
+		data = rest[hashLen:]
+	}
+	return entries, nil
+}
+
+// PathModes returns the modes of the given paths in treeish, keyed by path.
+// Paths absent from treeish are absent from the result.
+// If no paths are given, it returns the modes of all paths in treeish.
+func (g *Git) PathModes(ctx context.Context, treeish string, paths []string) (map[string]string, error) {
+	lines, err := g.baseCommand(ctx).
+		AppendArgs("ls-tree", "-r", "-t", "-z", "--format=%(objectmode) %(path)", treeish, "--").
+		AppendArgs(paths...).
+		Describef("get path modes in %s", treeish).
+		Run().
+		Split("\x00")
+	if err != nil {
+		return nil, err
+	}
+	modes := make(map[string]string)
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		mode, path, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("unexpected line: %s", line)
+		}
+		modes[path] = mode
+	}
+	return modes, nil
+}
+
+// PathBlobs returns the object hashes of the given paths in treeish, keyed by path.
+// Paths absent from treeish are absent from the result.
+func (g *Git) PathBlobs(ctx context.Context, treeish string, paths []string) (map[string]string, error) {
+	lines, err := g.baseCommand(ctx).
+		AppendArgs("ls-tree", "-r", "-z", "--format=%(objectname) %(path)", treeish, "--").
+		AppendArgs(paths...).
+		Describef("get path blobs in %s", treeish).
+		Run().
+		Split("\x00")
+	if err != nil {
+		return nil, err
+	}
+	blobs := make(map[string]string)
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		sha, path, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("unexpected line: %s", line)
+		}
+		blobs[path] = sha
+	}
+	return blobs, nil
 }
 
 func (g *Git) packObjects(ctx context.Context, objects []string) (string, error) {
@@ -309,43 +587,421 @@ func (g *Git) packObjects(ctx context.Context, objects []string) (string, error)
 		String()
 }
 
-func (g *Git) MergePack(ctx context.Context, main, topic string) (string, error) {
+// A Pack is a pack of objects needed to analyze and combine two branches,
+// along with metadata gathered while building it.
+type Pack struct {
+	Data  string              // pack file contents
+	Modes map[string][]string // paths whose mode varies across the branches -> distinct modes seen
+}
+
+// PackOptions adjusts which objects MergePack includes.
+type PackOptions struct {
+	Exclude []string // paths resolved locally, whose varying contents need not be sent
+	Extra   []string // additional objects to include, e.g. locally resolved blobs
+
+	// If HistoryBudget is positive, only that many of the most recent commits on each side,
+	// and those that touch Conflicts, are sent with their trees and blobs; older commits are sent alone.
+	HistoryBudget int
+	Conflicts     []string // paths that conflict
+}
+
+// A PackPlan lists the objects of a merge pack, so that the pack can be streamed as it is built.
+type PackPlan struct {
+	Objects []string            // objects to pack
+	Modes   map[string][]string // paths whose mode varies across the branches -> distinct modes seen
+}
+
+// MergePack builds a pack of the objects needed to analyze and combine main and topic.
+// opts may be nil.
+func (g *Git) MergePack(ctx context.Context, main, topic string, opts *PackOptions) (*Pack, error) {
+	plan, err := g.PlanMergePack(ctx, main, topic, opts)
+	if err != nil {
+		return nil, err
+	}
+	data, err := g.packObjects(ctx, plan.Objects)
+	if err != nil {
+		return nil, err
+	}
+	// fmt.Println("pack size", len(data))
+	return &Pack{Data: data, Modes: plan.Modes}, nil
+}
+
+// WritePack writes a pack of objects to w as pack-objects produces it.
+func (g *Git) WritePack(ctx context.Context, objects []string, w io.Writer) error {
+	return g.baseCommand(ctx).
+		AppendArgs("pack-objects", "--stdout", "--delta-base-offset", "-q").
+		StdinString(strings.Join(objects, "\n")+"\n").
+		Stdout(w).
+		Describef("packing %v objects", len(objects)).
+		Run().
+		Wait()
+}
+
+// PlanMergePack lists the objects MergePack would pack, without packing them.
+// opts may be nil.
+func (g *Git) PlanMergePack(ctx context.Context, main, topic string, opts *PackOptions) (*PackPlan, error) {
+	if opts == nil {
+		opts = new(PackOptions)
+	}
 	base, err := g.UniqueAncestorMergeBase(ctx, []string{main, topic})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	commits, err := g.commitsBetween(ctx, base, []string{main, topic})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	// fmt.Println("n commits:", len(commits))
-	trees, err := g.treesReferenced(ctx, commits)
+	full, err := g.budgetCommits(ctx, base, main, topic, commits, opts)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	trees, err := g.treesReferenced(ctx, full)
+	if err != nil {
+		return nil, err
 	}
 	// fmt.Println("n trees:", len(trees))
-	varying, err := g.varyingPaths(ctx, trees)
+	varying, modes, err := g.varyingPaths(ctx, trees, opts.Exclude)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	var need []string
 	need = append(need, commits...)
 	need = append(need, trees...)
 	need = append(need, varying...)
+	need = append(need, opts.Extra...)
 	// fmt.Println("n varying:", len(varying))
-	pack, err := g.packObjects(ctx, need)
-	if err != nil {
-		return "", err
+	return &PackPlan{Objects: need, Modes: modes}, nil
+}
+
+// budgetCommits returns the commits, among those between base and main or topic,
+// whose trees and blobs fit opts.HistoryBudget: base, the most recent on each side, and those touching opts.Conflicts.
+func (g *Git) budgetCommits(ctx context.Context, base, main, topic string, commits []string, opts *PackOptions) ([]string, error) {
+	n := opts.HistoryBudget
+	if n <= 0 || len(commits) <= 2*n+1 {
+		return commits, nil
+	}
+	keep := map[string]bool{base: true}
+	for _, tip := range []string{main, topic} {
+		recent, err := splitLines(g.baseCommand(ctx).
+			AppendArgs("rev-list", fmt.Sprintf("--max-count=%d", n), tip, "--not", base).
+			Describef("get the %d most recent commits of %s", n, tip).
+			Run().
+			TrimSpace().
+			String())
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range recent {
+			keep[c] = true
+		}
+	}
+	if len(opts.Conflicts) > 0 {
+		// Pathspecs go on stdin: there may be too many for the command line.
+		touching, err := splitLines(g.envCommand(ctx, "GIT_LITERAL_PATHSPECS=1").
+			AppendArgs("rev-list", "--stdin").
+			StdinString(fmt.Sprintf("%s\n%s\n^%s\n--\n%s\n", main, topic, base, strings.Join(opts.Conflicts, "\n"))).
+			Describe("get commits touching conflicting paths").
+			Run().
+			TrimSpace().
+			String())
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range touching {
+			keep[c] = true
+		}
 	}
-	// fmt.Println("pack size", len(pack))
-	return pack, nil
+	return slices.DeleteFunc(slices.Clone(commits), func(c string) bool { return !keep[c] }), nil
 }
 
+// UnpackObjects writes the objects in pack as loose objects.
+// Objects the repository already has are skipped; malformed objects are rejected.
+// The pack may be thin: unpack-objects resolves deltas against objects in the repository.
 func (g *Git) UnpackObjects(ctx context.Context, pack *bytes.Buffer) error {
 	return g.baseCommand(ctx).
-		AppendArgs("unpack-objects", "-q").
+		AppendArgs("unpack-objects", "-q", "--strict").
 		Stdin(pack).
 		Describef("unpacking %d bytes worth of objects", pack.Len()).
 		Run().
 		Wait()
 }
+
+// IndexPack stores the objects in pack that the repository lacks in its pack directory, with an index.
+// Unlike UnpackObjects, it writes no loose objects.
+// The pack may be thin. Like UnpackObjects, it rejects malformed objects.
+func (g *Git) IndexPack(ctx context.Context, pack *bytes.Buffer) error {
+	dir, err := os.MkdirTemp("", "merde-pack-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	objectsDir, err := g.GitPath(ctx, "objects")
+	if err != nil {
+		return err
+	}
+	// Index the pack into dir, laid out as an object directory so that git can read from it as an alternate.
+	// The pack may be thin, with deltas against objects the repository has;
+	// --fix-thin completes it with those objects, read from the repository as an alternate.
+	err = os.MkdirAll(filepath.Join(dir, "pack"), 0o700)
+	if err != nil {
+		return err
+	}
+	out, err := g.envCommand(ctx, "GIT_OBJECT_DIRECTORY="+dir, "GIT_ALTERNATE_OBJECT_DIRECTORIES="+objectsDir).
+		AppendArgs("index-pack", "--stdin", "--fix-thin", "--strict").
+		StdinBytes(pack.Bytes()).
+		Describe("index incoming pack").
+		Run().
+		TrimSpace().
+		String()
+	if err != nil {
+		return err
+	}
+	hash := strings.TrimPrefix(strings.TrimPrefix(out, "pack"), "\t")
+	path := filepath.Join(dir, "pack", "pack-"+hash+".pack")
+	idx, err := os.ReadFile(strings.TrimSuffix(path, ".pack") + ".idx")
+	if err != nil {
+		return err
+	}
+	var objects []string
+	lines, err := splitLines(g.baseCommand(ctx).
+		AppendArgs("show-index").
+		StdinBytes(idx).
+		Describe("list incoming objects").
+		Run().
+		TrimSpace().
+		String())
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if fields := strings.Fields(line); len(fields) >= 2 {
+			objects = append(objects, fields[1])
+		}
+	}
+	// Without the alternate, cat-file sees only the objects the repository already has.
+	lines, err = splitLines(g.baseCommand(ctx).
+		AppendArgs("cat-file", "--batch-check=%(objectname)").
+		StdinBytes([]byte(strings.Join(objects, "\n") + "\n")).
+		Describe("check for incoming objects already present").
+		Run().
+		TrimSpace().
+		String())
+	if err != nil {
+		return err
+	}
+	var missing []string
+	for _, line := range lines {
+		if name, ok := strings.CutSuffix(line, " missing"); ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if len(missing) < len(objects) {
+		data, err = g.envCommand(ctx, "GIT_ALTERNATE_OBJECT_DIRECTORIES="+dir).
+			AppendArgs("pack-objects", "--stdout", "-q").
+			StdinBytes([]byte(strings.Join(missing, "\n")+"\n")).
+			Describef("repack %d new of %d incoming objects", len(missing), len(objects)).
+			Run().
+			Bytes()
+		if err != nil {
+			return err
+		}
+	}
+	return g.baseCommand(ctx).
+		AppendArgs("index-pack", "--stdin").
+		StdinBytes(data).
+		Describef("indexing %d bytes worth of objects", len(data)).
+		Run().
+		Wait()
+}
+
+// ChangedPaths returns the paths that differ between the trees of from and to,
+// mapped to git's single-letter status for that path (A, D, M, or T).
+// Renames are reported as a deletion plus an addition.
+func (g *Git) ChangedPaths(ctx context.Context, from, to string) (map[string]string, error) {
+	fields, err := g.baseCommand(ctx).
+		AppendArgs("diff-tree", "-r", "-z", "--no-renames", "--name-status", from, to).
+		Describef("get paths changed between %s and %s", from, to).
+		Run().
+		Split("\x00")
+	if err != nil {
+		return nil, err
+	}
+	changed := make(map[string]string)
+	for i := 0; i+1 < len(fields); i += 2 {
+		status, path := fields[i], fields[i+1]
+		if status == "" {
+			continue
+		}
+		changed[path] = status[:1]
+	}
+	return changed, nil
+}
+
+// Diff returns the unified diff of paths between the trees of from and to,
+// as git itself would show it, without external diff drivers or textconv filters.
+func (g *Git) Diff(ctx context.Context, from, to string, paths []string) (string, error) {
+	return g.baseCommand(ctx).
+		AppendArgs("diff", "--no-ext-diff", "--no-textconv", "--no-color", from, to, "--").
+		AppendArgs(paths...).
+		Describef("diff %s and %s", from, to).
+		Run().
+		String()
+}
+
+// ChangedDeclarations returns the declarations, such as functions, that the changes to path between from and to
+// fall within, as git names them in the headers of diff hunks.
+// Git finds them with the diff driver that .gitattributes sets for path, such as diff=golang,
+// or else by its default rule: the nearest line above that starts with a letter, underscore, or dollar sign.
+func (g *Git) ChangedDeclarations(ctx context.Context, from, to, path string) ([]string, error) {
+	lines, err := splitLines(g.envCommand(ctx, "GIT_LITERAL_PATHSPECS=1").
+		AppendArgs("diff", "--unified=0", "--no-ext-diff", "--no-textconv", "--no-color", from, to, "--", path).
+		Describef("diff %s between %s and %s", path, from, to).
+		Run().
+		String())
+	if err != nil {
+		return nil, err
+	}
+	var decls []string
+	for _, line := range lines {
+		// A hunk header is @@ -a,b +c,d @@, followed by the declaration, if any.
+		// Lines of the diff itself start with +, -, or a space instead.
+		rest, ok := strings.CutPrefix(line, "@@ ")
+		if !ok {
+			continue
+		}
+		_, decl, _ := strings.Cut(rest, " @@")
+		decl = strings.TrimSpace(decl)
+		if decl != "" && !slices.Contains(decls, decl) {
+			decls = append(decls, decl)
+		}
+	}
+	return decls, nil
+}
+
+// CommonDir returns the absolute path of the git directory shared by all worktrees.
+func (g *Git) CommonDir(ctx context.Context) (string, error) {
+	return g.baseCommand(ctx).
+		AppendArgs("rev-parse", "--path-format=absolute", "--git-common-dir").
+		Describe("get git common dir").
+		Run().
+		TrimSpace().
+		String()
+}
+
+// GitPath returns the absolute path git uses for name in the git directory, such as objects or refs/heads,
+// taking into account worktrees and variables such as GIT_OBJECT_DIRECTORY.
+func (g *Git) GitPath(ctx context.Context, name string) (string, error) {
+	return g.baseCommand(ctx).
+		AppendArgs("rev-parse", "--path-format=absolute", "--git-path", name).
+		Describef("get git path %s", name).
+		Run().
+		TrimSpace().
+		String()
+}
+
+// ShowTo runs a git command that displays something to the user, such as a diff,
+// with its output connected to the terminal so that git's pager and colors work.
+func (g *Git) ShowTo(ctx context.Context, args ...string) error {
+	return g.baseCommand(ctx).
+		AppendArgs(args...).
+		Stdout(os.Stdout).
+		Stderr(os.Stderr).
+		Describef("git %s", strings.Join(args, " ")).
+		Run().
+		Wait()
+}
+
+// FullRefName returns the full name of refName, such as refs/heads/main for main.
+// If refName is not a ref, such as a commit hash, it returns "", nil.
+func (g *Git) FullRefName(ctx context.Context, refName string) (string, error) {
+	return g.baseCommand(ctx).
+		AppendArgs("rev-parse", "--symbolic-full-name", refName).
+		Describef("get full ref name of %s", refName).
+		Run().
+		TrimSpace().
+		String()
+}
+
+// ResetKeep resets the current branch and working tree to commit, keeping local changes,
+// as git reset --keep does.
+func (g *Git) ResetKeep(ctx context.Context, commit string) error {
+	return g.baseCommand(ctx).
+		AppendArgs("reset", "--keep", "--quiet", commit).
+		Describef("reset to %s", commit).
+		Run().
+		Wait()
+}
+
+// UnmergedPaths returns the paths with unresolved conflicts in the index.
+func (g *Git) UnmergedPaths(ctx context.Context) ([]string, error) {
+	out, err := g.baseCommand(ctx).
+		AppendArgs("diff", "--name-only", "-z", "--diff-filter=U").
+		Describe("list unmerged paths").
+		Run().
+		String()
+	if err != nil {
+		return nil, err
+	}
+	return strings.FieldsFunc(out, func(r rune) bool { return r == 0 }), nil
+}
+
+// CountCommits returns the number of commits reachable from tip but not from exclude.
+func (g *Git) CountCommits(ctx context.Context, exclude, tip string) (int, error) {
+	out, err := g.baseCommand(ctx).
+		AppendArgs("rev-list", "--count", tip, "--not", exclude, "--").
+		Describef("count commits in %s..%s", exclude, tip).
+		Run().
+		TrimSpace().
+		String()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(out)
+}
+
+// MergeTree merges a and b in memory, without touching the index or working tree.
+// It returns the merged tree, and the paths that conflict; the tree holds conflict markers in those paths.
+func (g *Git) MergeTree(ctx context.Context, a, b string) (string, []string, error) {
+	out, err := g.baseCommand(ctx).
+		AppendArgs("merge-tree", "--write-tree", "--name-only", "--no-messages", "-z", a, b).
+		Describef("merge %s and %s in memory", a, b).
+		Run().
+		AllowExitCodes(1). // conflicts
+		String()
+	if err != nil {
+		return "", nil, err
+	}
+	// The first field is the merged tree.
+	fields := strings.FieldsFunc(out, func(r rune) bool { return r == 0 })
+	if len(fields) == 0 {
+		return "", nil, fmt.Errorf("unexpected merge-tree output")
+	}
+	return fields[0], fields[1:], nil
+}
+
+// Branches returns the names of the local branches.
+func (g *Git) Branches(ctx context.Context) ([]string, error) {
+	return splitLines(g.baseCommand(ctx).
+		AppendArgs("for-each-ref", "--format=%(refname:short)", "refs/heads/").
+		Describe("list branches").
+		Run().
+		TrimSpace().
+		String())
+}
+
+// RunMaintenance runs one git maintenance task, such as loose-objects.
+func (g *Git) RunMaintenance(ctx context.Context, task string) error {
+	return g.baseCommand(ctx).
+		AppendArgs("maintenance", "run", "--quiet", "--task="+task).
+		Describef("run maintenance task %s", task).
+		Run().
+		Wait()
+}
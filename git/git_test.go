@@ -0,0 +1,214 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package git
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"slices"
+	"testing"
+
+	"merde.ai/git/gittest"
+)
+
+func TestParseTree(t *testing.T) {
+	blob := bytes.Repeat([]byte{0xab}, 20)
+	tree := bytes.Repeat([]byte{0x01}, 20)
+	sub := bytes.Repeat([]byte{0xff}, 20)
+	var data []byte
+	for _, e := range []struct {
+		mode, name string
+		sha        []byte
+	}{
+		{"100644", "a.txt", blob},
+		{"100755", "run.sh", blob},
+		{"120000", "link", blob},
+		{"40000", "dir with space", tree},
+		{"160000", "sub", sub},
+	} {
+		data = append(data, e.mode+" "+e.name+"\x00"...)
+		data = append(data, e.sha...)
+	}
+	got, err := parseTree(data, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []treeEntry{
+		{mode: "100644", typ: "blob", sha: hex.EncodeToString(blob), name: "a.txt"},
+		{mode: "100755", typ: "blob", sha: hex.EncodeToString(blob), name: "run.sh"},
+		{mode: "120000", typ: "blob", sha: hex.EncodeToString(blob), name: "link"},
+		{mode: "040000", typ: "tree", sha: hex.EncodeToString(tree), name: "dir with space"},
+		{mode: "160000", typ: "commit", sha: hex.EncodeToString(sub), name: "sub"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("parseTree = %+v\nwant %+v", got, want)
+	}
+
+	// SHA-256 repositories have 32-byte object ids.
+	long := bytes.Repeat([]byte{0x42}, 32)
+	got, err = parseTree(append([]byte("100644 f\x00"), long...), 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].sha != hex.EncodeToString(long) {
+		t.Errorf("parseTree with 32-byte ids = %+v", got)
+	}
+
+	if got, err := parseTree(nil, 20); err != nil || len(got) != 0 {
+		t.Errorf("parseTree of an empty tree = %+v, %v; want no entries", got, err)
+	}
+	for _, bad := range []string{
+		"100644",                    // no name
+		"100644 a.txt",              // no NUL
+		"100644 a.txt\x00" + "\x01", // short object id
+	} {
+		if _, err := parseTree([]byte(bad), 20); err == nil {
+			t.Errorf("parseTree(%q) succeeded; want an error", bad)
+		}
+	}
+}
+
+func TestVaryingPaths(t *testing.T) {
+	dir, run := gittest.Repo(t)
+	ctx := context.Background()
+	g, err := NewGit(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := gittest.Commit(t, dir, run, "base", map[string]string{
+		"a.txt":            "a\n",
+		"run.sh":           "echo\n",
+		"dir/b.txt":        "b\n",
+		"dir/deep/c.txt":   "c\n",
+		"dir/same/d.txt":   "d\n",
+		"unchanged/e.txt":  "e\n",
+		"unchanged/f/g.go": "g\n",
+	})
+	main := gittest.Commit(t, dir, run, "main", map[string]string{
+		"a.txt":          "a on main\n",
+		"dir/deep/c.txt": "c on main\n",
+	})
+	run("checkout", "-q", "-b", "topic", base)
+	topic := gittest.Commit(t, dir, run, "topic", map[string]string{
+		"a.txt":     "a on topic\n",
+		"run.sh*":   "echo\n",
+		"dir/b.txt": "b on topic\n",
+		"dir/new":   "only on topic\n",
+	})
+	trees := []string{run("rev-parse", base+"^{tree}"), run("rev-parse", main+"^{tree}"), run("rev-parse", topic+"^{tree}")}
+	objects := func(specs ...string) []string {
+		var shas []string
+		for _, spec := range specs {
+			shas = append(shas, run("rev-parse", spec))
+		}
+		slices.Sort(shas)
+		return slices.Compact(shas)
+	}
+
+	varying, modes, err := g.varyingPaths(ctx, trees, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(varying)
+	varying = slices.Compact(varying)
+	want := objects(
+		base+":a.txt", main+":a.txt", topic+":a.txt",
+		base+":run.sh",
+		base+":dir", main+":dir", topic+":dir",
+		base+":dir/b.txt", topic+":dir/b.txt",
+		base+":dir/deep", main+":dir/deep",
+		base+":dir/deep/c.txt", main+":dir/deep/c.txt",
+	)
+	if !slices.Equal(varying, want) {
+		t.Errorf("varyingPaths = %v\nwant %v", varying, want)
+	}
+	if len(modes) != 1 || !slices.Equal(modes["run.sh"], []string{"100644", "100755"}) {
+		t.Errorf("varyingPaths modes = %v; want run.sh: [100644 100755]", modes)
+	}
+
+	// Excluded paths are left out, though their directories still vary.
+	varying, _, err = g.varyingPaths(ctx, trees, []string{"a.txt", "dir/deep/c.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sha := range objects(base+":a.txt", main+":a.txt", topic+":a.txt", base+":dir/deep/c.txt", main+":dir/deep/c.txt") {
+		if slices.Contains(varying, sha) {
+			t.Errorf("varyingPaths with exclusions includes excluded object %s", sha)
+		}
+	}
+	if !slices.Contains(varying, run("rev-parse", main+":dir/deep")) {
+		t.Errorf("varyingPaths with exclusions left out dir/deep")
+	}
+
+	// The same tree given twice varies nowhere.
+	varying, modes, err = g.varyingPaths(ctx, []string{trees[0], trees[0]}, nil)
+	if err != nil || len(varying) != 0 || len(modes) != 0 {
+		t.Errorf("varyingPaths of one tree = %v, %v, %v; want nothing", varying, modes, err)
+	}
+
+	// A submodule at different commits cannot be sent.
+	run("update-index", "--add", "--cacheinfo", "160000,"+base+",sub")
+	withSub := run("write-tree")
+	run("update-index", "--cacheinfo", "160000,"+main+",sub")
+	movedSub := run("write-tree")
+	if _, _, err := g.varyingPaths(ctx, []string{withSub, movedSub}, nil); err == nil {
+		t.Errorf("varyingPaths with a moved submodule succeeded; want an error")
+	}
+}
+
+func TestBudgetCommits(t *testing.T) {
+	dir, run := gittest.Repo(t)
+	ctx := context.Background()
+	g, err := NewGit(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := gittest.Commit(t, dir, run, "base", map[string]string{"conflict.txt": "base\n", "other.txt": "0\n"})
+	var mainCommits, topicCommits []string
+	for i, c := range []string{"1", "2", "3", "4", "5"} {
+		files := map[string]string{"other.txt": "main " + c + "\n"}
+		if i == 1 {
+			files["conflict.txt"] = "main\n" // an old commit that touches a conflicting path
+		}
+		mainCommits = append(mainCommits, gittest.Commit(t, dir, run, "main "+c, files))
+	}
+	main := mainCommits[len(mainCommits)-1]
+	run("checkout", "-q", "-b", "topic", base)
+	for _, c := range []string{"1", "2", "3", "4"} {
+		topicCommits = append(topicCommits, gittest.Commit(t, dir, run, "topic "+c, map[string]string{"topic.txt": c + "\n"}))
+	}
+	topic := topicCommits[len(topicCommits)-1]
+	commits, err := g.commitsBetween(ctx, base, []string{main, topic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 10 {
+		t.Fatalf("commitsBetween = %d commits; want 10", len(commits))
+	}
+
+	tests := []struct {
+		budget    int
+		conflicts []string
+		want      []string
+	}{
+		{0, nil, commits},
+		{5, nil, commits}, // the budget covers every commit
+		{2, nil, []string{base, mainCommits[3], mainCommits[4], topicCommits[2], topicCommits[3]}},
+		{2, []string{"conflict.txt"}, []string{base, mainCommits[1], mainCommits[3], mainCommits[4], topicCommits[2], topicCommits[3]}},
+		{1, []string{"topic.txt", "missing.txt"}, []string{base, mainCommits[4], topicCommits[0], topicCommits[1], topicCommits[2], topicCommits[3]}},
+	}
+	for _, tt := range tests {
+		got, err := g.budgetCommits(ctx, base, main, topic, commits, &PackOptions{HistoryBudget: tt.budget, Conflicts: tt.conflicts})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// budgetCommits keeps the order of commits.
+		want := slices.DeleteFunc(slices.Clone(commits), func(c string) bool { return !slices.Contains(tt.want, c) })
+		if len(want) != len(tt.want) || !slices.Equal(got, want) {
+			t.Errorf("budgetCommits with budget %d and conflicts %q = %v\nwant %v", tt.budget, tt.conflicts, got, want)
+		}
+	}
+}
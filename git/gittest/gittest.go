@@ -0,0 +1,77 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+// Package gittest makes throwaway git repositories for tests.
+package gittest
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Repo makes a repository in a temporary directory and points GIT_DIR and GIT_WORK_TREE at it for the rest of the test.
+// It keeps git away from the user's config and pins the identities and dates of commits,
+// so that their hashes are the same on every run.
+// It returns the repository's directory and a function that runs git there and returns its trimmed output.
+func Repo(t testing.TB) (string, func(args ...string) string) {
+	t.Helper()
+	dir := t.TempDir()
+	for k, v := range map[string]string{
+		"HOME":                dir,
+		"GIT_CONFIG_NOSYSTEM": "1",
+		"GIT_CONFIG_GLOBAL":   os.DevNull,
+		"GIT_AUTHOR_NAME":     "A U Thor",
+		"GIT_AUTHOR_EMAIL":    "author@example.com",
+		"GIT_AUTHOR_DATE":     "1700000000 +0000",
+		"GIT_COMMITTER_NAME":  "C O Mitter",
+		"GIT_COMMITTER_EMAIL": "committer@example.com",
+		"GIT_COMMITTER_DATE":  "1700000000 +0000",
+		"GIT_DIR":             filepath.Join(dir, ".git"),
+		"GIT_WORK_TREE":       dir,
+	} {
+		t.Setenv(k, v)
+	}
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	run("init", "-q", "-b", "main", dir)
+	return dir, run
+}
+
+// Commit commits files in the repository at dir and returns the commit.
+// Files maps paths to contents, or to "" to delete the path; a path ending in * is also made executable.
+func Commit(t testing.TB, dir string, run func(args ...string) string, message string, files map[string]string) string {
+	t.Helper()
+	for path, contents := range files {
+		path, exe := strings.CutSuffix(path, "*")
+		if contents == "" {
+			run("rm", "-q", "--", path)
+			continue
+		}
+		name := filepath.Join(dir, filepath.FromSlash(path))
+		err := os.MkdirAll(filepath.Dir(name), 0o755)
+		if err == nil {
+			err = os.WriteFile(name, []byte(contents), 0o644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		run("add", "--", path)
+		if exe {
+			run("update-index", "--chmod=+x", "--", path)
+		}
+	}
+	run("commit", "-q", "--allow-empty", "-m", message)
+	return run("rev-parse", "HEAD")
+}
@@ -0,0 +1,169 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CheckAttr returns the value of attr for each of paths, as reported by git check-attr.
+// Values are "set", "unset", "unspecified", or a string value.
+func (g *Git) CheckAttr(ctx context.Context, attr string, paths []string) (map[string]string, error) {
+	fields, err := g.baseCommand(ctx).
+		AppendArgs("check-attr", "-z", "--stdin", attr).
+		StdinString(strings.Join(paths, "\x00")+"\x00").
+		Describef("check %s attribute", attr).
+		Run().
+		Split("\x00")
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	for i := 0; i+2 < len(fields); i += 3 {
+		values[fields[i]] = fields[i+2]
+	}
+	return values, nil
+}
+
+// ConfigValue returns the value of the git config key, or "" if it is not set.
+func (g *Git) ConfigValue(ctx context.Context, key string) (string, error) {
+	return g.baseCommand(ctx).
+		AppendArgs("config", "--get", key).
+		Describef("get config %s", key).
+		Run().
+		TrimSpace().
+		AllowExitCodes(1).
+		String()
+}
+
+// ReadBlob returns the contents of path at commit.
+func (g *Git) ReadBlob(ctx context.Context, commit, path string) ([]byte, error) {
+	return g.baseCommand(ctx).
+		AppendArgs("cat-file", "blob", commit+":"+path).
+		Describef("read %s at %s", path, commit).
+		Run().
+		Bytes()
+}
+
+// WriteBlob writes data to the object store and returns its sha.
+func (g *Git) WriteBlob(ctx context.Context, data []byte) (string, error) {
+	return g.baseCommand(ctx).
+		AppendArgs("hash-object", "-w", "--stdin").
+		StdinBytes(data).
+		Describe("write blob").
+		Run().
+		TrimSpace().
+		String()
+}
+
+// A FileMerge is a three-way merge of a single file, performed locally.
+type FileMerge struct {
+	Path   string // path of the file in the repository
+	Base   []byte // common ancestor contents
+	Ours   []byte // contents on the side being merged into
+	Theirs []byte // contents on the side being merged in
+}
+
+// MergeUnion merges m with git merge-file --union, which keeps the lines of both sides.
+func (g *Git) MergeUnion(ctx context.Context, m *FileMerge) ([]byte, error) {
+	dir, files, err := writeMergeFiles(m)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(LongPath(dir))
+	return g.baseCommand(ctx).
+		AppendArgs("merge-file", "--union", "-p", files[1], files[0], files[2]).
+		Describef("union merge %s", m.Path).
+		Run().
+		Bytes()
+}
+
+// MergeMarkers merges m with git merge-file, leaving conflict markers labeled ours and theirs where the sides conflict.
+func (g *Git) MergeMarkers(ctx context.Context, m *FileMerge, ours, theirs string) ([]byte, error) {
+	dir, files, err := writeMergeFiles(m)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(LongPath(dir))
+	// merge-file exits with the number of conflicts, up to 127.
+	conflicts := make([]int, 127)
+	for i := range conflicts {
+		conflicts[i] = i + 1
+	}
+	return g.baseCommand(ctx).
+		AppendArgs("merge-file", "-p", "-L", ours, "-L", "base", "-L", theirs, files[1], files[0], files[2]).
+		Describef("merge %s with conflict markers", m.Path).
+		Run().
+		AllowExitCodes(conflicts...).
+		Bytes()
+}
+
+// MergeWithDriver merges m by running the custom merge driver command,
+// as configured in merge.<name>.driver.
+// It reports whether the driver merged cleanly.
+func (g *Git) MergeWithDriver(ctx context.Context, m *FileMerge, driver string) ([]byte, bool, error) {
+	dir, files, err := writeMergeFiles(m)
+	if err != nil {
+		return nil, false, err
+	}
+	defer os.RemoveAll(LongPath(dir))
+	// Like git, quote the file names, which on Windows are full of backslashes.
+	r := strings.NewReplacer(
+		"%O", ShellQuote(filepath.ToSlash(files[0])),
+		"%A", ShellQuote(filepath.ToSlash(files[1])),
+		"%B", ShellQuote(filepath.ToSlash(files[2])),
+		"%L", "7",
+		"%P", ShellQuote(m.Path),
+		"%%", "%",
+	)
+	res := g.Shell(ctx, r.Replace(driver)).
+		Dir(g.root).
+		Describef("run merge driver for %s", m.Path).
+		Run()
+	err = res.Wait()
+	if err != nil {
+		if res.ExitCode() > 0 {
+			// the driver ran, but left conflicts
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	out, err := os.ReadFile(LongPath(files[1]))
+	if err != nil {
+		return nil, false, err
+	}
+	return out, true, nil
+}
+
+// writeMergeFiles writes the base, ours, and theirs contents of m to a new temporary directory.
+func writeMergeFiles(m *FileMerge) (string, [3]string, error) {
+	var files [3]string
+	dir, err := os.MkdirTemp("", "merde-merge-")
+	if err != nil {
+		return "", files, err
+	}
+	for i, contents := range [][]byte{m.Base, m.Ours, m.Theirs} {
+		files[i] = filepath.Join(dir, fmt.Sprintf("%d-%s", i, filepath.Base(m.Path)))
+		err := os.WriteFile(LongPath(files[i]), contents, 0o600)
+		if err != nil {
+			os.RemoveAll(LongPath(dir))
+			return "", files, err
+		}
+	}
+	return dir, files, nil
+}
+
+// ReadObject returns the contents of the blob object, such as a blob hash or commit:path.
+func (g *Git) ReadObject(ctx context.Context, object string) ([]byte, error) {
+	return g.baseCommand(ctx).
+		AppendArgs("cat-file", "blob", object).
+		Describef("read %s", object).
+		Run().
+		Bytes()
+}
@@ -0,0 +1,23 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+//go:build !windows
+
+package git
+
+// LongPath returns p; only Windows limits path lengths.
+func LongPath(p string) string {
+	return p
+}
+
+// gitInstallPaths returns the places git is commonly installed, for when it is not on PATH.
+// Elsewhere than Windows, PATH is reliable.
+func gitInstallPaths() []string {
+	return nil
+}
+
+// shellExe returns the shell to run scripts with, for the git binary gitBin.
+func shellExe(gitBin string) string {
+	return "sh"
+}
@@ -0,0 +1,58 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// LongPath returns p in a form that Windows file APIs accept beyond MAX_PATH.
+// Use it only for Go's own file operations: git does not understand the \\?\ prefix.
+func LongPath(p string) string {
+	if len(p) < 240 || strings.HasPrefix(p, `\\`) {
+		return p
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return p
+	}
+	return `\\?\` + abs
+}
+
+// gitInstallPaths returns the places git for Windows is commonly installed, for when it is not on PATH.
+func gitInstallPaths() []string {
+	var paths []string
+	for _, env := range []string{"ProgramFiles", "ProgramW6432", "ProgramFiles(x86)"} {
+		if dir := os.Getenv(env); dir != "" {
+			paths = append(paths, filepath.Join(dir, "Git", "cmd", "git.exe"))
+		}
+	}
+	if dir := os.Getenv("LOCALAPPDATA"); dir != "" {
+		paths = append(paths, filepath.Join(dir, "Programs", "Git", "cmd", "git.exe"))
+	}
+	return paths
+}
+
+// shellExe returns the shell to run scripts with, for the git binary gitBin:
+// the sh.exe that Git for Windows installs next to it, as in Git\cmd\git.exe and Git\bin\sh.exe,
+// or else any sh on PATH.
+func shellExe(gitBin string) string {
+	// git.exe is in cmd, bin, or mingw64\bin below the installation.
+	dir := filepath.Dir(gitBin)
+	for _, root := range []string{filepath.Dir(dir), filepath.Dir(filepath.Dir(dir))} {
+		for _, sh := range []string{filepath.Join(root, "bin", "sh.exe"), filepath.Join(root, "usr", "bin", "sh.exe")} {
+			if _, err := os.Stat(sh); err == nil {
+				return sh
+			}
+		}
+	}
+	if sh, err := exec.LookPath("sh"); err == nil {
+		return sh
+	}
+	return "sh"
+}
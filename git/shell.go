@@ -0,0 +1,24 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package git
+
+import (
+	"context"
+	"strings"
+
+	"github.com/josharian/xc"
+)
+
+// Shell returns a command that runs script with the shell git itself runs hooks, editors, and merge drivers with,
+// and args as $1, $2, and so on: sh, which on Windows is the one that comes with Git for Windows.
+// The command runs in the current directory unless the caller sets one.
+func (g *Git) Shell(ctx context.Context, script string, args ...string) *xc.Builder {
+	return xc.Command(ctx, shellExe(g.bin), append([]string{"-c", script, "sh"}, args...)...)
+}
+
+// ShellQuote quotes s as a single word for sh.
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,279 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// InDir returns a copy of g that runs git commands in dir, typically a worktree.
+// The copy ignores any repository set in the environment, so that git finds the one in dir.
+func (g *Git) InDir(dir string) *Git {
+	g2 := *g
+	g2.root = dir
+	g2.env = slices.DeleteFunc(slices.Clone(g.environ()), func(kv string) bool {
+		key, _, _ := strings.Cut(kv, "=")
+		return slices.Contains(repoEnv, key)
+	})
+	return &g2
+}
+
+// AddWorktree checks out commit, detached, into a new worktree at dir.
+func (g *Git) AddWorktree(ctx context.Context, dir, commit string) error {
+	return g.baseCommand(ctx).
+		AppendArgs("worktree", "add", "--detach", "--quiet", dir, commit).
+		Describef("add worktree for %s", commit).
+		Run().
+		Wait()
+}
+
+// RemoveWorktree removes the worktree at dir, discarding any changes in it.
+func (g *Git) RemoveWorktree(ctx context.Context, dir string) error {
+	return g.baseCommand(ctx).
+		AppendArgs("worktree", "remove", "--force", dir).
+		Describef("remove worktree %s", dir).
+		Run().
+		Wait()
+}
+
+// CommitAll commits all changes in the working tree with message and returns the new commit hash.
+// If there are no changes, it returns "", nil.
+func (g *Git) CommitAll(ctx context.Context, message string) (string, error) {
+	err := g.baseCommand(ctx).
+		AppendArgs("add", "--all").
+		Describe("stage all changes").
+		Run().
+		Wait()
+	if err != nil {
+		return "", err
+	}
+	// Everything is staged, so untracked files need not be scanned.
+	status, err := g.Status(ctx, false)
+	if err != nil || len(status) == 0 {
+		return "", err
+	}
+	err = g.baseCommand(ctx).
+		AppendArgs("commit", "--quiet", "--no-verify", "-m", message).
+		Describe("commit changes").
+		Run().
+		Wait()
+	if err != nil {
+		return "", err
+	}
+	return g.ResolveRef(ctx, "HEAD")
+}
+
+// StatusEntry is a path with changes in the index or working tree, as reported by Status.
+type StatusEntry struct {
+	Path string
+	// XY is the staged and unstaged status of the path, as in git status --porcelain=v2, such as ".M".
+	// It is "??" for untracked files and "UU" and so on for unmerged paths.
+	XY string
+}
+
+// Status returns the paths with changes in the index or working tree.
+// It uses git status, which the repository's core.fsmonitor and core.untrackedCache settings
+// keep fast on very large working trees.
+// Untracked files are listed only if untracked is set; leaving them out skips the slowest part of the scan.
+func (g *Git) Status(ctx context.Context, untracked bool) ([]StatusEntry, error) {
+	mode := "--untracked-files=no"
+	if untracked {
+		mode = "--untracked-files=normal"
+	}
+	out, err := g.baseCommand(ctx).
+		AppendArgs("status", "--porcelain=v2", "-z", "--no-renames", "--ignore-submodules=dirty", mode).
+		Describe("check for changes").
+		Run().
+		String()
+	if err != nil {
+		return nil, err
+	}
+	var entries []StatusEntry
+	records := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	for i := 0; i < len(records); i++ {
+		rec := records[i]
+		if rec == "" {
+			continue
+		}
+		// The number of space-separated fields before the path depends on the record type.
+		var n int
+		switch rec[0] {
+		case '1':
+			n = 8
+		case '2':
+			n = 9
+			i++ // skip the original path
+		case 'u':
+			n = 10
+		case '?':
+			entries = append(entries, StatusEntry{Path: rec[2:], XY: "??"})
+			continue
+		default:
+			continue
+		}
+		fields := strings.SplitN(rec, " ", n+1)
+		if len(fields) != n+1 {
+			return nil, fmt.Errorf("unexpected git status output: %q", rec)
+		}
+		entries = append(entries, StatusEntry{Path: fields[n], XY: fields[1]})
+	}
+	return entries, nil
+}
+
+// UpdateRef points refName at sha, provided that it currently points at old.
+func (g *Git) UpdateRef(ctx context.Context, refName, sha, old string) error {
+	return g.baseCommand(ctx).
+		AppendArgs("update-ref", refName, sha, old).
+		Describef("update %s to %s", refName, sha).
+		Run().
+		Wait()
+}
+
+// SetRef points refName at sha, whatever it pointed at before.
+func (g *Git) SetRef(ctx context.Context, refName, sha string) error {
+	return g.baseCommand(ctx).
+		AppendArgs("update-ref", refName, sha).
+		Describef("update %s to %s", refName, sha).
+		Run().
+		Wait()
+}
+
+// Fetch fetches from remote.
+func (g *Git) Fetch(ctx context.Context, remote string) error {
+	return g.baseCommand(ctx).
+		AppendArgs("fetch", "--quiet", remote).
+		Describef("fetch %s", remote).
+		Run().
+		Wait()
+}
+
+// Push force-pushes sha to refName on remote.
+func (g *Git) Push(ctx context.Context, remote, sha, refName string) error {
+	return g.baseCommand(ctx).
+		AppendArgs("push", "--quiet", "--force", remote, sha+":"+refName).
+		Describef("push %s to %s", refName, remote).
+		Run().
+		Wait()
+}
+
+// DeleteRef deletes refName, provided that it currently points at old.
+func (g *Git) DeleteRef(ctx context.Context, refName, old string) error {
+	return g.baseCommand(ctx).
+		AppendArgs("update-ref", "-d", refName, old).
+		Describef("delete %s", refName).
+		Run().
+		Wait()
+}
+
+// CheckoutTree updates the index and working tree from commit from to commit to,
+// refusing to overwrite local changes.
+func (g *Git) CheckoutTree(ctx context.Context, from, to string) error {
+	return g.baseCommand(ctx).
+		AppendArgs("read-tree", "-m", "-u", from, to).
+		Describef("check out %s", to).
+		Run().
+		Wait()
+}
+
+// ResetTree makes the index and working tree match tree, discarding any conflicts
+// and local changes to tracked files, as git read-tree --reset -u does.
+func (g *Git) ResetTree(ctx context.Context, tree string) error {
+	return g.baseCommand(ctx).
+		AppendArgs("read-tree", "--reset", "-u", tree).
+		Describef("reset to %s", tree).
+		Run().
+		Wait()
+}
+
+// CommitMessage returns the message of commit.
+func (g *Git) CommitMessage(ctx context.Context, commit string) (string, error) {
+	return g.baseCommand(ctx).
+		AppendArgs("log", "-1", "--format=%B", commit).
+		Run().
+		String()
+}
+
+// ListRefs returns the refs under prefix, mapped to the objects they point at.
+func (g *Git) ListRefs(ctx context.Context, prefix string) (map[string]string, error) {
+	lines, err := splitLines(
+		g.baseCommand(ctx).
+			AppendArgs("for-each-ref", "--format=%(refname) %(objectname)", "--", prefix).
+			Run().
+			TrimSpace().
+			String())
+	if err != nil {
+		return nil, err
+	}
+	refs := make(map[string]string)
+	for _, line := range lines {
+		ref, sha, ok := strings.Cut(line, " ")
+		if ok {
+			refs[ref] = sha
+		}
+	}
+	return refs, nil
+}
+
+// Abort aborts the in-progress operation of the given kind, such as "merge" or "rebase",
+// restoring the state from before it started.
+func (g *Git) Abort(ctx context.Context, kind string) error {
+	return g.baseCommand(ctx).
+		AppendArgs(kind, "--abort").
+		Describef("abort %s", kind).
+		Run().
+		Wait()
+}
+
+// Add stages paths, marking any conflicts in them resolved.
+func (g *Git) Add(ctx context.Context, paths ...string) error {
+	return g.baseCommand(ctx).
+		AppendArgs("add", "--").
+		AppendArgs(paths...).
+		Describe("stage resolved paths").
+		Run().
+		Wait()
+}
+
+// PushWithLease pushes sha to refName on remote, replacing it only if the remote still has expect there.
+// An empty expect means the remote must not have refName yet.
+func (g *Git) PushWithLease(ctx context.Context, remote, sha, refName, expect string) error {
+	return g.baseCommand(ctx).
+		AppendArgs("push", "--quiet", "--force-with-lease="+refName+":"+expect, remote, sha+":"+refName).
+		Describef("push %s to %s", refName, remote).
+		Run().
+		Wait()
+}
+
+// AddRemote adds a remote called name for url.
+func (g *Git) AddRemote(ctx context.Context, name, url string) error {
+	return g.baseCommand(ctx).
+		AppendArgs("remote", "add", name, url).
+		Describef("add remote %s", name).
+		Run().
+		Wait()
+}
+
+// SetRemoteHead asks remote for its default branch and records it as name/HEAD.
+func (g *Git) SetRemoteHead(ctx context.Context, name string) error {
+	return g.baseCommand(ctx).
+		AppendArgs("remote", "set-head", name, "--auto").
+		Describef("find the default branch of %s", name).
+		Run().
+		Wait()
+}
+
+// RemoteURL returns the url of remote, or "" if there is no such remote.
+func (g *Git) RemoteURL(ctx context.Context, remote string) (string, error) {
+	return g.baseCommand(ctx).
+		AppendArgs("remote", "get-url", remote).
+		Describef("get URL for remote %s", remote).
+		Run().
+		TrimSpace().
+		AllowExitCodes(2).
+		String()
+}
@@ -0,0 +1,133 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/carlmjohnson/requests"
+)
+
+// githubPathRx extracts owner and repo from the path of a GitHub remote URL,
+// e.g. /owner/repo.git for git@github.com:owner/repo.git or https://github.com/owner/repo.
+var githubPathRx = regexp.MustCompile(`^/([^/]+)/([^/]+?)(?:\.git)?/?$`)
+
+// A githubForge is a repository on GitHub.
+type githubForge struct {
+	cfg         *Config
+	owner, repo string
+}
+
+// githubHost returns the host that serves the repositories of the GitHub API at github_api:
+// github.com for api.github.com, and the API's own host for GitHub Enterprise,
+// whose API lives at https://host/api/v3.
+func githubHost(cfg *Config) string {
+	u, err := url.Parse(cfg.Get(githubAPIKey))
+	if err != nil {
+		return ""
+	}
+	if u.Hostname() == "api.github.com" {
+		return "github.com"
+	}
+	return u.Hostname()
+}
+
+func matchGitHub(cfg *Config, remote string) forge {
+	u, err := parseRemote(remote)
+	if err != nil || !strings.EqualFold(u.Hostname(), githubHost(cfg)) {
+		return nil
+	}
+	m := githubPathRx.FindStringSubmatch(u.Path)
+	if m == nil {
+		return nil
+	}
+	return &githubForge{cfg: cfg, owner: m[1], repo: m[2]}
+}
+
+func (g *githubForge) String() string {
+	return g.owner + "/" + g.repo
+}
+
+func (g *githubForge) tokenKey() string {
+	return githubTokenKey
+}
+
+// githubRequest returns a request builder for the GitHub API.
+func githubRequest(cfg *Config) *requests.Builder {
+	return requests.URL(cfg.Get(githubAPIKey)).
+		Bearer(cfg.Get(githubTokenKey)).
+		Accept("application/vnd.github+json").
+		Header("X-GitHub-Api-Version", "2022-11-28")
+}
+
+func (g *githubForge) findPullRequest(ctx context.Context, branch string) (int, error) {
+	var pulls []struct {
+		Number int `json:"number"`
+	}
+	err := githubRequest(g.cfg).
+		Pathf("/repos/%s/%s/pulls", g.owner, g.repo).
+		Param("head", g.owner+":"+branch).
+		Param("state", "open").
+		ToJSON(&pulls).
+		Fetch(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(pulls) == 0 {
+		return 0, nil
+	}
+	return pulls[0].Number, nil
+}
+
+func (g *githubForge) comment(ctx context.Context, pr int, body string) error {
+	return githubRequest(g.cfg).
+		Pathf("/repos/%s/%s/issues/%d/comments", g.owner, g.repo, pr).
+		BodyJSON(map[string]string{"body": body}).
+		Post().
+		Fetch(ctx)
+}
+
+func (g *githubForge) openPullRequest(ctx context.Context, title, head, base, body string) (int, error) {
+	var created struct {
+		Number int `json:"number"`
+	}
+	err := githubRequest(g.cfg).
+		Pathf("/repos/%s/%s/pulls", g.owner, g.repo).
+		BodyJSON(map[string]string{
+			"title": title,
+			"head":  head,
+			"base":  base,
+			"body":  body,
+		}).
+		ToJSON(&created).
+		Fetch(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return created.Number, nil
+}
+
+func (g *githubForge) parentURL(ctx context.Context) (string, error) {
+	var info struct {
+		Parent *struct {
+			CloneURL string `json:"clone_url"`
+		} `json:"parent"`
+	}
+	err := githubRequest(g.cfg).
+		Pathf("/repos/%s/%s", g.owner, g.repo).
+		ToJSON(&info).
+		Fetch(ctx)
+	if err != nil {
+		return "", fmt.Errorf("looking up %s: %w", g, err)
+	}
+	if info.Parent == nil {
+		return "", nil
+	}
+	return info.Parent.CloneURL, nil
+}
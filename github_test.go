@@ -0,0 +1,39 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import "testing"
+
+func TestMatchGitHub(t *testing.T) {
+	tests := []struct {
+		api    string
+		remote string
+		want   string // owner/repo, or "" for no match
+	}{
+		{remote: "git@github.com:owner/repo.git", want: "owner/repo"},
+		{remote: "https://github.com/owner/repo", want: "owner/repo"},
+		{remote: "ssh://git@github.com/owner/repo.git/", want: "owner/repo"},
+		{remote: "https://notgithub.com/owner/repo"},
+		{remote: "git@github.com:owner/group/repo.git"},
+		{remote: "/srv/git/repo.git"},
+		{api: "https://ghe.example.com/api/v3", remote: "git@ghe.example.com:owner/repo.git", want: "owner/repo"},
+		{api: "https://ghe.example.com/api/v3", remote: "https://ghe.example.com/owner/repo.git", want: "owner/repo"},
+		{api: "https://ghe.example.com/api/v3", remote: "git@github.com:owner/repo.git"},
+	}
+	for _, tt := range tests {
+		t.Setenv(configEnv(githubAPIKey), "")
+		cfg := &Config{Values: map[string]string{}}
+		if tt.api != "" {
+			cfg.Values[githubAPIKey] = tt.api
+		}
+		got := ""
+		if f := matchGitHub(cfg, tt.remote); f != nil {
+			got = f.String()
+		}
+		if got != tt.want {
+			t.Errorf("matchGitHub(%q) with github_api %q = %q, want %q", tt.remote, tt.api, got, tt.want)
+		}
+	}
+}
@@ -10,4 +10,6 @@ require (
 	github.com/peterbourgon/ff/v3 v3.4.0
 )
 
-require golang.org/x/net v0.27.0 // indirect
+require golang.org/x/net v0.27.0
+
+require golang.org/x/crypto v0.25.0
@@ -0,0 +1,30 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"slices"
+)
+
+// negotiateHaves lists the objects the client has, so that the server can leave them out of its response
+// and send the rest as deltas against them.
+// Each commit stands for everything reachable from it: the commits of the operation,
+// and the upstreams of its branches, which the server may know from the remote.
+// Blobs written by local resolutions are listed individually.
+func negotiateHaves(ctx context.Context, cfg *Config, info *deconflictRequestInfo) error {
+	haves := []string{info.mainSHA, info.topicSHA, info.baseSHA}
+	for _, ref := range []string{info.mainRef, info.topicRef} {
+		if sha, err := cfg.Git.ResolveRef(ctx, ref+"@{upstream}"); err == nil {
+			haves = append(haves, sha)
+		}
+	}
+	for _, lr := range info.resolved {
+		haves = append(haves, lr.sha)
+	}
+	slices.Sort(haves)
+	info.haves = slices.Compact(haves)
+	return nil
+}
@@ -0,0 +1,36 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"strings"
+	"time"
+
+	"merde.ai/git"
+)
+
+// gitHeartbeat is how long a git command runs before merde says what it is waiting for,
+// and how often it says so again, so that a slow command does not look like a hang.
+const gitHeartbeat = 5 * time.Second
+
+// observeGit has gg report slow git commands and, with --verbose, every git command it runs.
+// With --git-timings, it also records how long each one takes.
+func observeGit(gg *git.Git) {
+	if flagGitTimings {
+		gg.SetTiming(recordGitTiming)
+	}
+	if flagVerbose {
+		gg.SetTrace(func(args []string) {
+			ui.Status("+ git %s", strings.Join(args, " "))
+		})
+	}
+	gg.SetHeartbeat(gitHeartbeat, func(desc string, elapsed time.Duration) {
+		hint := ""
+		if !flagVerbose {
+			hint = "; merde --verbose shows each git command"
+		}
+		ui.Status("still waiting for git to %s (%v so far%s)", desc, elapsed.Round(time.Second), hint)
+	})
+}
@@ -0,0 +1,139 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/peterbourgon/ff/v3/ffcli"
+)
+
+// helpCacheTTL is how long fetched help is served without asking the server again.
+const helpCacheTTL = 24 * time.Hour
+
+// A cachedHelp is help content fetched from the server.
+type cachedHelp struct {
+	Time   time.Time `json:"time"`
+	Stdout string    `json:"stdout"`
+	Stderr string    `json:"stderr"`
+}
+
+// print writes h's content to stdout and stderr.
+func (h *cachedHelp) print() {
+	fmt.Print(h.Stdout)
+	fmt.Fprint(os.Stderr, h.Stderr)
+}
+
+// helpCachePath returns the file caching the help for the topic named by args.
+func helpCachePath(cfg *Config, args []string) string {
+	topic := "index"
+	if len(args) > 0 {
+		topic = strings.Join(args, "-")
+	}
+	topic = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' {
+			return '_'
+		}
+		return r
+	}, topic)
+	// Help is localized, so each language has its own cache.
+	return filepath.Join(filepath.Dir(cfg.path), "help", language, topic+".json")
+}
+
+// readHelpCache returns the cached help for args, or nil if there is none.
+func readHelpCache(cfg *Config, args []string) *cachedHelp {
+	data, err := os.ReadFile(helpCachePath(cfg, args))
+	if err != nil {
+		return nil
+	}
+	h := new(cachedHelp)
+	if json.Unmarshal(data, h) != nil {
+		return nil
+	}
+	return h
+}
+
+// writeHelpCache caches h as the help for args.
+func writeHelpCache(cfg *Config, args []string, h *cachedHelp) error {
+	path := helpCachePath(cfg, args)
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// fetchHelp fetches and prints the help for args from the server.
+func fetchHelp(ctx context.Context, cfg *Config, args []string) (*cachedHelp, error) {
+	req, err := helpRequest(ctx, cfg, args)
+	if err != nil {
+		return nil, err
+	}
+	h := &cachedHelp{Time: time.Now()}
+	for part, err := range doRequest(cfg, req) {
+		if err != nil {
+			return nil, err
+		}
+		_, err := part.Process(ctx, cfg) // ignore binary data
+		if err != nil {
+			return nil, err
+		}
+		h.Stdout += part.Stdout
+		h.Stderr += part.Stderr
+	}
+	return h, nil
+}
+
+// builtinHelp prints the usage of the command named by args, from the command definitions.
+func builtinHelp(args []string) {
+	cmd := rootCommand
+	for _, name := range args {
+		for _, sub := range cmd.Subcommands {
+			if sub.Name == name {
+				cmd = sub
+				break
+			}
+		}
+	}
+	fmt.Println(ffcli.DefaultUsageFunc(cmd))
+}
+
+func doHelp(ctx context.Context, args []string) error {
+	cfg, err := LoadDefault(ctx)
+	if err != nil {
+		return err
+	}
+	cached := readHelpCache(cfg, args)
+	if cached != nil && time.Since(cached.Time) < helpCacheTTL {
+		cached.print()
+		return nil
+	}
+	h, err := fetchHelp(ctx, cfg, args)
+	if err == nil {
+		return writeHelpCache(cfg, args, h)
+	}
+	if !isNetworkError(err) {
+		return err
+	}
+	if cached != nil {
+		ui.Warn("merde.ai is unreachable; showing help cached %s, which may be out of date", humanize.Time(cached.Time))
+		cached.print()
+		return nil
+	}
+	ui.Warn("merde.ai is unreachable; showing built-in usage only")
+	builtinHelp(args)
+	return nil
+}
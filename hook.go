@@ -0,0 +1,418 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// hookScript is a shell function wrapping git that offers merde when a merge, rebase, or cherry-pick stops on conflicts.
+// It works in bash and zsh.
+const hookScript = `# merde hook: offer merde when git merge/rebase/cherry-pick stops on conflicts.
+# Installed by "merde hook install"; remove with "merde hook uninstall".
+git() {
+	command git "$@"
+	local merde_status=$?
+	case "$1" in
+	merge|rebase|pull|cherry-pick)
+		if [ $merde_status -ne 0 ] && [ -n "$(command git diff --name-only --diff-filter=U 2>/dev/null)" ]; then
+			if [ "${MERDE_HOOK_AUTO:-%d}" = 1 ]; then
+				merde continue
+				return $?
+			fi
+			printf 'merde: conflicts detected. resolve them with merde? [y/N] '
+			local merde_answer
+			read -r merde_answer
+			case "$merde_answer" in
+			[yY]*) merde continue; return $? ;;
+			esac
+		fi
+		;;
+	esac
+	return $merde_status
+}
+`
+
+// hookMarker marks the line that hook install adds to shell rc files.
+const hookMarker = "# merde hook"
+
+// hookPath returns the path of the installed hook script.
+func hookPath() (string, error) {
+	configPath, err := DefaultPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), "hook.sh"), nil
+}
+
+// shellRC returns the rc file of the user's shell.
+func shellRC() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	switch filepath.Base(os.Getenv("SHELL")) {
+	case "zsh":
+		return filepath.Join(home, ".zshrc"), nil
+	case "bash", "":
+		return filepath.Join(home, ".bashrc"), nil
+	}
+	return "", fmt.Errorf("unsupported shell %s; source the output of 'merde hook print' from your shell's rc file instead", os.Getenv("SHELL"))
+}
+
+func renderHook(auto bool) string {
+	autoDefault := 0
+	if auto {
+		autoDefault = 1
+	}
+	return fmt.Sprintf(hookScript, autoDefault)
+}
+
+func doHookPrint(ctx context.Context, args []string) error {
+	fmt.Print(renderHook(flagHookAuto))
+	return nil
+}
+
+func doHookInstall(ctx context.Context, args []string) error {
+	path, err := hookPath()
+	if err != nil {
+		return err
+	}
+	rc, err := shellRC()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(path, []byte(renderHook(flagHookAuto)), 0o644)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(rc)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if !bytes.Contains(data, []byte(hookMarker)) {
+		f, err := os.OpenFile(rc, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(f, "\n[ -f %q ] && . %q %s\n", path, path, hookMarker)
+		err = f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	ui.Status("installed merde hook in %s; open a new shell to use it", rc)
+	return nil
+}
+
+func doHookUninstall(ctx context.Context, args []string) error {
+	path, err := hookPath()
+	if err != nil {
+		return err
+	}
+	rc, err := shellRC()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(rc)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var kept []string
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if line != "" && !strings.Contains(line, hookMarker) {
+			kept = append(kept, line)
+		}
+	}
+	if len(kept) > 0 && kept[len(kept)-1] == "\n" {
+		kept = kept[:len(kept)-1] // the blank line added by install
+	}
+	if len(data) > 0 {
+		err = os.WriteFile(rc, []byte(strings.Join(kept, "")), 0o644)
+		if err != nil {
+			return err
+		}
+	}
+	err = os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	ui.Status("uninstalled merde hook from %s", rc)
+	return nil
+}
+
+// inProgress describes a conflicted git merge, rebase, or cherry-pick in progress.
+type inProgress struct {
+	verb     string // merge, rebase, or cherry-pick
+	mainRef  string // empty for a cherry-pick
+	topicRef string // empty for the current branch
+}
+
+// findInProgress returns the git merge, rebase, or cherry-pick in progress, or nil if there is none.
+func findInProgress(ctx context.Context, cfg *Config) (*inProgress, error) {
+	gitDir, err := cfg.Git.GitDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	readState := func(name string) string {
+		data, _ := os.ReadFile(filepath.Join(gitDir, name))
+		return strings.TrimSpace(string(data))
+	}
+	if head := readState("MERGE_HEAD"); head != "" {
+		return &inProgress{verb: "merge", mainRef: strings.Fields(head)[0]}, nil
+	}
+	for _, dir := range []string{"rebase-merge", "rebase-apply"} {
+		if onto := readState(dir + "/onto"); onto != "" {
+			topic := strings.TrimPrefix(readState(dir+"/head-name"), "refs/heads/")
+			return &inProgress{verb: "rebase", mainRef: onto, topicRef: topic}, nil
+		}
+	}
+	if readState("CHERRY_PICK_HEAD") != "" {
+		return &inProgress{verb: "cherry-pick"}, nil
+	}
+	return nil, nil
+}
+
+// doContinue hands an in-progress, conflicted git merge, rebase, or cherry-pick over to merde:
+// it resolves the conflicts git stopped on and stages the result, for the user to finish with git.
+// Paths the user has already resolved keep their resolution.
+func doContinue(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		return usageErrorf("merde continue takes no arguments")
+	}
+	cfg, err := LoadDefault(ctx)
+	if err != nil {
+		return err
+	}
+	ip, err := findInProgress(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if ip == nil {
+		return fmt.Errorf("no conflicted merge, rebase, or cherry-pick in progress")
+	}
+	switch ip.verb {
+	case "merge":
+		return continueMerge(ctx, cfg)
+	case "rebase":
+		return continueRebase(ctx, cfg)
+	}
+	return continueCherryPick(ctx, cfg)
+}
+
+// continueMerge resolves the conflicted git merge in progress with merde and stages the result in it,
+// so that the user can finish it with git commit, keeping git's merge message.
+// Paths the user has already resolved keep their resolution.
+func continueMerge(ctx context.Context, cfg *Config) error {
+	gitDir, err := cfg.Git.GitDir(ctx)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filepath.Join(gitDir, "MERGE_HEAD"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("no git merge in progress")
+	}
+	if err != nil {
+		return err
+	}
+	heads := strings.Fields(string(data))
+	if len(heads) != 1 {
+		return fmt.Errorf("cannot continue an octopus merge of %d branches", len(heads))
+	}
+	head, err := cfg.Git.FullRefName(ctx, "HEAD")
+	if err != nil {
+		return err
+	}
+	branch, ok := strings.CutPrefix(head, "refs/heads/")
+	if !ok {
+		return fmt.Errorf("cannot continue a merge into a detached HEAD")
+	}
+	message, err := os.ReadFile(filepath.Join(gitDir, "MERGE_MSG"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	_, conflicts, err := cfg.Git.MergeTree(ctx, "HEAD", heads[0])
+	if err != nil {
+		return err
+	}
+	kept, err := userResolutions(ctx, cfg, conflicts)
+	if err != nil {
+		return err
+	}
+	ui.Status("plan: resolve the git merge of %s into %s", heads[0][:12], branch)
+	// The merge in progress stays as it is until merde has a result to replace it with.
+	op, err := deconflict(ctx, cfg, "merge", heads[0], branch)
+	if err != nil {
+		return err
+	}
+	err = cfg.Git.Abort(ctx, "merge")
+	if err != nil {
+		return err
+	}
+	err = stageMerge(ctx, cfg, op)
+	if err != nil {
+		return err
+	}
+	if len(message) > 0 {
+		err = os.WriteFile(filepath.Join(gitDir, "MERGE_MSG"), message, 0o644)
+		if err != nil {
+			return err
+		}
+	}
+	return kept.restore(ctx, cfg)
+}
+
+// resolvedFiles holds the contents of files in the working tree, by path relative to its root;
+// nil contents mean the file was deleted.
+type resolvedFiles struct {
+	root  string
+	files map[string][]byte
+	modes map[string]os.FileMode
+}
+
+// userResolutions returns those of the conflicts of the git operation in progress
+// that are no longer unmerged, because the user has resolved them, with their contents.
+func userResolutions(ctx context.Context, cfg *Config, conflicts []string) (*resolvedFiles, error) {
+	root, err := cfg.Git.RootDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	unmerged, err := cfg.Git.UnmergedPaths(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rf := &resolvedFiles{root: root, files: make(map[string][]byte), modes: make(map[string]os.FileMode)}
+	for _, p := range conflicts {
+		if slices.Contains(unmerged, p) {
+			continue
+		}
+		name := filepath.Join(root, filepath.FromSlash(p))
+		fi, err := os.Lstat(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			rf.files[p] = nil
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !fi.Mode().IsRegular() {
+			continue // symlinks and submodules are left to merde
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		rf.files[p] = data
+		rf.modes[p] = fi.Mode().Perm()
+	}
+	return rf, nil
+}
+
+// restore writes rf's files back to the working tree and stages them.
+func (rf *resolvedFiles) restore(ctx context.Context, cfg *Config) error {
+	if len(rf.files) == 0 {
+		return nil
+	}
+	var paths []string
+	for p, data := range rf.files {
+		name := filepath.Join(rf.root, filepath.FromSlash(p))
+		var err error
+		if data == nil {
+			err = os.Remove(name)
+			if errors.Is(err, fs.ErrNotExist) {
+				err = nil
+			}
+		} else {
+			err = os.WriteFile(name, data, rf.modes[p])
+		}
+		if err != nil {
+			return err
+		}
+		paths = append(paths, p)
+	}
+	slices.Sort(paths)
+	ui.Status("kept your resolutions of %s", strings.Join(paths, ", "))
+	return cfg.Git.Add(ctx, paths...)
+}
+
+// continueRebase resolves the conflicted step of the git rebase in progress with merde and stages the result,
+// so that the user can carry on with git rebase --continue.
+// Paths the user has already resolved keep their resolution.
+func continueRebase(ctx context.Context, cfg *Config) error {
+	ip, err := findInProgress(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if ip == nil || ip.verb != "rebase" {
+		return fmt.Errorf("no git rebase in progress")
+	}
+	return continuePick(ctx, cfg, "rebase", "REBASE_HEAD")
+}
+
+// continueCherryPick resolves the conflicted pick of the git cherry-pick in progress with merde and stages the result,
+// so that the user can carry on with git cherry-pick --continue.
+// Paths the user has already resolved keep their resolution.
+func continueCherryPick(ctx context.Context, cfg *Config) error {
+	ip, err := findInProgress(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if ip == nil || ip.verb != "cherry-pick" {
+		return fmt.Errorf("no git cherry-pick in progress")
+	}
+	return continuePick(ctx, cfg, "cherry-pick", "CHERRY_PICK_HEAD")
+}
+
+// continuePick resolves the conflicted pick of pickRef onto HEAD that the git verb in progress stopped on,
+// and stages the result.
+func continuePick(ctx context.Context, cfg *Config, verb, pickRef string) error {
+	unmerged, err := cfg.Git.UnmergedPaths(ctx)
+	if err != nil {
+		return err
+	}
+	if len(unmerged) == 0 {
+		return fmt.Errorf("the git %s in progress has no conflicts; carry on with: git %s --continue", verb, verb)
+	}
+	pick, err := cfg.Git.ResolveRef(ctx, pickRef)
+	if err != nil {
+		return err
+	}
+	head, err := cfg.Git.ResolveRef(ctx, "HEAD")
+	if err != nil {
+		return err
+	}
+	ui.Status("plan: resolve the git %s's pick of %s onto %s", verb, pick[:12], head[:12])
+	// The operation in progress stays as it is until merde has a result to replace it with.
+	tree, conflicts, err := pickedTree(ctx, cfg, pick, head)
+	if err != nil {
+		return err
+	}
+	kept, err := userResolutions(ctx, cfg, conflicts)
+	if err != nil {
+		return err
+	}
+	err = cfg.Git.ResetTree(ctx, tree)
+	if err != nil {
+		return err
+	}
+	err = kept.restore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	ui.Status("staged the resolved pick; adjust it and run git %s --continue (or git %s --abort)", verb, verb)
+	return nil
+}
@@ -14,11 +14,11 @@ import (
 	"mime"
 	"mime/multipart"
 	"net/http"
-	"os"
 	"runtime"
 	"strings"
 
 	"github.com/carlmjohnson/requests"
+	"github.com/dustin/go-humanize"
 )
 
 var (
@@ -29,16 +29,22 @@ var (
 )
 
 func baseRequest(cfg *Config) *requests.Builder {
-	return requests.New().
+	rb := requests.New()
+	headers, _ := authHeaders(cfg) // configureHTTP has warned about any error
+	for _, h := range headers {
+		rb.Header(h[0], h[1])
+	}
+	return rb.
 		Bearer(cfg.Get(tokenKey)).
 		Accept("multipart/mixed").
-		Header("Git-Version", cfg.GitVersion).
+		HeaderOptional("Git-Version", unlessRedacted(cfg, redactGit, cfg.GitVersion)).
 		Header("Merde-Client-Version", version).
 		Header("Merde-Client-Commit", commit).
 		Header("Merde-Client-Date", date).
-		Header("Merde-Client-OS", runtime.GOOS).
-		Header("Merde-Client-Arch", runtime.GOARCH).
-		Header("Merde-Client-Go", runtime.Version()).
+		HeaderOptional("Merde-Client-OS", unlessRedacted(cfg, redactOS, runtime.GOOS)).
+		HeaderOptional("Merde-Client-Arch", unlessRedacted(cfg, redactArch, runtime.GOARCH)).
+		HeaderOptional("Merde-Client-Go", unlessRedacted(cfg, redactGo, runtime.Version())).
+		HeaderOptional("Accept-Language", unlessRedacted(cfg, redactLanguage, language)).
 		Header("Merde-Client-API-Version", apiRequestVersion).
 		BaseURL(cfg.Get(serverRootKey))
 }
@@ -56,23 +62,63 @@ func helpRequest(ctx context.Context, cfg *Config, args []string) (*http.Request
 }
 
 func deconflictRequest(ctx context.Context, cfg *Config, info *deconflictRequestInfo) (*http.Request, error) {
-	remotes, _ := cfg.Git.Remotes(ctx) // best effort
+	rate, err := uploadRate(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var remotes []string
+	if !redacted(cfg, redactRemotes) {
+		remotes, _ = forgeRemotes(ctx, cfg) // best effort
+	}
 	req := baseRequest(cfg).
 		Path("/cli/"+info.verb+"/").
 		Param("args", info.args...).
-		Header("Main-Ref", info.mainRef).
-		Header("Topic-Ref", info.topicRef).
+		HeaderOptional("Main-Ref", unlessRedacted(cfg, redactRefs, info.mainRef)).
+		HeaderOptional("Topic-Ref", unlessRedacted(cfg, redactRefs, info.topicRef)).
 		Header("Main-SHA", info.mainSHA).
 		Header("Topic-SHA", info.topicSHA).
-		Header("Pack-Size", fmt.Sprintf("%d", len(info.pack))).
+		HeaderOptional("Pack-Size", packSizeParam(info)). // unknown until the end when streaming
+		HeaderOptional("Pack-Objects", packObjectsParam(info)).
+		Header("Accept-Thin-Pack", "true"). // the response may delta against the haves
+		Header("Result-Format", cfg.Get(resultFormatKey)).
+		HeaderOptional("Retention", retentionParam(cfg)).
 		Method("POST").
-		BodyReader(strings.NewReader(info.pack))
-	for _, remote := range remotes {
-		req = req.Header("Remote", remote)
+		Body(func() (io.ReadCloser, error) {
+			info.uploaded.Store(0)
+			if info.packPlan != nil {
+				return countingReader{streamPack(ctx, cfg, info, rate), &info.uploaded}, nil
+			}
+			return countingReader{io.NopCloser(throttle(strings.NewReader(info.pack), rate)), &info.uploaded}, nil
+		}).
+		Header("Remote", remotes...).
+		Param("delete_modify", stringsOf(info.deleteModify)...).
+		Param("mode", stringsOf(info.modes)...).
+		Param("resolved", stringsOf(info.resolved)...).
+		Param("eol", stringsOf(info.eols)...).
+		Param("generated", stringsOf(info.generated)...).
+		Param("duplicate", stringsOf(info.duplicates)...).
+		Param("repeated_hunk", stringsOf(info.repeatedHunks)...).
+		Param("landed", info.landed...).
+		Param("plan", stringsOf(info.plan)...).
+		Param("reword", rewords(info.plan)...).
+		Param("have", info.haves...).
+		ParamOptional("split", info.split).
+		ParamOptional("min_confidence", cfg.Get(minConfidenceKey))
+	if len(info.landed) > 0 {
+		req = req.Param("drop_landed", fmt.Sprint(info.dropLanded))
 	}
 	return req.Request(ctx)
 }
 
+// stringsOf returns the string forms of xs, for use as repeated query parameters.
+func stringsOf[T fmt.Stringer](xs []T) []string {
+	var out []string
+	for _, x := range xs {
+		out = append(out, x.String())
+	}
+	return out
+}
+
 // A Response is a response from the server.
 // It is a union type between a JSON response and a binary response.
 type Response struct {
@@ -88,57 +134,210 @@ type Response struct {
 	Ref string `json:"ref"`
 	SHA string `json:"sha"`
 
+	OperationID string `json:"operation_id"` // the server's ID for the operation, for the audit log
+
+	ObjectsDeleted bool `json:"objects_deleted"` // the server deleted the uploaded objects, as Retention: ephemeral asks
+
+	// Resolution response fields
+	Resolutions []Resolution `json:"resolutions"` // how the server resolved conflicts
+
 	// Binary response fields
 	Data *bytes.Buffer `json:"-"`
+
+	// Prompt response fields
+	Prompt *Prompt `json:"-"`
+
+	// Patch response fields, when the result comes as patches rather than a pack
+	Patch *patchSet `json:"-"`
+}
+
+// A Prompt is a question the server asks the user mid-operation.
+// The server keeps the response open until the answer arrives at Continuation.
+type Prompt struct {
+	ID           string   `json:"id"`
+	Question     string   `json:"question"`
+	Choices      []string `json:"choices"`      // allowed answers; if empty, any text
+	Default      string   `json:"default"`      // answer to use when the user cannot be asked
+	Continuation string   `json:"continuation"` // path to POST the answer to
+
+	reply func(ctx context.Context, answer string) error // if non-nil, sends the answer instead of POSTing it
+}
+
+// answer asks the user p's question and sends their answer to the server.
+func (p *Prompt) answer(ctx context.Context, cfg *Config) error {
+	var answer string
+	var err error
+	switch {
+	case !interactive && p.Default != "":
+		answer = p.Default
+	case len(p.Choices) > 0:
+		answer, err = prompt(fmt.Sprintf("%s [%s]", p.Question, strings.Join(p.Choices, "/")), p.Choices...)
+	default:
+		answer, err = promptText(p.Question)
+	}
+	if err != nil {
+		return err
+	}
+	if p.reply != nil {
+		return p.reply(ctx, answer)
+	}
+	return baseRequest(cfg).
+		Path(p.Continuation).
+		BodyJSON(map[string]string{"id": p.ID, "answer": answer}).
+		Fetch(ctx)
+}
+
+// A Resolution describes how the server resolved the conflicts in one path.
+type Resolution struct {
+	Path        string  `json:"path"`
+	Explanation string  `json:"explanation"`
+	Confidence  float64 `json:"confidence"` // between 0 and 1; 0 if unknown
+	Hunks       []Hunk  `json:"hunks"`      // per-hunk detail, if the server provides it
+	Commit      string  `json:"commit"`     // for a rebase, the replayed commit the resolution is in; "" for the last
 }
 
 // Process auto-handles json responses and reports whether it was processed.
 func (r *Response) Process(ctx context.Context, cfg *Config) (bool, error) {
+	if r.Prompt != nil {
+		return true, r.Prompt.answer(ctx, cfg)
+	}
 	if !r.IsJSON {
 		return false, nil
 	}
 	if r.Ref != "" && r.SHA != "" {
+		// Defense in depth: never let the server touch branches or tags unless the user opted in.
+		if !refAllowed(cfg, r.Ref) {
+			return false, fmt.Errorf("refusing to create %s: the server may only create refs under %s unless you allow more with: merde config %s <patterns>", r.Ref, refNamespace(cfg), allowedRefsKey)
+		}
 		err := cfg.Git.CreateRef(ctx, r.Ref, r.SHA)
 		if err != nil {
 			return false, err
 		}
 	}
-	if r.Stdout != "" {
-		fmt.Print(r.Stdout)
-	}
-	if r.Stderr != "" {
-		fmt.Fprint(os.Stderr, r.Stderr)
+	if r.Stdout != "" || r.Stderr != "" {
+		ui.Server(r.Stdout, r.Stderr)
 	}
-	if r.ExitCode > 0 {
-		os.Exit(r.ExitCode)
+	if r.ExitCode > 0 && !requestExit(ctx, r.ExitCode) {
+		return true, &ExitError{Code: r.ExitCode}
 	}
 	return true, nil
 }
 
-func doRequest(req *http.Request) iter.Seq2[*Response, error] {
-	return func(yield func(*Response, error) bool) {
-		resp, err := http.DefaultClient.Do(req)
+// sizeLimit returns the byte size configured for key.
+func sizeLimit(cfg *Config, key string) (int64, error) {
+	n, err := humanize.ParseBytes(cfg.Get(key))
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return int64(n), nil
+}
+
+// A limitedReader reads from r until n bytes have been read, then fails with an error naming limit.
+// Unlike io.LimitReader, hitting the limit is an error rather than EOF.
+type limitedReader struct {
+	r     io.Reader
+	n     int64
+	limit string
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		return 0, fmt.Errorf("server response exceeds %s; raise it with: merde config %s <size>", l.limit, l.limit)
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n+1] // read one byte past the limit, to tell "exactly n" from "more than n"
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, fmt.Errorf("server response exceeds %s; raise it with: merde config %s <size>", l.limit, l.limit)
+	}
+	return n, err
+}
+
+// responseLimits returns the configured limits on the size of one part and of a whole response.
+func responseLimits(cfg *Config) (part, total int64, err error) {
+	part, err = sizeLimit(cfg, maxPartSizeKey)
+	if err != nil {
+		return 0, 0, err
+	}
+	total, err = sizeLimit(cfg, maxResponseSizeKey)
+	if err != nil {
+		return 0, 0, err
+	}
+	return part, total, nil
+}
+
+// doRequest sends req and yields the parts of the server's response,
+// using the configured transport.
+// If server_key is set, it asks the server to sign each part and checks the signatures.
+func doRequest(cfg *Config, req *http.Request) iter.Seq2[*Response, error] {
+	v, err := newPartVerifier(cfg, req)
+	if err != nil {
+		return func(yield func(*Response, error) bool) { yield(nil, err) }
+	}
+	switch cfg.Get(transportKey) {
+	case transportSSE:
+		return doSSERequest(cfg, req, v)
+	case transportWS:
+		return doWSRequest(cfg, req, v)
+	}
+	return doMultipartRequest(cfg, req, v)
+}
+
+// startResponse sends req and checks the status and API version of the response.
+// If an SSO proxy in front of the server asks the user to log in, it walks them through it and tries once more.
+func startResponse(cfg *Config, req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if sso := ssoChallenge(cfg, req, resp); sso != nil {
+		discard(resp.Body)
+		retry, err := ssoLogin(cfg, sso)
 		if err != nil {
-			yield(nil, err)
-			return
+			return nil, err
 		}
-		defer resp.Body.Close()
-		switch resp.StatusCode {
-		case http.StatusOK:
-			// continued below
-		default:
-			buf, _ := io.ReadAll(resp.Body)
-			err := fmt.Errorf("unexpected status code %d for %s: %s", resp.StatusCode, req.URL, string(buf))
+		// Replay the body only now: getting it again may start work, such as streaming a pack.
+		again := retryable(req)
+		if !retry || again == nil {
+			return nil, fmt.Errorf("logged in; run merde again")
+		}
+		resp, err = http.DefaultClient.Do(again)
+		if err != nil {
+			return nil, err
+		}
+		if sso := ssoChallenge(cfg, again, resp); sso != nil {
+			discard(resp.Body)
+			return nil, sso
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		buf, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code %d for %s: %s", resp.StatusCode, req.URL, string(buf))
+	}
+	serverVersion := resp.Header.Get("Merde-Server-API-Version")
+	if serverVersion != apiResponseVersion {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected response version %q, please update this client", serverVersion)
+	}
+	return resp, nil
+}
+
+func doMultipartRequest(cfg *Config, req *http.Request, v *partVerifier) iter.Seq2[*Response, error] {
+	return func(yield func(*Response, error) bool) {
+		maxPart, maxResponse, err := responseLimits(cfg)
+		if err != nil {
 			yield(nil, err)
 			return
 		}
-
-		serverVersion := resp.Header.Get("Merde-Server-API-Version")
-		if serverVersion != apiResponseVersion {
-			err := fmt.Errorf("unexpected response version %q, please update this client", serverVersion)
+		resp, err := startResponse(cfg, req)
+		if err != nil {
 			yield(nil, err)
 			return
 		}
+		defer resp.Body.Close()
 
 		mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
 		if err != nil {
@@ -150,45 +349,80 @@ func doRequest(req *http.Request) iter.Seq2[*Response, error] {
 			yield(nil, err)
 			return
 		}
-		mr := multipart.NewReader(resp.Body, params["boundary"])
+		// Enforce the limits while streaming, so that an oversized response fails before it exhausts memory.
+		body := &limitedReader{r: resp.Body, n: maxResponse, limit: maxResponseSizeKey}
+		mr := multipart.NewReader(body, params["boundary"])
 
 		for {
 			p, err := mr.NextPart()
 			if err == io.EOF {
-				break
+				if err := v.finish(); err != nil {
+					yield(nil, err)
+				}
+				return
 			}
 			if err != nil {
 				yield(nil, err)
 				return
 			}
-			switch p.Header.Get("Content-Type") {
-			case "application/json":
-				var r Response
-				err = json.NewDecoder(p).Decode(&r)
-				if err != nil {
-					yield(nil, err)
-					return
-				}
-				r.IsJSON = true
-				if !yield(&r, nil) {
-					return
-				}
-			case "application/octet-stream":
-				buf := new(bytes.Buffer)
-				_, err := io.Copy(buf, p)
-				if err != nil {
-					yield(nil, err)
-					return
-				}
-				r := &Response{Data: buf}
-				if !yield(r, nil) {
-					return
-				}
-			default:
-				err := fmt.Errorf("unexpected multipart content type: %s", p.Header.Get("Content-Type"))
-				yield(nil, err)
+			r, err := decodePart(v, p.Header.Get("Content-Type"), p, maxPart)
+			if err == nil && r == nil {
+				continue // signature or end
+			}
+			if !yield(r, err) || err != nil {
 				return
 			}
 		}
 	}
 }
+
+// decodePart decodes one part of a response, of the given content type, from body,
+// checking its signature with v if v is non-nil.
+// A signature part is recorded in v, and it and the end part decode to nil, nil.
+func decodePart(v *partVerifier, contentType string, body io.Reader, maxPart int64) (*Response, error) {
+	body = &limitedReader{r: body, n: maxPart, limit: maxPartSizeKey}
+	if contentType == signatureContentType {
+		return nil, v.record(body)
+	}
+	if contentType == endContentType && v == nil {
+		return nil, nil
+	}
+	body, err := v.verify(contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	switch contentType {
+	case endContentType:
+		return nil, nil
+	case "application/json":
+		r := new(Response)
+		err := json.NewDecoder(body).Decode(r)
+		if err != nil {
+			return nil, err
+		}
+		r.IsJSON = true
+		return r, nil
+	case "application/x-merde-prompt":
+		q := new(Prompt)
+		err := json.NewDecoder(body).Decode(q)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{Prompt: q}, nil
+	case "application/x-merde-patch":
+		ps := new(patchSet)
+		err := json.NewDecoder(body).Decode(ps)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{Patch: ps}, nil
+	case "application/octet-stream":
+		buf := new(bytes.Buffer)
+		_, err := io.Copy(buf, body)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{Data: buf}, nil
+	}
+	return nil, fmt.Errorf("unexpected part content type: %s", contentType)
+}
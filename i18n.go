@@ -0,0 +1,65 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// language is the user's language for messages, as a BCP 47 tag such as "de-DE",
+// or "" for untranslated English. main sets it once flags are parsed.
+var language string
+
+// catalogs holds the translations of messages, by language tag and then by English message.
+// Messages are keyed by their English format string, so anything without a translation
+// falls back to English. Translations may reorder arguments with explicit indexes, as in %[2]s.
+var catalogs = map[string]map[string]string{}
+
+// configureLanguage sets language from the language config or, failing that, the locale environment.
+func configureLanguage() error {
+	cfg, err := loadConfigValues()
+	if err != nil {
+		return err
+	}
+	language = localeLanguage(cfg.Get(languageKey))
+	return nil
+}
+
+// localeLanguage returns the language tag for locale, a POSIX locale such as de_DE.UTF-8.
+// If locale is "", it uses LC_ALL, LC_MESSAGES, or LANG, in the order gettext does.
+// It returns "" for the C and POSIX locales.
+func localeLanguage(locale string) string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if locale != "" {
+			break
+		}
+		locale = os.Getenv(name)
+	}
+	locale, _, _ = strings.Cut(locale, ".")
+	locale, _, _ = strings.Cut(locale, "@")
+	if locale == "C" || locale == "POSIX" {
+		return ""
+	}
+	return strings.ReplaceAll(locale, "_", "-")
+}
+
+// tr returns the translation of msg into the user's language,
+// trying the full tag and then each shorter prefix, such as de-DE and then de.
+// It returns msg if there is no translation.
+func tr(msg string) string {
+	tag := strings.ToLower(language)
+	for tag != "" {
+		if t, ok := catalogs[tag][msg]; ok {
+			return t
+		}
+		i := strings.LastIndex(tag, "-")
+		if i < 0 {
+			break
+		}
+		tag = tag[:i]
+	}
+	return msg
+}
@@ -0,0 +1,40 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+// German translations of the most common messages.
+func init() {
+	catalogs["de"] = map[string]string{
+		"warning": "Warnung",
+		"error":   "Fehler",
+
+		"please answer one of: %s": "bitte antworten Sie mit einem von: %s",
+		"adopt it anyway?":         "trotzdem übernehmen?",
+		"resolve with merde?":      "mit merde auflösen?",
+		"keep this history?":       "diese Historie behalten?",
+
+		"plan: merge %s into %s":                           "Plan: %s in %s mergen",
+		"plan: rebase %s onto %s":                          "Plan: %s auf %s rebasen",
+		"analyzing...":                                     "analysiere...",
+		"uploading %d objects as they are packed...":       "lade %d Objekte hoch, während sie gepackt werden...",
+		"uploading %v...":                                  "lade %v hoch...",
+		"analyzing":                                        "Analyse",
+		"uploading":                                        "Hochladen",
+		"%s: %v so far":                                    "%s: bisher %v",
+		"%s: %d%% of %s (%s/s, %s left)":                   "%s: %d%% von %s (%s/s, noch %s)",
+		"%s: %s (%s/s)":                                    "%s: %s (%s/s)",
+		"handled %d of %d conflicting paths locally":       "%d von %d Pfaden mit Konflikten lokal behandelt",
+		"dry run: merge %s into %s (merge base %s)":        "Probelauf: %s in %s mergen (Merge-Basis %s)",
+		"dry run: rebase %s onto %s (merge base %s)":       "Probelauf: %s auf %s rebasen (Merge-Basis %s)",
+		"no conflicting paths; git can do this on its own": "keine Pfade mit Konflikten; git schafft das allein",
+		"%d conflicting paths:\n%s":                        "%d Pfade mit Konflikten:\n%s",
+		"pack: %d objects, %s (before any conflicts merde resolves locally)": "Pack: %d Objekte, %s (bevor merde Konflikte lokal auflöst)",
+		"nothing was sent to the server":                                     "es wurde nichts an den Server gesendet",
+		"waiting for %s to finish...":                                        "warte auf das Ende von %s...",
+		"token stored":                                                       "Token gespeichert",
+		"wrote report to %s":                                                 "Bericht nach %s geschrieben",
+		"operation %s recorded; review it with: merde review, then adopt it with: merde adopt": "Operation %s aufgezeichnet; prüfen mit: merde review, dann übernehmen mit: merde adopt",
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// JSON-RPC 2.0 error codes.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+)
+
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// An rpcHandler handles one JSON-RPC method.
+type rpcHandler func(ctx context.Context, params json.RawMessage) (any, error)
+
+// An rpcConn is a JSON-RPC 2.0 connection over a byte stream.
+// Messages are framed with LSP-style Content-Length headers,
+// or, if newlineFramed, one message per line.
+type rpcConn struct {
+	r             *bufio.Reader
+	newlineFramed bool
+
+	mu sync.Mutex // guards w
+	w  io.Writer
+}
+
+func newRPCConn(r io.Reader, w io.Writer, newlineFramed bool) *rpcConn {
+	return &rpcConn{r: bufio.NewReader(r), w: w, newlineFramed: newlineFramed}
+}
+
+// read reads the next message.
+func (c *rpcConn) read() ([]byte, error) {
+	if c.newlineFramed {
+		for {
+			line, err := c.r.ReadBytes('\n')
+			if len(strings.TrimSpace(string(line))) > 0 {
+				return line, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	header, err := textproto.NewReader(c.r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("bad Content-Length: %w", err)
+	}
+	buf := make([]byte, n)
+	_, err = io.ReadFull(c.r, buf)
+	return buf, err
+}
+
+// write writes msg.
+func (c *rpcConn) write(msg *rpcMessage) error {
+	msg.JSONRPC = "2.0"
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.newlineFramed {
+		_, err = fmt.Fprintf(c.w, "%s\n", data)
+		return err
+	}
+	_, err = fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	return err
+}
+
+// notify sends a notification, ignoring errors; notifications are best effort.
+func (c *rpcConn) notify(method string, params any) {
+	data, _ := json.Marshal(params)
+	c.write(&rpcMessage{Method: method, Params: data})
+}
+
+// serve reads requests from c and dispatches them to handlers concurrently, until the stream ends
+// or a handler for the method exitMethod is called.
+func (c *rpcConn) serve(ctx context.Context, handlers map[string]rpcHandler, exitMethod string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		data, err := c.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		msg := new(rpcMessage)
+		err = json.Unmarshal(data, msg)
+		if err != nil {
+			c.write(&rpcMessage{ID: json.RawMessage("null"), Error: &rpcError{Code: rpcParseError, Message: err.Error()}})
+			continue
+		}
+		if msg.Method == exitMethod {
+			return nil
+		}
+		isNotification := len(msg.ID) == 0
+		h, ok := handlers[msg.Method]
+		if !ok {
+			if !isNotification {
+				c.write(&rpcMessage{ID: msg.ID, Error: &rpcError{Code: rpcMethodNotFound, Message: "method not found: " + msg.Method}})
+			}
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// An exit code the server asks for fails this request, not the whole server.
+			var result any
+			err := collectExit(ctx, func(ctx context.Context) error {
+				var err error
+				result, err = h(ctx, msg.Params)
+				return err
+			})
+			if isNotification {
+				return
+			}
+			resp := &rpcMessage{ID: msg.ID, Result: result}
+			if err != nil {
+				rerr, ok := err.(*rpcError)
+				if !ok {
+					rerr = &rpcError{Code: rpcInternalError, Message: err.Error()}
+				}
+				resp.Result = nil
+				resp.Error = rerr
+			} else if result == nil {
+				resp.Result = json.RawMessage("null")
+			}
+			c.write(resp)
+		}()
+	}
+}
+
+// decodeParams decodes params into v, reporting failures as JSON-RPC invalid params errors.
+func decodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 {
+		return nil
+	}
+	err := json.Unmarshal(params, v)
+	if err != nil {
+		return &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
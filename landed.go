@@ -0,0 +1,61 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// findLandedCommits finds the topic commits of a rebase whose changes already landed on main, judged by patch ID,
+// and asks whether to drop them. Replaying such commits is the most common source of pointless conflicts.
+// Like git rebase, it drops them when the user cannot be asked.
+func findLandedCommits(ctx context.Context, cfg *Config, info *deconflictRequestInfo) error {
+	if info.verb != "rebase" {
+		return nil
+	}
+	commits, err := cfg.Git.CommitsInRange(ctx, info.baseSHA+".."+info.topicSHA)
+	if err != nil {
+		return err
+	}
+	onMain, err := cfg.Git.CherryMarks(ctx, info.mainSHA, info.topicSHA)
+	if err != nil {
+		return err
+	}
+	for _, c := range commits {
+		if onMain[c] {
+			info.landed = append(info.landed, c)
+		}
+	}
+	if len(info.landed) == 0 {
+		return nil
+	}
+	var lines []string
+	for _, c := range info.landed {
+		subject, err := cfg.Git.CommitMessage(ctx, c)
+		if err != nil {
+			return err
+		}
+		subject, _, _ = strings.Cut(subject, "\n")
+		lines = append(lines, c[:12]+" "+subject)
+	}
+	ui.Status("%d topic commits are already in %s:\n%s", len(info.landed), info.mainRef, indent(lines))
+	info.dropLanded = true
+	if detached != nil && detached.DropLanded != nil {
+		info.dropLanded = *detached.DropLanded
+	} else {
+		yes, err := confirm(cfg, askDropLanded, "drop them from the rebase?")
+		if errors.Is(err, errUnattended) {
+			// Without anyone to ask, drop them.
+			yes, err = true, nil
+		}
+		if err != nil {
+			return err
+		}
+		info.dropLanded = yes
+	}
+	return nil
+}
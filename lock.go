@@ -0,0 +1,156 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// A lockInfo identifies the merde process holding a repository's lock.
+type lockInfo struct {
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+}
+
+// stale reports whether the process that took the lock is known to be gone.
+// Only processes on this host can be checked.
+func (l *lockInfo) stale() bool {
+	host, _ := os.Hostname()
+	return l.Host == host && !processAlive(l.PID)
+}
+
+// lockRepo takes the per-repository lock that keeps concurrent merde operations
+// from interleaving ref updates and object unpacking.
+// If another process holds the lock, it waits up to --lock-wait for it.
+// The returned function releases the lock.
+func lockRepo(ctx context.Context, cfg *Config) (func(), error) {
+	commonDir, err := cfg.Git.CommonDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(commonDir, "merde", "lock")
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return nil, err
+	}
+	host, _ := os.Hostname()
+	data, err := json.Marshal(&lockInfo{
+		PID:     os.Getpid(),
+		Host:    host,
+		Time:    time.Now(),
+		Command: strings.Join(os.Args, " "),
+	})
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(flagLockWait)
+	for {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			_, err = f.Write(data)
+			f.Close()
+			if err != nil {
+				os.Remove(path)
+				return nil, err
+			}
+			// If the lock was broken and taken by another process meanwhile, it is theirs to release.
+			return func() { breakLock(path, data) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		held, seen := readLock(path)
+		switch {
+		case flagForceUnlock:
+			flagForceUnlock = false // only break the lock we were asked to break
+			broken, err := breakLock(path, seen)
+			if err != nil {
+				return nil, err
+			}
+			if broken {
+				ui.Status("removed lock held by %s", held)
+			}
+			continue
+		case held != nil && held.stale():
+			broken, err := breakLock(path, seen)
+			if err != nil {
+				return nil, err
+			}
+			if broken {
+				ui.Status("removed stale lock left by %s", held)
+			}
+			continue
+		case time.Now().After(deadline):
+			return nil, fmt.Errorf("another merde operation is running in this repository (%s); wait for it, retry with --lock-wait, or remove the lock with --force-unlock", held)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+// readLock returns the holder of the lock at path, or nil if it cannot be read,
+// along with the lock's contents, for breakLock.
+func readLock(path string) (*lockInfo, []byte) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil
+	}
+	return lockHolder(data), data
+}
+
+// breakLock removes the lock at path if it still holds seen, and reports whether the lock is gone.
+// Another process may take the lock between reading it and removing it,
+// so breakLock first renames the lock aside, which is atomic, and checks what it moved:
+// a lock other than the one seen goes back, unless yet another process has taken its place.
+func breakLock(path string, seen []byte) (bool, error) {
+	aside := fmt.Sprintf("%s.%d.broken", path, os.Getpid())
+	err := os.Rename(path, aside)
+	if errors.Is(err, os.ErrNotExist) {
+		return true, nil // released meanwhile
+	}
+	if err != nil {
+		return false, err
+	}
+	data, err := os.ReadFile(aside)
+	if err == nil && bytes.Equal(data, seen) {
+		return true, os.Remove(aside)
+	}
+	// Unlike renaming, linking never replaces a lock taken since.
+	err = os.Link(aside, path)
+	os.Remove(aside)
+	if err != nil {
+		return false, fmt.Errorf("putting back the lock taken by %s: %w", lockHolder(data), err)
+	}
+	return false, nil
+}
+
+// lockHolder describes the holder of a lock with the given contents.
+func lockHolder(data []byte) *lockInfo {
+	l := new(lockInfo)
+	if json.Unmarshal(data, l) != nil {
+		return nil
+	}
+	return l
+}
+
+func (l *lockInfo) String() string {
+	if l == nil {
+		return "unknown process"
+	}
+	return fmt.Sprintf("pid %d on %s since %s: %s", l.PID, l.Host, l.Time.Format(time.DateTime), l.Command)
+}
@@ -0,0 +1,82 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLockRepo(t *testing.T) {
+	cfg, dir, _ := testRepo(t)
+	ctx := context.Background()
+	defer func(force bool) { flagForceUnlock = force }(flagForceUnlock)
+	path := filepath.Join(dir, ".git", "merde", "lock")
+	write := func(contents string) {
+		t.Helper()
+		err := os.WriteFile(path, []byte(contents), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func() string {
+		data, _ := os.ReadFile(path)
+		return string(data)
+	}
+
+	unlock, err := lockRepo(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lockRepo(ctx, cfg); err == nil || !strings.Contains(err.Error(), "another merde operation") {
+		t.Errorf("second lockRepo: err = %v; want the lock to be held", err)
+	}
+	// Our lock was broken and another process took it: releasing ours must leave theirs.
+	write(`{"pid":1,"host":"elsewhere"}`)
+	unlock()
+	if got := read(); got != `{"pid":1,"host":"elsewhere"}` {
+		t.Errorf("releasing a lock someone else took left %q", got)
+	}
+
+	// A lock taken after the one seen goes back.
+	seen := read()
+	write(`{"pid":2,"host":"elsewhere"}`)
+	broken, err := breakLock(path, []byte(seen))
+	if err != nil || broken {
+		t.Errorf("breakLock of a replaced lock = %v, %v; want false, nil", broken, err)
+	}
+	if got := read(); got != `{"pid":2,"host":"elsewhere"}` {
+		t.Errorf("breakLock of a replaced lock left %q", got)
+	}
+	broken, err = breakLock(path, []byte(read()))
+	if err != nil || !broken {
+		t.Errorf("breakLock of the lock seen = %v, %v; want true, nil", broken, err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 0 {
+		t.Errorf("breakLock left %v behind", entries)
+	}
+
+	// --force-unlock breaks a live lock once.
+	write(`{"pid":3,"host":"elsewhere"}`)
+	flagForceUnlock = true
+	unlock, err = lockRepo(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flagForceUnlock {
+		t.Errorf("flagForceUnlock still set after breaking the lock")
+	}
+	if got := lockHolder([]byte(read())); got == nil || got.PID != os.Getpid() {
+		t.Errorf("lock after --force-unlock = %v; want this process's", got)
+	}
+	unlock()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("lock still there after release: %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Operation stages, reported as progress.
+const (
+	stageIdle      = "idle"
+	stageAnalyzing = "analyzing"
+	stageUploading = "uploading"
+	stageApplying  = "applying"
+)
+
+var (
+	stageMu   sync.Mutex
+	stage     = stageIdle
+	stageHook func(stage string) // if non-nil, called on every stage change
+)
+
+// setStage records the current stage of the running operation.
+func setStage(s string) {
+	stageMu.Lock()
+	stage = s
+	hook := stageHook
+	stageMu.Unlock()
+	if hook != nil {
+		hook(s)
+	}
+}
+
+func currentStage() string {
+	stageMu.Lock()
+	defer stageMu.Unlock()
+	return stage
+}
+
+// deconflictParams are the parameters of editor requests that operate on a pair of branches.
+type deconflictParams struct {
+	Verb  string `json:"verb"`  // merge or rebase; default rebase
+	Main  string `json:"main"`  // default: the topic's upstream
+	Topic string `json:"topic"` // default: the current branch
+	Path  string `json:"path"`  // for resolveFile
+}
+
+// refs returns the main and topic refs for p, applying defaults.
+func (p *deconflictParams) refs(ctx context.Context, cfg *Config) (string, string, error) {
+	switch p.Verb {
+	case "":
+		p.Verb = "rebase"
+	case "merge", "rebase":
+	default:
+		return "", "", &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("unknown verb %q", p.Verb)}
+	}
+	var args []string
+	if p.Main != "" {
+		args = append(args, p.Main)
+		if p.Topic != "" {
+			args = append(args, p.Topic)
+		}
+	}
+	return mainTopic(ctx, cfg, p.Verb, args)
+}
+
+// lspHandlers returns the JSON-RPC methods exposed to editors.
+func lspHandlers() map[string]rpcHandler {
+	// Only one operation may run at a time: they share the repository and the progress stage.
+	var opMu sync.Mutex
+	return map[string]rpcHandler{
+		"initialize": func(ctx context.Context, params json.RawMessage) (any, error) {
+			return map[string]any{
+				"capabilities": map[string]any{},
+				"serverInfo":   map[string]string{"name": "merde", "version": version},
+			}, nil
+		},
+		"initialized": func(ctx context.Context, params json.RawMessage) (any, error) {
+			return nil, nil
+		},
+		"shutdown": func(ctx context.Context, params json.RawMessage) (any, error) {
+			return nil, nil
+		},
+		"merde/progress": func(ctx context.Context, params json.RawMessage) (any, error) {
+			return map[string]string{"stage": currentStage()}, nil
+		},
+		"merde/analyze": func(ctx context.Context, params json.RawMessage) (any, error) {
+			opMu.Lock()
+			defer opMu.Unlock()
+			defer setStage(stageIdle)
+			cfg, p, mainRef, topicRef, err := lspSetup(ctx, params)
+			if err != nil {
+				return nil, err
+			}
+			info, err := makeDeconflictRequestInfo(ctx, cfg, p.Verb, mainRef, topicRef)
+			if err != nil {
+				return nil, err
+			}
+			return makeReport(info), nil
+		},
+		"merde/resolveBranch": func(ctx context.Context, params json.RawMessage) (any, error) {
+			opMu.Lock()
+			defer opMu.Unlock()
+			defer setStage(stageIdle)
+			cfg, p, mainRef, topicRef, err := lspSetup(ctx, params)
+			if err != nil {
+				return nil, err
+			}
+			return deconflict(ctx, cfg, p.Verb, mainRef, topicRef)
+		},
+		"merde/resolveFile": func(ctx context.Context, params json.RawMessage) (any, error) {
+			opMu.Lock()
+			defer opMu.Unlock()
+			defer setStage(stageIdle)
+			cfg, p, mainRef, topicRef, err := lspSetup(ctx, params)
+			if err != nil {
+				return nil, err
+			}
+			if p.Path == "" {
+				return nil, &rpcError{Code: rpcInvalidParams, Message: "path is required"}
+			}
+			op, err := deconflict(ctx, cfg, p.Verb, mainRef, topicRef)
+			if err != nil {
+				return nil, err
+			}
+			for _, res := range op.Report.Resolutions {
+				if res.Path == p.Path {
+					return map[string]any{"operation": op.ID, "result": op.result(), "resolution": res}, nil
+				}
+			}
+			return nil, fmt.Errorf("%s was not in conflict", p.Path)
+		},
+	}
+}
+
+// lspSetup loads the config and decodes deconflict params for an editor request.
+func lspSetup(ctx context.Context, params json.RawMessage) (*Config, *deconflictParams, string, string, error) {
+	p := new(deconflictParams)
+	err := decodeParams(params, p)
+	if err != nil {
+		return nil, nil, "", "", err
+	}
+	cfg, err := LoadDefault(ctx)
+	if err != nil {
+		return nil, nil, "", "", err
+	}
+	err = requireCleanGitStatus(ctx, cfg)
+	if err != nil {
+		return nil, nil, "", "", err
+	}
+	mainRef, topicRef, err := p.refs(ctx, cfg)
+	if err != nil {
+		return nil, nil, "", "", err
+	}
+	return cfg, p, mainRef, topicRef, nil
+}
+
+func doLSP(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		return usageErrorf("merde lsp takes no arguments")
+	}
+	// stdout belongs to the protocol.
+	err := takeOverStdout()
+	if err != nil {
+		return err
+	}
+	conn := newRPCConn(os.Stdin, os.Stdout, false)
+	stageHook = func(s string) {
+		conn.notify("merde/progress", map[string]string{"stage": s})
+	}
+	return conn.serve(ctx, lspHandlers(), "exit")
+}
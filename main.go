@@ -5,13 +5,21 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/dustin/go-humanize"
+	"merde.ai/git"
 )
 
 // Overwritten by -ldflags by goreleaser for release builds.
@@ -19,12 +27,63 @@ var (
 	version = "dev"
 	commit  = "-"
 	date    = "-"
+	builtBy = "-"
 )
 
+// ansi reports whether stdout understands escape sequences, for progress output.
+var ansi bool
+
 func main() {
-	err := rootCommand.ParseAndRun(context.Background(), os.Args[1:])
+	ansi = enableANSI()
+	start := time.Now()
+	err := rootCommand.Parse(os.Args[1:])
+	// ffcli has already printed usage for bad flags.
+	usage := err != nil
+	if err == nil && flagChdir != "" {
+		// Like git -C: everything, including git's own discovery of the repository, starts there.
+		err = os.Chdir(flagChdir)
+	}
+	if err == nil {
+		err = configureOutput()
+	}
+	if err == nil {
+		err = configureLanguage()
+	}
+	if err == nil {
+		err = configureHTTP()
+	}
+	ctx, status := withExitStatus(context.Background())
+	if err == nil {
+		startTelemetry()
+		err = rootCommand.Run(ctx)
+	}
+	if flagGitTimings {
+		printGitTimings()
+	}
+	var ue *usageError
+	if errors.As(err, &ue) {
+		usage = true
+	} else if errors.Is(err, flag.ErrHelp) {
+		// -h: ffcli has printed the requested usage.
+		return
+	}
+	code := 0
+	var ee *ExitError
+	if errors.As(err, &ee) {
+		// The server has already explained itself.
+		code = ee.Code
+	} else if err != nil {
+		ui.Error(err)
+	}
+	recordTelemetry(rootFlagSet.Args(), start, err)
+	// An exit code the server asked for wins, even over an error that followed it.
+	if code = max(code, status.code()); code > 0 {
+		os.Exit(code)
+	}
+	if usage {
+		os.Exit(2)
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
 }
@@ -38,7 +97,7 @@ func doRoot(ctx context.Context, args []string) error {
 	if err != nil {
 		return err
 	}
-	parts := doRequest(req)
+	parts := doRequest(cfg, req)
 	for part, err := range parts {
 		if err != nil {
 			return err
@@ -52,7 +111,7 @@ func doRoot(ctx context.Context, args []string) error {
 }
 
 func doConfig(ctx context.Context, args []string) error {
-	cfg, err := LoadDefault(ctx)
+	cfg, err := loadConfigValues()
 	if err != nil {
 		return err
 	}
@@ -69,7 +128,101 @@ func doConfig(ctx context.Context, args []string) error {
 			return err
 		}
 	default:
-		return fmt.Errorf("usage: merde config [key] [value]")
+		return usageErrorf("merde config takes at most 2 arguments")
+	}
+	return nil
+}
+
+// A configEntry describes one config key, for merde config get and list.
+type configEntry struct {
+	Key     string `json:"key"`
+	Value   string `json:"value"`             // effective value
+	Source  string `json:"source,omitempty"`  // env, config, or default; empty if unset
+	Default string `json:"default,omitempty"` // value when neither set nor overridden
+	Env     string `json:"env"`               // environment variable that overrides it
+	Help    string `json:"help,omitempty"`
+}
+
+// loadConfigEntry describes key in cfg.
+func loadConfigEntry(cfg *Config, key string) *configEntry {
+	return &configEntry{
+		Key:     key,
+		Value:   cfg.Get(key),
+		Source:  cfg.Source(key),
+		Default: defaultValues[key],
+		Env:     configEnv(key),
+		Help:    keyHelp[key],
+	}
+}
+
+// loadConfigValues loads the user's config, without requiring a git repository.
+func loadConfigValues() (*Config, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return loadValues(path)
+}
+
+// trailingJSON strips a --json that follows the arguments, as in "merde config get key --json",
+// and sets flagConfigJSON for it. The flag package stops parsing flags at the first argument.
+func trailingJSON(args []string) []string {
+	if n := len(args); n > 0 && (args[n-1] == "--json" || args[n-1] == "-json") {
+		flagConfigJSON = true
+		return args[:n-1]
+	}
+	return args
+}
+
+func doConfigGet(ctx context.Context, args []string) error {
+	args = trailingJSON(args)
+	if len(args) != 1 {
+		return usageErrorf("merde config get takes exactly one key")
+	}
+	cfg, err := loadConfigValues()
+	if err != nil {
+		return err
+	}
+	e := loadConfigEntry(cfg, args[0])
+	if flagConfigJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(e)
+	}
+	fmt.Println(e.Value)
+	return nil
+}
+
+func doConfigList(ctx context.Context, args []string) error {
+	args = trailingJSON(args)
+	if len(args) > 0 {
+		return usageErrorf("merde config list takes no arguments")
+	}
+	cfg, err := loadConfigValues()
+	if err != nil {
+		return err
+	}
+	// Known keys, plus any others stored in the config file.
+	keys := configKeys()
+	for k := range cfg.Values {
+		if !slices.Contains(keys, k) {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	var entries []*configEntry
+	for _, k := range keys {
+		entries = append(entries, loadConfigEntry(cfg, k))
+	}
+	if flagConfigJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+	for _, e := range entries {
+		if e.Source != "" {
+			fmt.Printf("%s: %s (%s)\n", e.Key, e.Value, e.Source)
+		}
 	}
 	return nil
 }
@@ -81,12 +234,16 @@ func doVersion(ctx context.Context, args []string) error {
 	}
 	fmt.Printf("merde version %s (%s, %s)\n", version, commit, date)
 	fmt.Println(cfg.GitVersion)
-	return nil
+	if !flagVersionCheck {
+		return nil
+	}
+	printProvenance()
+	return checkVersion(ctx, cfg)
 }
 
 func doAuth(ctx context.Context, args []string) error {
 	if len(args) > 1 {
-		return fmt.Errorf("usage: merde auth [token]")
+		return usageErrorf("merde auth takes at most 1 argument")
 	}
 
 	cfg, err := LoadDefault(ctx)
@@ -100,7 +257,25 @@ func doAuth(ctx context.Context, args []string) error {
 		if err != nil {
 			return err
 		}
-		fmt.Printf("token stored\n")
+		ui.Status("token stored")
+	}
+	if flagAuthRepo {
+		remotes, err := forgeRemotes(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		if len(remotes) == 0 {
+			return fmt.Errorf("no remote on GitHub, Bitbucket, sourcehut, or GitLab found to limit the token to")
+		}
+		tok, err := deriveToken(ctx, cfg, remotes)
+		if err != nil {
+			return err
+		}
+		err = cfg.Update(tokenKey, tok)
+		if err != nil {
+			return err
+		}
+		ui.Status("stored a token limited to %s", strings.Join(remotes, ", "))
 	}
 
 	req, err := checkAuthRequest(ctx, cfg)
@@ -108,7 +283,7 @@ func doAuth(ctx context.Context, args []string) error {
 		return err
 	}
 
-	parts := doRequest(req)
+	parts := doRequest(cfg, req)
 	for part, err := range parts {
 		if err != nil {
 			return err
@@ -117,28 +292,35 @@ func doAuth(ctx context.Context, args []string) error {
 		if err != nil {
 			return err
 		}
+		if part.ExitCode > 0 {
+			return nil
+		}
 	}
-	return nil
-}
-
-func doHelp(ctx context.Context, args []string) error {
-	cfg, err := LoadDefault(ctx)
+	info, err := fetchTokenInfo(ctx, cfg)
 	if err != nil {
 		return err
 	}
-	req, err := helpRequest(ctx, cfg, args)
+	info.print()
+	return nil
+}
+
+// checkAuth checks with the server that the configured token is valid.
+func checkAuth(ctx context.Context, cfg *Config) error {
+	req, err := checkAuthRequest(ctx, cfg)
 	if err != nil {
 		return err
 	}
-	parts := doRequest(req)
-	for part, err := range parts {
+	for part, err := range doRequest(cfg, req) {
 		if err != nil {
-			return err
+			return fmt.Errorf("checking authentication: %w", err)
 		}
-		_, err := part.Process(ctx, cfg) // ignore binary data
+		_, err := part.Process(ctx, cfg)
 		if err != nil {
 			return err
 		}
+		if part.ExitCode > 0 {
+			return fmt.Errorf("authentication failed; run: merde auth <token>")
+		}
 	}
 	return nil
 }
@@ -148,13 +330,15 @@ func doMerge(ctx context.Context, args []string) error {
 	if err != nil {
 		return err
 	}
-	// TODO: check auth before doing anything else?
-	// TODO: do that concurrently with building the merge pack?
-	// TODO: detect when the merge will succeed without our help and tell the user.
-	for _, arg := range args {
-		if strings.HasPrefix(arg, "-") {
-			return fmt.Errorf("merde merge does not support flags yet")
+	err = checkDetach(cfg)
+	if err != nil {
+		return err
+	}
+	if flagMergeContinue {
+		if len(args) > 0 {
+			return usageErrorf("merde merge --continue takes no arguments")
 		}
+		return continueMerge(ctx, cfg)
 	}
 	err = requireCleanGitStatus(ctx, cfg)
 	if err != nil {
@@ -164,13 +348,34 @@ func doMerge(ctx context.Context, args []string) error {
 	if err != nil {
 		return err
 	}
-	fmt.Printf("plan: merge %s into %s\n", mainRef, topicRef)
-	info, err := makeDeconflictRequestInfo(ctx, cfg, mainRef, topicRef)
+	if flagNoCommit && flagAdopt {
+		return usageErrorf("--no-commit and --adopt cannot be combined")
+	}
+	if flagNoCommit {
+		err = requireCheckedOut(ctx, cfg, topicRef)
+		if err != nil {
+			return err
+		}
+	}
+	if flagDryRun {
+		return dryRun(ctx, cfg, "merge", mainRef, topicRef)
+	}
+	done, err := skipIfConflictFree(ctx, cfg, "merge", mainRef, topicRef)
+	if err != nil || done {
+		return err
+	}
+	ui.Status("plan: merge %s into %s", mainRef, topicRef)
+	op, err := deconflict(ctx, cfg, "merge", mainRef, topicRef)
+	if errors.Is(err, errDetached) {
+		return nil
+	}
 	if err != nil {
 		return err
 	}
-	info.verb = "merge"
-	return processDeconflictRequest(ctx, cfg, info)
+	if flagNoCommit {
+		return stageMerge(ctx, cfg, op)
+	}
+	return autoAdopt(ctx, cfg, op)
 }
 
 func doRebase(ctx context.Context, args []string) error {
@@ -178,13 +383,15 @@ func doRebase(ctx context.Context, args []string) error {
 	if err != nil {
 		return err
 	}
-	// TODO: check auth before doing anything else?
-	// TODO: do that concurrently with building the merge pack?
-	// TODO: detect when the rebase will succeed without our help and tell the user.
-	for _, arg := range args {
-		if strings.HasPrefix(arg, "-") {
-			return fmt.Errorf("merde rebase does not support flags yet")
+	err = checkDetach(cfg)
+	if err != nil {
+		return err
+	}
+	if flagRebaseContinue {
+		if len(args) > 0 {
+			return usageErrorf("merde rebase --continue takes no arguments")
 		}
+		return continueRebase(ctx, cfg)
 	}
 	err = requireCleanGitStatus(ctx, cfg)
 	if err != nil {
@@ -194,13 +401,177 @@ func doRebase(ctx context.Context, args []string) error {
 	if err != nil {
 		return err
 	}
-	fmt.Printf("plan: rebase %s onto %s\n", topicRef, mainRef)
-	info, err := makeDeconflictRequestInfo(ctx, cfg, mainRef, topicRef)
+	if flagDryRun {
+		return dryRun(ctx, cfg, "rebase", mainRef, topicRef)
+	}
+	done, err := skipIfConflictFree(ctx, cfg, "rebase", mainRef, topicRef)
+	if err != nil || done {
+		return err
+	}
+	ui.Status("plan: rebase %s onto %s", topicRef, mainRef)
+	op, err := deconflict(ctx, cfg, "rebase", mainRef, topicRef)
+	if errors.Is(err, errDetached) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return autoAdopt(ctx, cfg, op)
+}
+
+func doAdopt(ctx context.Context, args []string) error {
+	if len(args) > 1 {
+		return usageErrorf("merde adopt takes at most 1 argument")
+	}
+	cfg, err := LoadDefault(ctx)
+	if err != nil {
+		return err
+	}
+	var id string
+	if len(args) == 1 {
+		id = args[0]
+	}
+	op, err := loadOperation(ctx, cfg, id)
+	if err != nil {
+		return err
+	}
+	return adoptOperation(ctx, cfg, op)
+}
+
+func doUndo(ctx context.Context, args []string) error {
+	if len(args) > 1 {
+		return usageErrorf("merde undo takes at most 1 argument")
+	}
+	cfg, err := LoadDefault(ctx)
+	if err != nil {
+		return err
+	}
+	var op *operation
+	if len(args) == 1 {
+		op, err = loadOperation(ctx, cfg, args[0])
+	} else {
+		op, err = lastUndoable(ctx, cfg)
+	}
+	if err != nil {
+		return err
+	}
+	return undo(ctx, cfg, op)
+}
+
+func doReview(ctx context.Context, args []string) error {
+	if len(args) > 1 {
+		return usageErrorf("merde review takes at most 1 argument")
+	}
+	cfg, err := LoadDefault(ctx)
+	if err != nil {
+		return err
+	}
+	var id string
+	if len(args) == 1 {
+		id = args[0]
+	}
+	op, err := loadOperation(ctx, cfg, id)
 	if err != nil {
 		return err
 	}
-	info.verb = "rebase"
-	return processDeconflictRequest(ctx, cfg, info)
+	r := op.Report
+	ui.Status("operation %s (%s)", op.ID, op.Time.Format(time.DateTime))
+	ui.Status("%s %s (%s) and %s (%s)", r.Verb, r.MainRef, r.MainSHA, r.TopicRef, r.TopicSHA)
+	for _, res := range r.Resolutions {
+		ui.Status("  %s: resolved by %s: %s", res.Path, res.By, res.Explanation)
+	}
+	r.printUnexpected(ui)
+	result := op.result()
+	if result == "" {
+		ui.Status("the operation produced no result")
+		return nil
+	}
+	if r.Verb == "rebase" {
+		// Compare the original topic commits with their rebased versions.
+		return cfg.Git.ShowTo(ctx, "range-diff", r.BaseSHA+".."+r.TopicSHA, r.MainSHA+".."+result)
+	}
+	// Show how the resolution differs from what git's own merge would have produced.
+	return cfg.Git.ShowTo(ctx, "show", "--remerge-diff", result)
+}
+
+// deconflict analyzes mainRef and topicRef, has the server combine them using verb,
+// and records and reports on the result.
+func deconflict(ctx context.Context, cfg *Config, verb, mainRef, topicRef string) (op *operation, err error) {
+	err = startJob(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { finishJob(ctx, cfg, op, err) }()
+	err = checkRepoAccess(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := lockRepo(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	releaseGC, err := holdGC(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer releaseGC()
+	err = saveRetry(ctx, cfg, verb, mainRef, topicRef)
+	if err != nil {
+		return nil, err
+	}
+	info, err := makeDeconflictRequestInfo(ctx, cfg, verb, mainRef, topicRef)
+	if err != nil {
+		return nil, err
+	}
+	err = confirmCost(ctx, cfg, info)
+	if err != nil {
+		return nil, err
+	}
+	if flagDetach {
+		err = startDetachedJob(ctx, cfg, info)
+		if err != nil {
+			return nil, err
+		}
+		return nil, errDetached
+	}
+	err = processDeconflictRequest(ctx, cfg, info)
+	if err != nil {
+		// Keep the pack for merde retry; without it, the retry builds the pack again.
+		cached := cachePack(ctx, cfg, info) == nil
+		var unreachable *unreachableError
+		if isNetworkError(err) && !errors.As(err, &unreachable) {
+			return nil, &unreachableError{err: err, cached: cached}
+		}
+		return nil, err
+	}
+	tidyObjects(ctx, cfg)
+	err = approveSplit(ctx, cfg, info)
+	if err != nil {
+		return nil, err
+	}
+	err = clearRetry(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	op, err = saveOperation(ctx, cfg, info)
+	if err != nil {
+		return nil, err
+	}
+	ui.Operation(op)
+	err = writeReport(info, flagReport)
+	if err != nil {
+		return nil, err
+	}
+	err = commentOnPullRequest(ctx, cfg, info, flagPR)
+	if err != nil {
+		return nil, err
+	}
+	err = tagResult(ctx, cfg, op, flagTag, flagSign)
+	if err != nil {
+		return nil, err
+	}
+	return op, nil
 }
 
 // requireCleanGitStatus checks that the git status is sufficiently clean for a deconflict operation.
@@ -216,13 +587,13 @@ func requireCleanGitStatus(ctx context.Context, cfg *Config) error {
 	for _, dir := range rebaseDirs {
 		_, err := os.Stat(filepath.Join(gitDir, dir))
 		if err == nil {
-			return fmt.Errorf("cannot proceed: rebase in progress")
+			return fmt.Errorf("cannot proceed: rebase in progress; resolve its conflicts with: merde rebase --continue")
 		}
 	}
 
 	filesReason := map[string]string{
-		"MERGE_HEAD":       "merge is in progress",
-		"CHERRY_PICK_HEAD": "cherry-pick is in progress",
+		"MERGE_HEAD":       "merge is in progress; resolve it with: merde merge --continue",
+		"CHERRY_PICK_HEAD": "cherry-pick is in progress; resolve it with: merde continue",
 		"REVERT_HEAD":      "revert is in progress",
 		"BISECT_LOG":       "bisect is in progress",
 	}
@@ -248,12 +619,12 @@ func mainTopic(ctx context.Context, cfg *Config, verb string, args []string) (st
 		// but it would have different semantics from "git merge X Y",
 		// which does an octopus merge, so for now, tread lightly.
 		if verb == "merge" {
-			return "", "", fmt.Errorf("merde merge takes at most 1 argument")
+			return "", "", usageErrorf("merde merge takes at most 1 argument")
 		}
 		mainRef = args[0]
 		topicRef = args[1]
 	default:
-		return "", "", fmt.Errorf("too many arguments to merde %v", verb)
+		return "", "", usageErrorf("too many arguments to merde %v", verb)
 	}
 	if topicRef == "" {
 		abbrev, err := cfg.Git.AbbrevRef(ctx, "HEAD")
@@ -267,29 +638,130 @@ func mainTopic(ctx context.Context, cfg *Config, verb string, args []string) (st
 		if err != nil {
 			return "", "", err
 		}
-		if !hasUpstream {
-			return "", "", fmt.Errorf("no upstream set for %s, please explicitly specify a main branch: merde %s <main>", topicRef, verb)
+		if hasUpstream {
+			mainRef, err = cfg.Git.AbbrevRef(ctx, topicRef+"@{upstream}")
+			if err != nil {
+				return "", "", err
+			}
 		}
-		upstream, err := cfg.Git.AbbrevRef(ctx, topicRef+"@{upstream}")
-		if err != nil {
-			return "", "", err
+		// In a fork, the topic's upstream is often on the fork's remote, not the one to merge with.
+		if remote := preferredRemote(cfg); remote != "" && !strings.HasPrefix(mainRef, remote+"/") {
+			mainRef, err = cfg.Git.AbbrevRef(ctx, remote+"/HEAD")
+			if err != nil {
+				return "", "", fmt.Errorf("cannot find the default branch of remote %s; run: git remote set-head %s --auto", remote, remote)
+			}
+		}
+		if mainRef == "" {
+			return "", "", fmt.Errorf("no upstream set for %s, please explicitly specify a main branch: merde %s <main>", topicRef, verb)
 		}
-		mainRef = upstream
 	}
 	return mainRef, topicRef, nil
 }
 
+// preferredRemote returns the remote chosen with --remote or the preferred_remote config, if any.
+func preferredRemote(cfg *Config) string {
+	return cmp.Or(flagRemote, cfg.Get(preferredRemoteKey))
+}
+
 type deconflictRequestInfo struct {
-	verb     string   // "merge" or "rebase"
-	args     []string // args associated with verb, placeholder for now
-	mainRef  string   // e.g. "main" or "origin/main"
-	topicRef string   // e.g. "topic" or "main"
-	mainSHA  string   // commit hash of mainRef
-	topicSHA string   // commit hash of topicRef
-	pack     string   // pack file of objects needed to analyze and combine the two branches
+	verb      string              // "merge" or "rebase"
+	args      []string            // args associated with verb, placeholder for now
+	mainRef   string              // e.g. "main" or "origin/main"
+	topicRef  string              // e.g. "topic" or "main"
+	mainSHA   string              // commit hash of mainRef
+	topicSHA  string              // commit hash of topicRef
+	baseSHA   string              // commit hash of the merge base of mainSHA and topicSHA
+	pack      string              // pack file of objects needed to analyze and combine the two branches
+	packModes map[string][]string // paths whose mode varies across the branches -> distinct modes seen, from building the pack
+	packPlan  *git.PackPlan       // if non-nil, the objects to stream as the pack while uploading, in place of pack
+	packSent  atomic.Int64        // bytes of pack sent, once streamed
+	uploaded  atomic.Int64        // bytes of the request body read so far, for progress
+
+	deleteModify  []*deleteModify      // paths deleted on one side and modified on the other, with decided policies
+	modes         []*modeChange        // paths whose mode varies across the branches
+	resolved      []*localResolution   // paths resolved locally, without the server
+	eols          []*eolHint           // line-ending conventions of conflicted text paths
+	generated     []*generatedPath     // generated paths modified on both sides
+	duplicates    []*duplicateConflict // paths whose conflicts repeat those of other paths
+	repeatedHunks []*repeatedHunk      // conflict hunks that recur across the commits a rebase replays
+	owners        map[string][]string  // CODEOWNERS owners of conflicting paths
+	landed        []string             // topic commits of a rebase whose changes are already in main
+	dropLanded    bool                 // whether to drop the landed commits from the rebase
+	plan          []*planStep          // the user's rebase plan, from merde rebase -i
+	split         string               // topic commit the server should split into smaller commits
+	haves         []string             // objects the client has, which the server may omit or delta against
+	history       int                  // most recent commits on each side to send in full; 0 means all
+
+	// Filled in while processing the server's response
+	serverResolutions []Resolution // how the server resolved conflicts
+	serverOperationID string       // the server's ID for the operation, if it sent one
+	outOfScope        []string     // paths the server's result changes outside the conflicts
+	ephemeral         bool         // the upload asked the server not to keep the objects
+	objectsDeleted    bool         // the server acknowledged deleting them
+	createdRefs       []createdRef // refs created or updated, in order
+
+	mainChanges  map[string]string // paths changed between baseSHA and mainSHA -> status
+	topicChanges map[string]string // paths changed between baseSHA and topicSHA -> status
 }
 
-func makeDeconflictRequestInfo(ctx context.Context, cfg *Config, mainRef, topicRef string) (*deconflictRequestInfo, error) {
+// packSize returns the size of the pack: as built, or as streamed so far.
+func (info *deconflictRequestInfo) packSize() int {
+	if info.packPlan != nil {
+		return int(info.packSent.Load())
+	}
+	return len(info.pack)
+}
+
+// refSHA returns the commit hash most recently assigned to ref during the operation.
+func (info *deconflictRequestInfo) refSHA(ref string) string {
+	for i := len(info.createdRefs) - 1; i >= 0; i-- {
+		if info.createdRefs[i].ref == ref {
+			return info.createdRefs[i].sha
+		}
+	}
+	return ""
+}
+
+// packOptions returns the pack options implied by the local analysis in info.
+func (info *deconflictRequestInfo) packOptions() *git.PackOptions {
+	opts := &git.PackOptions{
+		HistoryBudget: info.history,
+		Conflicts:     unresolved(info),
+	}
+	for _, lr := range info.resolved {
+		opts.Exclude = append(opts.Exclude, lr.path)
+		opts.Extra = append(opts.Extra, lr.sha)
+	}
+	return opts
+}
+
+func makeDeconflictRequestInfo(ctx context.Context, cfg *Config, verb, mainRef, topicRef string) (info *deconflictRequestInfo, err error) {
+	// Check that the server is reachable and the token valid while the local work happens,
+	// and stop the local work as soon as either turns out not to be.
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	preflight := make(chan error, 1)
+	go func() {
+		err := checkReachable(ctx, cfg)
+		if err == nil {
+			err = checkAuth(ctx, cfg)
+		}
+		if err != nil {
+			cancel(err)
+		}
+		preflight <- err
+	}()
+	defer func() {
+		if err == nil {
+			err = <-preflight
+		} else if cause := context.Cause(ctx); cause != nil {
+			err = cause
+		}
+		if err != nil {
+			info = nil
+		}
+	}()
+
 	mainSHA, err := cfg.Git.ResolveRef(ctx, mainRef)
 	if err != nil {
 		return nil, err
@@ -301,44 +773,219 @@ func makeDeconflictRequestInfo(ctx context.Context, cfg *Config, mainRef, topicR
 	if mainSHA == topicSHA {
 		return nil, fmt.Errorf("%v and %v are the same", mainRef, topicRef)
 	}
-	fmt.Printf("analyzing...\n")
-	// TODO: this can be slow, might need a spinner
-	pack, err := cfg.Git.MergePack(ctx, mainSHA, topicSHA)
+	baseSHA, err := cfg.Git.UniqueAncestorMergeBase(ctx, []string{mainSHA, topicSHA})
 	if err != nil {
 		return nil, err
 	}
-	info := &deconflictRequestInfo{
+	if baseSHA == "" {
+		return nil, fmt.Errorf("%v and %v have no common ancestor", mainRef, topicRef)
+	}
+	info = &deconflictRequestInfo{
+		verb:     verb,
 		mainRef:  mainRef,
 		topicRef: topicRef,
 		mainSHA:  mainSHA,
 		topicSHA: topicSHA,
-		pack:     pack,
+		baseSHA:  baseSHA,
+	}
+	info.history, err = historyBudget(cfg)
+	if err != nil {
+		return nil, err
+	}
+	info.mainChanges, err = cfg.Git.ChangedPaths(ctx, baseSHA, mainSHA)
+	if err != nil {
+		return nil, err
+	}
+	info.topicChanges, err = cfg.Git.ChangedPaths(ctx, baseSHA, topicSHA)
+	if err != nil {
+		return nil, err
+	}
+	err = findLandedCommits(ctx, cfg, info)
+	if err != nil {
+		return nil, err
+	}
+	if verb == "rebase" && flagInteractive {
+		err = editRebasePlan(ctx, cfg, info)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = resolveDeleteModify(ctx, cfg, info)
+	if err != nil {
+		return nil, err
+	}
+	err = resolveGenerated(ctx, cfg, info)
+	if err != nil {
+		return nil, err
+	}
+	err = resolveWithMergeDrivers(ctx, cfg, info)
+	if err != nil {
+		return nil, err
+	}
+	err = resolveEOLNoise(ctx, cfg, info)
+	if err != nil {
+		return nil, err
+	}
+	err = resolveWhitespaceOnly(ctx, cfg, info)
+	if err != nil {
+		return nil, err
+	}
+	err = routeByOwner(ctx, cfg, info)
+	if err != nil {
+		return nil, err
+	}
+	err = resolveFromMemory(ctx, cfg, info)
+	if err != nil {
+		return nil, err
+	}
+	err = findDuplicateConflicts(ctx, cfg, info)
+	if err != nil {
+		return nil, err
+	}
+	err = findHeavyCommit(ctx, cfg, info)
+	if err != nil {
+		return nil, err
+	}
+	if len(info.resolved) > 0 {
+		ui.Status("handled %d of %d conflicting paths locally", len(info.resolved), len(bothModified(info)))
+	}
+	setStage(stageAnalyzing)
+	ui.Status("analyzing...")
+	analyzing := startProgress("analyzing", 0, nil)
+	pack, err := buildPack(ctx, cfg, info)
+	if err == nil {
+		info.pack, info.packModes = pack.Data, pack.Modes
+		err = negotiateHaves(ctx, cfg, info)
+	}
+	analyzing.stop()
+	if err != nil {
+		return nil, err
+	}
+	err = analyzeModes(ctx, cfg, info, pack.Modes)
+	if err != nil {
+		return nil, err
 	}
 	return info, nil
 }
 
-func processDeconflictRequest(ctx context.Context, cfg *Config, info *deconflictRequestInfo) error {
+// rewordMerge recommits the server's merge commit sha with the message given by -m or --edit, if any,
+// and returns the commit to use in its place.
+func rewordMerge(ctx context.Context, cfg *Config, info *deconflictRequestInfo, sha string) (string, error) {
+	if info.verb != "merge" || (flagMessage == "" && !flagEdit) {
+		return sha, nil
+	}
+	message := flagMessage
+	if flagEdit {
+		initial := flagMessage
+		if initial == "" {
+			var err error
+			initial, err = cfg.Git.CommitMessage(ctx, sha)
+			if err != nil {
+				return "", err
+			}
+		}
+		var err error
+		message, err = editMessage(ctx, cfg, initial)
+		if err != nil {
+			return "", err
+		}
+	}
+	return cfg.Git.Recommit(ctx, sha, message)
+}
+
+func processDeconflictRequest(ctx context.Context, cfg *Config, info *deconflictRequestInfo) (err error) {
 	dr, err := deconflictRequest(ctx, cfg, info)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("uploading %v...\n", humanize.Bytes(uint64(len(info.pack))))
-	parts := doRequest(dr)
+	audit, err := beginAudit(ctx, cfg, info.verb)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		audit.recordDeconflict(info)
+		err = cmp.Or(err, audit.finish(ctx, cfg, err))
+	}()
+	setStage(stageUploading)
+	info.ephemeral = ephemeral(cfg)
+	var total int64 // unknown until the end when streaming
+	if info.packPlan != nil {
+		ui.Status("uploading %d objects as they are packed...", len(info.packPlan.Objects))
+	} else {
+		total = int64(len(info.pack))
+		ui.Status("uploading %v...", humanize.Bytes(uint64(total)))
+	}
+	uploading := startProgress("uploading", total, info.uploaded.Load)
+	defer uploading.stop()
+	parts := doRequest(cfg, dr)
 	for part, err := range parts {
+		// The server answers once it has the whole pack.
+		uploading.stop()
 		if err != nil {
 			return err
 		}
+		if part.Patch != nil {
+			part, err = applyPatchSet(ctx, cfg, part.Patch)
+			if err != nil {
+				return err
+			}
+		}
+		if part.IsJSON && part.Ref != "" && part.SHA != "" {
+			part.Ref = namespacedRef(cfg, part.Ref)
+			err = verifyTopology(ctx, cfg, info, part.SHA)
+			if err != nil {
+				return err
+			}
+			err = verifyResult(ctx, cfg, info, part.SHA)
+			if err != nil {
+				return err
+			}
+			part.SHA, err = rewordMerge(ctx, cfg, info, part.SHA)
+			if err != nil {
+				return err
+			}
+			err = verifyModes(ctx, cfg, info, part.SHA)
+			if err != nil {
+				return err
+			}
+			verifyEOL(ctx, cfg, info, part.SHA)
+		}
+		setStage(stageApplying)
 		done, err := part.Process(ctx, cfg)
 		if err != nil {
 			return err
 		}
+		info.serverResolutions = append(info.serverResolutions, part.Resolutions...)
+		info.serverOperationID = cmp.Or(part.OperationID, info.serverOperationID)
+		info.objectsDeleted = info.objectsDeleted || part.ObjectsDeleted
+		if audit != nil {
+			audit.OperationID = cmp.Or(part.OperationID, audit.OperationID)
+			if part.Data != nil {
+				audit.BytesReceived += int64(part.Data.Len())
+			}
+		}
+		if part.IsJSON && part.Ref != "" && part.SHA != "" {
+			info.createdRefs = append(info.createdRefs, createdRef{ref: part.Ref, sha: part.SHA})
+			err = applyDuplicateResolutions(ctx, cfg, info, part.Ref, part.SHA)
+			if err != nil {
+				return err
+			}
+			err = applyConfidenceThreshold(ctx, cfg, info, part.Ref, info.refSHA(part.Ref))
+			if err != nil {
+				return err
+			}
+			err = regenerate(ctx, cfg, info, part.Ref, info.refSHA(part.Ref))
+			if err != nil {
+				return err
+			}
+		}
 		if !done {
-			// binary data, unpack git objects
-			err = cfg.Git.UnpackObjects(ctx, part.Data)
+			// binary data, store git objects
+			err = storeObjects(ctx, cfg, part.Data)
 			if err != nil {
 				return err
 			}
 		}
 	}
-	return nil
+	return checkDeleted(cfg, info.objectsDeleted)
 }
@@ -0,0 +1,25 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"merde.ai/git"
+	"merde.ai/git/gittest"
+)
+
+// testRepo makes a repository with gittest.Repo and returns a config that uses it,
+// the repository's directory, and a function that runs git there.
+func testRepo(t *testing.T) (*Config, string, func(args ...string) string) {
+	t.Helper()
+	dir, run := gittest.Repo(t)
+	g, err := git.NewGit(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Config{Git: g, Values: make(map[string]string)}, dir, run
+}
@@ -0,0 +1,178 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// mcpProtocolVersion is the Model Context Protocol revision implemented by merde mcp.
+const mcpProtocolVersion = "2024-11-05"
+
+// An mcpTool is a tool exposed to agents over MCP.
+type mcpTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+
+	call func(ctx context.Context, args json.RawMessage) (any, error)
+}
+
+var (
+	branchesSchema = map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"verb":  map[string]any{"type": "string", "enum": []string{"merge", "rebase"}, "description": "how to combine the branches (default rebase)"},
+			"main":  map[string]any{"type": "string", "description": "main branch (default: topic's upstream)"},
+			"topic": map[string]any{"type": "string", "description": "topic branch (default: current branch)"},
+		},
+	}
+	operationSchema = map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"operation": map[string]any{"type": "string", "description": "operation id (default: most recent)"},
+		},
+	}
+)
+
+// mcpTools returns the tools exposed to agents.
+func mcpTools() []*mcpTool {
+	lsp := lspHandlers()
+	return []*mcpTool{
+		{
+			Name:        "analyze_conflicts",
+			Description: "Analyze the conflicts between two branches locally, without uploading anything.",
+			InputSchema: branchesSchema,
+			call:        lsp["merde/analyze"],
+		},
+		{
+			Name:        "resolve_conflicts",
+			Description: "Resolve the conflicts between two branches with merde.ai. The result is stored in a ref for review; the branches are not changed.",
+			InputSchema: branchesSchema,
+			call:        lsp["merde/resolveBranch"],
+		},
+		{
+			Name:        "apply_result",
+			Description: "Adopt a resolved operation: move its topic branch to its result, keeping a backup.",
+			InputSchema: operationSchema,
+			call: withOperation(func(ctx context.Context, cfg *Config, op *operation) (any, error) {
+				return nil, adoptOperation(ctx, cfg, op)
+			}),
+		},
+		{
+			Name:        "undo",
+			Description: "Restore the topic branch of an applied operation to its previous value.",
+			InputSchema: operationSchema,
+			call: withOperation(func(ctx context.Context, cfg *Config, op *operation) (any, error) {
+				return nil, undoOperation(ctx, cfg, op)
+			}),
+		},
+	}
+}
+
+// withOperation adapts f into a tool that operates on a recorded operation.
+func withOperation(f func(ctx context.Context, cfg *Config, op *operation) (any, error)) func(context.Context, json.RawMessage) (any, error) {
+	return func(ctx context.Context, args json.RawMessage) (any, error) {
+		var p struct {
+			Operation string `json:"operation"`
+		}
+		err := decodeParams(args, &p)
+		if err != nil {
+			return nil, err
+		}
+		cfg, err := LoadDefault(ctx)
+		if err != nil {
+			return nil, err
+		}
+		op, err := loadOperation(ctx, cfg, p.Operation)
+		if err != nil {
+			return nil, err
+		}
+		result, err := f(ctx, cfg, op)
+		if result == nil && err == nil {
+			result = map[string]string{"operation": op.ID, "status": "ok"}
+		}
+		return result, err
+	}
+}
+
+// mcpHandlers returns the JSON-RPC methods of the MCP server.
+func mcpHandlers() map[string]rpcHandler {
+	tools := mcpTools()
+	return map[string]rpcHandler{
+		"initialize": func(ctx context.Context, params json.RawMessage) (any, error) {
+			return map[string]any{
+				"protocolVersion": mcpProtocolVersion,
+				"capabilities":    map[string]any{"tools": map[string]any{}},
+				"serverInfo":      map[string]string{"name": "merde", "version": version},
+			}, nil
+		},
+		"notifications/initialized": func(ctx context.Context, params json.RawMessage) (any, error) {
+			return nil, nil
+		},
+		"ping": func(ctx context.Context, params json.RawMessage) (any, error) {
+			return map[string]any{}, nil
+		},
+		"tools/list": func(ctx context.Context, params json.RawMessage) (any, error) {
+			return map[string]any{"tools": tools}, nil
+		},
+		"tools/call": func(ctx context.Context, params json.RawMessage) (any, error) {
+			var p struct {
+				Name      string          `json:"name"`
+				Arguments json.RawMessage `json:"arguments"`
+			}
+			err := decodeParams(params, &p)
+			if err != nil {
+				return nil, err
+			}
+			for _, tool := range tools {
+				if tool.Name == p.Name {
+					var result any
+					err := collectExit(ctx, func(ctx context.Context) error {
+						var err error
+						result, err = tool.call(ctx, p.Arguments)
+						return err
+					})
+					return mcpToolResult(result, err), nil
+				}
+			}
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "unknown tool: " + p.Name}
+		},
+	}
+}
+
+// mcpToolResult converts the outcome of a tool call into an MCP tool result.
+// Tool failures are reported to the agent in the result, not as protocol errors.
+func mcpToolResult(result any, err error) map[string]any {
+	if err != nil {
+		return map[string]any{
+			"isError": true,
+			"content": []map[string]string{{"type": "text", "text": err.Error()}},
+		}
+	}
+	text, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		text = []byte(fmt.Sprint(result))
+	}
+	return map[string]any{
+		"content": []map[string]string{{"type": "text", "text": string(text)}},
+	}
+}
+
+func doMCP(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		return usageErrorf("merde mcp takes no arguments")
+	}
+	// stdout belongs to the protocol.
+	err := takeOverStdout()
+	if err != nil {
+		return err
+	}
+	conn := newRPCConn(os.Stdin, os.Stdout, true)
+	return conn.serve(ctx, mcpHandlers(), "")
+}
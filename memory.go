@@ -0,0 +1,184 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// A memoryEntry is a resolution shared with the user's team, keyed by conflict fingerprint.
+type memoryEntry struct {
+	Fingerprint string    `json:"fingerprint"`
+	Path        string    `json:"path"`
+	Contents    []byte    `json:"contents,omitempty"`
+	Created     time.Time `json:"created"`
+	By          string    `json:"by,omitempty"` // who shared it, as the server knows them
+}
+
+// teamMemoryEnabled reports whether the user opted in to sharing resolutions with their team.
+func teamMemoryEnabled(cfg *Config) bool {
+	return cfg.Get(teamMemoryKey) == "on"
+}
+
+// resolveFromMemory resolves unresolved paths whose conflicts the user's team has resolved before,
+// exactly as they were resolved then.
+func resolveFromMemory(ctx context.Context, cfg *Config, info *deconflictRequestInfo) error {
+	if !teamMemoryEnabled(cfg) {
+		return nil
+	}
+	paths := make(map[string][]string) // fingerprint -> paths
+	for _, p := range unresolved(info) {
+		m, err := readFileMerge(ctx, cfg, info, p)
+		if err != nil {
+			return err
+		}
+		fp := conflictFingerprint(m)
+		paths[fp] = append(paths[fp], p)
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	var req struct {
+		Fingerprints []string `json:"fingerprints"`
+	}
+	for fp := range paths {
+		req.Fingerprints = append(req.Fingerprints, fp)
+	}
+	var resp struct {
+		Entries []memoryEntry `json:"entries"`
+	}
+	err := baseRequest(cfg).
+		Path("/cli/memory/lookup").
+		Accept("application/json").
+		BodyJSON(&req).
+		ToJSON(&resp).
+		Fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("looking up team resolutions: %w", err)
+	}
+	for _, e := range resp.Entries {
+		for _, p := range paths[e.Fingerprint] {
+			err := resolveLocally(ctx, cfg, info, p, e.Contents, "team memory")
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rememberResolutions shares the server's resolutions in the adopted operation op with the user's team.
+func rememberResolutions(ctx context.Context, cfg *Config, op *operation) error {
+	if !teamMemoryEnabled(cfg) {
+		return nil
+	}
+	r := op.Report
+	info := &deconflictRequestInfo{
+		verb:     r.Verb,
+		mainSHA:  r.MainSHA,
+		topicSHA: r.TopicSHA,
+		baseSHA:  r.BaseSHA,
+	}
+	var err error
+	info.mainChanges, err = cfg.Git.ChangedPaths(ctx, r.BaseSHA, r.MainSHA)
+	if err != nil {
+		return err
+	}
+	var entries []memoryEntry
+	for _, res := range r.Resolutions {
+		if res.By != "server" {
+			continue // only the server's resolutions are worth remembering
+		}
+		m, err := readFileMerge(ctx, cfg, info, res.Path)
+		if err != nil {
+			return err
+		}
+		contents, err := cfg.Git.ReadBlob(ctx, op.result(), res.Path)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, memoryEntry{Fingerprint: conflictFingerprint(m), Path: res.Path, Contents: contents})
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	audit, err := beginAudit(ctx, cfg, "memory")
+	if err != nil {
+		return err
+	}
+	if audit != nil {
+		audit.MainRef, audit.TopicRef = r.MainRef, r.TopicRef
+		audit.MainSHA, audit.TopicSHA, audit.BaseSHA = r.MainSHA, r.TopicSHA, r.BaseSHA
+		for _, e := range entries {
+			audit.BytesSent += int64(len(e.Contents))
+		}
+	}
+	err = baseRequest(cfg).
+		Path("/cli/memory").
+		Accept("application/json").
+		BodyJSON(map[string]any{"entries": entries}).
+		Fetch(ctx)
+	err = cmp.Or(err, audit.finish(ctx, cfg, err))
+	if err != nil {
+		return err
+	}
+	ui.Status("shared %d resolutions with your team", len(entries))
+	return nil
+}
+
+func doMemoryList(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		return usageErrorf("merde memory list takes no arguments")
+	}
+	cfg, err := LoadDefault(ctx)
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Entries []memoryEntry `json:"entries"`
+	}
+	err = baseRequest(cfg).
+		Path("/cli/memory").
+		Accept("application/json").
+		ToJSON(&resp).
+		Fetch(ctx)
+	if err != nil {
+		return err
+	}
+	if len(resp.Entries) == 0 {
+		fmt.Printf("no shared resolutions\n")
+		return nil
+	}
+	for _, e := range resp.Entries {
+		fmt.Printf("%s  %-12s  %-20s  %s\n", e.Fingerprint[:12], humanize.Time(e.Created), e.By, e.Path)
+	}
+	return nil
+}
+
+func doMemoryForget(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return usageErrorf("merde memory forget takes exactly one fingerprint")
+	}
+	cfg, err := LoadDefault(ctx)
+	if err != nil {
+		return err
+	}
+	err = baseRequest(cfg).
+		Path("/cli/memory/" + url.PathEscape(args[0])).
+		Accept("application/json").
+		Delete().
+		Fetch(ctx)
+	if err != nil {
+		return err
+	}
+	ui.Status("forgot %s", args[0])
+	return nil
+}
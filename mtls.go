@@ -0,0 +1,129 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/josharian/xc"
+)
+
+// tlsConfig is the TLS configuration for connections to the server, shared by all transports.
+var tlsConfig *tls.Config
+
+// configureTLS sets up the client certificate, if any, that the server or a proxy in front of it requires,
+// and returns the transport to use: transport, with the certificate presented only to the server's host,
+// never to forges or other hosts merde talks to.
+// The certificate is loaded only when the server asks for one, so that a keychain is never consulted needlessly.
+func configureTLS(cfg *Config, server *url.URL, transport *http.Transport) http.RoundTripper {
+	certRef, keyRef := cfg.Get(clientCertKey), cfg.Get(clientKeyKey)
+	if certRef == "" {
+		return transport
+	}
+	load := sync.OnceValues(func() (*tls.Certificate, error) {
+		certPEM, err := readPEM(certRef)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", clientCertKey, err)
+		}
+		keyPEM := certPEM // the key may be in the same file
+		if keyRef != "" {
+			keyPEM, err = readPEM(keyRef)
+			if err != nil {
+				return nil, fmt.Errorf("reading %s: %w", clientKeyKey, err)
+			}
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		return &cert, nil
+	})
+	tlsConfig = &tls.Config{
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return load()
+		},
+	}
+	toServer := transport.Clone()
+	toServer.TLSClientConfig = tlsConfig
+	return &serverTransport{host: server.Host, server: toServer, other: transport}
+}
+
+// A serverTransport sends requests to the server's host with one transport, and all others with another.
+type serverTransport struct {
+	host          string
+	server, other http.RoundTripper
+}
+
+func (t *serverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == t.host {
+		return t.server.RoundTrip(req)
+	}
+	return t.other.RoundTrip(req)
+}
+
+// readPEM reads a PEM file, or, for a reference starting with "!", the output of a command,
+// such as one that reads the key from a keychain: !security find-generic-password -s merde-key -w
+// The command runs without a shell, so that it works the same everywhere; its arguments are split at spaces,
+// except within single or double quotes.
+func readPEM(ref string) ([]byte, error) {
+	command, ok := strings.CutPrefix(ref, "!")
+	if !ok {
+		return os.ReadFile(ref)
+	}
+	args, err := splitArgs(command)
+	if err != nil {
+		return nil, err
+	}
+	out, err := xc.Command(context.Background(), args[0], args[1:]...).
+		Describef("run %s", command).
+		Run().
+		String()
+	return []byte(out), err
+}
+
+// splitArgs splits a command line into its arguments at spaces, except within single or double quotes,
+// which it removes.
+func splitArgs(command string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+	for _, r := range command {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			arg.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", command)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	return args, nil
+}
@@ -0,0 +1,34 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"security find-generic-password -s merde-key -w", []string{"security", "find-generic-password", "-s", "merde-key", "-w"}},
+		{"  op  read\t'op://vault/merde key/cert' ", []string{"op", "read", "op://vault/merde key/cert"}},
+		{`cat "C:\Program Files\merde\cert.pem"`, []string{"cat", `C:\Program Files\merde\cert.pem`}},
+		{`pass show merde/"client cert"s`, []string{"pass", "show", "merde/client certs"}},
+		{`echo ''`, []string{"echo", ""}},
+	}
+	for _, tt := range tests {
+		got, err := splitArgs(tt.in)
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("splitArgs(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "   ", `echo "unterminated`} {
+		if got, err := splitArgs(bad); err == nil {
+			t.Errorf("splitArgs(%q) = %q; want error", bad, got)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"merde.ai/git"
+)
+
+// An unreachableError reports that the server could not be reached at all.
+type unreachableError struct {
+	err    error
+	cached bool // whether the pack was cached for merde retry
+}
+
+func (e *unreachableError) Error() string {
+	if e.cached {
+		return fmt.Sprintf("server unreachable (%v): check your network or proxy; your pack was cached and merde retry will reuse it", e.err)
+	}
+	return fmt.Sprintf("server unreachable (%v): check your network or proxy, then run: merde retry", e.err)
+}
+
+func (e *unreachableError) Unwrap() error { return e.err }
+
+// isNetworkError reports whether err means the server could not be reached, as opposed to answering badly.
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	return errors.As(err, &opErr) || errors.As(err, &dnsErr) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// checkReachable sends a quick HEAD request to the server.
+// Any HTTP response at all counts as reachable.
+func checkReachable(ctx context.Context, cfg *Config) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := baseRequest(cfg).Path("/cli/root").Method(http.MethodHead).Request(ctx)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return &unreachableError{err: err}
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// A retryRecord remembers the operation to redo with merde retry.
+type retryRecord struct {
+	Verb  string `json:"verb"`
+	Main  string `json:"main"`
+	Topic string `json:"topic"`
+}
+
+// merdeDir returns the directory for merde's own state inside the git dir.
+func merdeDir(ctx context.Context, cfg *Config) (string, error) {
+	commonDir, err := cfg.Git.CommonDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(commonDir, "merde"), nil
+}
+
+// packCachePaths returns where the pack for the given commits and options is cached:
+// the pack itself, and the path modes found while building it.
+func packCachePaths(ctx context.Context, cfg *Config, mainSHA, topicSHA string, opts *git.PackOptions) (string, string, error) {
+	dir, err := merdeDir(ctx, cfg)
+	if err != nil {
+		return "", "", err
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n%d\n", mainSHA, topicSHA, strings.Join(opts.Exclude, "\x00"), strings.Join(opts.Extra, "\x00"), opts.HistoryBudget)
+	base := filepath.Join(dir, "pack-cache", fmt.Sprintf("%x", h.Sum(nil))[:16])
+	return base + ".pack", base + ".modes.json", nil
+}
+
+// historyBudget returns the configured history budget: how many of the most recent commits on each side
+// to send with their trees and blobs, or 0 for all.
+func historyBudget(cfg *Config) (int, error) {
+	s := cfg.Get(historyBudgetKey)
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid %s %q, want a positive number of commits", historyBudgetKey, s)
+	}
+	return n, nil
+}
+
+// buildPack returns the pack for info, reusing the one cached by a failed attempt if possible.
+// If the pack can be streamed, buildPack only plans it, in info.packPlan, and returns a pack with no data;
+// streamPack then packs it during the upload, overlapping packing with sending.
+func buildPack(ctx context.Context, cfg *Config, info *deconflictRequestInfo) (*git.Pack, error) {
+	opts := info.packOptions()
+	packPath, modesPath, err := packCachePaths(ctx, cfg, info.mainSHA, info.topicSHA, opts)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(packPath)
+	if err == nil {
+		pack := &git.Pack{Data: string(data)}
+		modes, err := os.ReadFile(modesPath)
+		if err == nil && json.Unmarshal(modes, &pack.Modes) == nil {
+			ui.Status("reusing the pack cached by the previous attempt")
+			recordPackSize(len(pack.Data))
+			return pack, nil
+		}
+	}
+	// A detached job needs the whole pack cached before the foreground process hands it over.
+	// Streaming is opt-in: a streamed upload has no Pack-Size for servers that price by it.
+	if cfg.Get(streamPackKey) == "on" && !flagDetach {
+		plan, err := cfg.Git.PlanMergePack(ctx, info.mainSHA, info.topicSHA, opts)
+		if err != nil {
+			return nil, err
+		}
+		info.packPlan = plan
+		return &git.Pack{Modes: plan.Modes}, nil
+	}
+	pack, err := cfg.Git.MergePack(ctx, info.mainSHA, info.topicSHA, opts)
+	if err != nil {
+		return nil, err
+	}
+	recordPackSize(len(pack.Data))
+	return pack, nil
+}
+
+// cachePack caches info's pack, so that merde retry, or a detached job, reuses it rather than building it again.
+// A streamed pack was never kept whole, so it is packed again.
+func cachePack(ctx context.Context, cfg *Config, info *deconflictRequestInfo) error {
+	packPath, modesPath, err := packCachePaths(ctx, cfg, info.mainSHA, info.topicSHA, info.packOptions())
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(modesPath); err == nil {
+		return nil // reused from the cache
+	}
+	// Only the latest pack is worth keeping.
+	os.RemoveAll(filepath.Dir(packPath))
+	err = os.MkdirAll(filepath.Dir(packPath), 0o755)
+	if err != nil {
+		return fmt.Errorf("caching pack: %w", err)
+	}
+	modes := info.packModes
+	if info.packPlan != nil {
+		modes = info.packPlan.Modes
+		f, err := os.Create(packPath)
+		if err != nil {
+			return fmt.Errorf("caching pack: %w", err)
+		}
+		err = cfg.Git.WritePack(ctx, info.packPlan.Objects, f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	} else {
+		err = os.WriteFile(packPath, []byte(info.pack), 0o644)
+	}
+	if err == nil {
+		var data []byte
+		data, err = json.Marshal(modes)
+		if err == nil {
+			// The modes are written last: they mark the pack complete.
+			err = os.WriteFile(modesPath, data, 0o644)
+		}
+	}
+	if err != nil {
+		os.RemoveAll(filepath.Dir(packPath))
+		return fmt.Errorf("caching pack: %w", err)
+	}
+	return nil
+}
+
+// streamPack returns a request body that packs the objects of info.packPlan as it is read,
+// at no more than rate bytes per second if rate is positive.
+func streamPack(ctx context.Context, cfg *Config, info *deconflictRequestInfo, rate int64) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		info.packSent.Store(0)
+		w := writerFunc(func(p []byte) (int, error) {
+			n, err := pw.Write(p)
+			info.packSent.Add(int64(n))
+			return n, err
+		})
+		err := cfg.Git.WritePack(ctx, info.packPlan.Objects, w)
+		recordPackSize(int(info.packSent.Load()))
+		pw.CloseWithError(err)
+	}()
+	return struct {
+		io.Reader
+		io.Closer
+	}{throttle(pr, rate), pr}
+}
+
+// A writerFunc is a function that implements io.Writer.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+// clearRetry forgets the pack cache and retry record once an operation has succeeded.
+func clearRetry(ctx context.Context, cfg *Config) error {
+	dir, err := merdeDir(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	os.RemoveAll(filepath.Join(dir, "pack-cache"))
+	err = os.Remove(filepath.Join(dir, "retry.json"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// saveRetry records the operation so that merde retry can redo it.
+func saveRetry(ctx context.Context, cfg *Config, verb, mainRef, topicRef string) error {
+	dir, err := merdeDir(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(&retryRecord{Verb: verb, Main: mainRef, Topic: topicRef})
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "retry.json"), data, 0o644)
+}
+
+func doRetry(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		return usageErrorf("merde retry takes no arguments")
+	}
+	cfg, err := LoadDefault(ctx)
+	if err != nil {
+		return err
+	}
+	dir, err := merdeDir(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "retry.json"))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no failed merde operation to retry")
+	}
+	if err != nil {
+		return err
+	}
+	var rr retryRecord
+	err = json.Unmarshal(data, &rr)
+	if err != nil {
+		return err
+	}
+	err = requireCleanGitStatus(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	ui.Status("retrying: merde %s of %s and %s", rr.Verb, rr.Main, rr.Topic)
+	_, err = deconflict(ctx, cfg, rr.Verb, rr.Main, rr.Topic)
+	return err
+}
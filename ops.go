@@ -0,0 +1,150 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// An operation is a record of a completed merde operation, kept in the local operations store.
+type operation struct {
+	ID      string     `json:"id"`
+	Time    time.Time  `json:"time"`
+	Report  *report    `json:"report"`
+	Adopted *time.Time `json:"adopted,omitempty"` // when the topic branch was moved to the result, if it was
+	Undone  *time.Time `json:"undone,omitempty"`  // when merde undo deleted the refs it created, if it did
+
+	ServerID string `json:"server_id,omitempty"` // the server's ID for the operation, if it sent one
+	BotRef   string `json:"bot_ref,omitempty"`   // where merde bot delivered the result, as given by botJob.target
+}
+
+// result returns the commit hash the operation finally produced, or "" if none.
+func (op *operation) result() string {
+	if len(op.Report.Refs) == 0 {
+		return ""
+	}
+	return op.Report.Refs[len(op.Report.Refs)-1].SHA
+}
+
+// opsDir returns the directory of the local operations store, which lives inside the git dir.
+func opsDir(ctx context.Context, cfg *Config) (string, error) {
+	commonDir, err := cfg.Git.CommonDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(commonDir, "merde", "operations"), nil
+}
+
+// saveOperation records the operation described by info in the local operations store.
+func saveOperation(ctx context.Context, cfg *Config, info *deconflictRequestInfo) (*operation, error) {
+	now := time.Now()
+	op := &operation{
+		// IDs sort chronologically.
+		ID:       now.UTC().Format("20060102-150405") + "-" + info.topicSHA[:8],
+		Time:     now,
+		Report:   makeReport(info),
+		ServerID: info.serverOperationID,
+	}
+	err := writeOperation(ctx, cfg, op)
+	if err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// writeOperation writes op to the local operations store, replacing any previous record of it.
+func writeOperation(ctx context.Context, cfg *Config, op *operation) error {
+	dir, err := opsDir(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(op, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, op.ID+".json"), data, 0o644)
+}
+
+// operationIDs returns the IDs of all recorded operations, oldest first.
+func operationIDs(ctx context.Context, cfg *Config) ([]string, error) {
+	dir, err := opsDir(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var ids []string
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if ok {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// lastUndoable returns the most recent operation that merde undo has not yet undone.
+func lastUndoable(ctx context.Context, cfg *Config) (*operation, error) {
+	ids, err := operationIDs(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(ids) - 1; i >= 0; i-- {
+		op, err := loadOperation(ctx, cfg, ids[i])
+		if err != nil {
+			return nil, err
+		}
+		if op.Undone == nil {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("nothing to undo: every merde operation in this repository is already undone")
+}
+
+// loadOperation loads the operation with the given ID from the local operations store.
+// An empty ID means the most recent operation.
+func loadOperation(ctx context.Context, cfg *Config, id string) (*operation, error) {
+	if id == "" {
+		ids, err := operationIDs(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return nil, fmt.Errorf("no merde operations recorded in this repository")
+		}
+		id = ids[len(ids)-1]
+	}
+	dir, err := opsDir(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, id+".json"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no merde operation %s", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	op := new(operation)
+	err = json.Unmarshal(data, op)
+	if err != nil {
+		return nil, fmt.Errorf("reading operation %s: %w", id, err)
+	}
+	return op, nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+)
+
+// Result formats: how the server sends back the objects of its result.
+const (
+	resultPack  = "pack"  // a pack of the new objects
+	resultPatch = "patch" // per-commit diffs, from which the client rebuilds the objects
+)
+
+// A patchSet is the server's result as patches, sent in place of a pack when result_format is patch.
+// The client rebuilds each commit and checks its hash against the server's.
+type patchSet struct {
+	Ref     string        `json:"ref"`
+	Commits []patchCommit `json:"commits"` // parents before children; the last is the result
+}
+
+// A patchCommit is one commit of a patchSet.
+type patchCommit struct {
+	SHA       string   `json:"sha"` // hash the rebuilt commit must have
+	Tree      string   `json:"tree"`
+	Parents   []string `json:"parents"`
+	Base      string   `json:"base"`      // tree-ish that Diff applies to; default the first parent
+	Diff      string   `json:"diff"`      // git diff --binary from Base to Tree
+	Author    string   `json:"author"`    // "Name <email> time zone", as in the commit object
+	Committer string   `json:"committer"` // likewise
+	Message   string   `json:"message"`
+}
+
+// applyPatchSet rebuilds the commits of ps locally, verifying each against the hashes the server declared,
+// and returns the part to process in its place, which creates ps.Ref.
+func applyPatchSet(ctx context.Context, cfg *Config, ps *patchSet) (*Response, error) {
+	if len(ps.Commits) == 0 {
+		return nil, fmt.Errorf("server sent a patch result for %s with no commits", ps.Ref)
+	}
+	var sha string
+	for _, c := range ps.Commits {
+		if len(c.Parents) == 0 && c.Base == "" {
+			return nil, fmt.Errorf("server sent patch commit %s with no parent or base", c.SHA)
+		}
+		var base string
+		if c.Base != "" {
+			base = c.Base
+		} else {
+			base = c.Parents[0]
+		}
+		tree, err := cfg.Git.ApplyToTree(ctx, base, c.Diff)
+		if err != nil {
+			return nil, fmt.Errorf("applying the server's patch for %s: %w", c.SHA, err)
+		}
+		if tree != c.Tree {
+			return nil, fmt.Errorf("server's patch for %s builds tree %s, but the server declared %s", c.SHA, tree, c.Tree)
+		}
+		var obj bytes.Buffer
+		fmt.Fprintf(&obj, "tree %s\n", tree)
+		for _, p := range c.Parents {
+			fmt.Fprintf(&obj, "parent %s\n", p)
+		}
+		fmt.Fprintf(&obj, "author %s\ncommitter %s\n\n%s", c.Author, c.Committer, c.Message)
+		sha, err = cfg.Git.WriteCommit(ctx, obj.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		if sha != c.SHA {
+			return nil, fmt.Errorf("rebuilt commit %s from the server's patch, but the server declared %s", sha, c.SHA)
+		}
+	}
+	return &Response{IsJSON: true, Ref: ps.Ref, SHA: sha}, nil
+}
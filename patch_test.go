@@ -0,0 +1,90 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"merde.ai/git/gittest"
+)
+
+// patchCommitOf describes the commit sha as the server would in a patchSet, with its diff against base.
+func patchCommitOf(t *testing.T, run func(args ...string) string, sha, base string) patchCommit {
+	t.Helper()
+	c := patchCommit{SHA: sha, Tree: run("rev-parse", sha+"^{tree}"), Base: base}
+	raw := run("cat-file", "commit", sha)
+	header, message, _ := strings.Cut(raw, "\n\n")
+	c.Message = message + "\n"
+	for _, line := range strings.Split(header, "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "parent":
+			c.Parents = append(c.Parents, value)
+		case "author":
+			c.Author = value
+		case "committer":
+			c.Committer = value
+		}
+	}
+	from := base
+	if from == "" {
+		from = c.Parents[0]
+	}
+	c.Diff = run("diff", "--binary", "--full-index", from, sha) + "\n"
+	return c
+}
+
+func TestApplyPatchSet(t *testing.T) {
+	cfg, dir, run := testRepo(t)
+	ctx := context.Background()
+	base := gittest.Commit(t, dir, run, "base", map[string]string{"a.txt": "one\ntwo\nthree\n", "bin": "\x00\x01\x02"})
+	first := gittest.Commit(t, dir, run, "first", map[string]string{"a.txt": "one\n2\nthree\n", "new.txt": "new\n"})
+	second := gittest.Commit(t, dir, run, "second\n\nwith a body", map[string]string{"bin": "\x00\x03", "new.txt": ""})
+	run("checkout", "-q", "-b", "side", base)
+	side := gittest.Commit(t, dir, run, "side", map[string]string{"side.txt": "side\n"})
+	run("merge", "-q", "--no-edit", second)
+	merge := run("rev-parse", "HEAD")
+
+	ps := &patchSet{Ref: "refs/merde/result", Commits: []patchCommit{
+		patchCommitOf(t, run, first, ""),
+		patchCommitOf(t, run, second, ""),
+		// A merge's diff may apply to another parent than the first.
+		patchCommitOf(t, run, merge, second),
+	}}
+	if got := ps.Commits[2].Parents; len(got) != 2 || got[0] != side {
+		t.Fatalf("merge parents = %v; want %s first", got, side)
+	}
+	resp, err := applyPatchSet(ctx, cfg, ps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Ref != ps.Ref || resp.SHA != merge {
+		t.Errorf("applyPatchSet = %s at %s; want %s at %s", resp.Ref, resp.SHA, ps.Ref, merge)
+	}
+
+	tests := []struct {
+		name   string
+		change func(c *patchCommit)
+		want   string
+	}{
+		{"tree", func(c *patchCommit) { c.Tree = strings.Repeat("0", 40) }, "but the server declared"},
+		{"sha", func(c *patchCommit) { c.Message = "other\n" }, "but the server declared"},
+		{"diff", func(c *patchCommit) { c.Diff = strings.ReplaceAll(c.Diff, "-two", "-zwei") }, "applying the server's patch"},
+		{"parents", func(c *patchCommit) { c.Parents = nil }, "no parent or base"},
+	}
+	for _, tt := range tests {
+		c := patchCommitOf(t, run, first, "")
+		tt.change(&c)
+		_, err := applyPatchSet(ctx, cfg, &patchSet{Ref: ps.Ref, Commits: []patchCommit{c}})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("with a bad %s: err = %v; want one containing %q", tt.name, err, tt.want)
+		}
+	}
+	if _, err := applyPatchSet(ctx, cfg, &patchSet{Ref: ps.Ref}); err == nil {
+		t.Errorf("applyPatchSet with no commits succeeded; want an error")
+	}
+}
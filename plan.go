@@ -0,0 +1,169 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// A planStep is one line of an interactive rebase plan: what to do with one topic commit.
+type planStep struct {
+	action  string // pick, drop, squash, or reword
+	commit  string
+	message string // new commit message, for reword
+}
+
+// String formats s for the server as "action:commit".
+func (s *planStep) String() string {
+	return s.action + ":" + s.commit
+}
+
+// planActions maps the actions of a rebase plan, and their abbreviations, to the actions.
+var planActions = map[string]string{
+	"pick": "pick", "p": "pick",
+	"drop": "drop", "d": "drop",
+	"squash": "squash", "s": "squash",
+	"reword": "reword", "r": "reword",
+}
+
+const planHelp = `
+# Rebase plan for %s onto %s.
+#
+# Commands:
+# p, pick <commit> = use commit
+# r, reword <commit> = use commit, but edit the commit message
+# s, squash <commit> = use commit, but meld into previous commit
+# d, drop <commit> = remove commit
+#
+# These lines can be re-ordered; they are executed from top to bottom.
+# Deleting a line drops its commit. An empty plan aborts the rebase.
+`
+
+// editRebasePlan lets the user edit the plan of a rebase in their git editor, as git rebase -i does,
+// and records it in info so that the resolved history has the structure they intend.
+func editRebasePlan(ctx context.Context, cfg *Config, info *deconflictRequestInfo) error {
+	if plan := detached.planAnswer(); plan != nil {
+		info.plan = plan
+		return nil
+	}
+	if !interactive {
+		return fmt.Errorf("cannot edit the rebase plan: not running interactively")
+	}
+	commits, err := cfg.Git.CommitsInRange(ctx, info.baseSHA+".."+info.topicSHA)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, c := range commits {
+		subject, err := cfg.Git.CommitMessage(ctx, c)
+		if err != nil {
+			return err
+		}
+		subject, _, _ = strings.Cut(subject, "\n")
+		action := "pick"
+		if info.dropLanded && slices.Contains(info.landed, c) {
+			action = "drop"
+		}
+		fmt.Fprintf(&b, "%s %s %s\n", action, c[:12], subject)
+	}
+	fmt.Fprintf(&b, planHelp, info.topicRef, info.mainRef)
+	text, err := editText(ctx, cfg, "MERDE_REBASE_TODO", b.String(), "edit rebase plan")
+	if err != nil {
+		return err
+	}
+	if text == "" {
+		return fmt.Errorf("aborting due to empty rebase plan")
+	}
+	steps, err := parseRebasePlan(text, commits)
+	if err != nil {
+		return err
+	}
+	err = rewordCommits(ctx, cfg, steps)
+	if err != nil {
+		return err
+	}
+	info.plan = steps
+	return nil
+}
+
+const rewordHelp = `
+# Please enter the new commit message for %s. Lines starting
+# with '#' will be ignored, and an empty message aborts the rebase.
+`
+
+// rewordCommits lets the user edit the message of each commit the plan rewords, in plan order, as git rebase -i does.
+func rewordCommits(ctx context.Context, cfg *Config, steps []*planStep) error {
+	for _, s := range steps {
+		if s.action != "reword" {
+			continue
+		}
+		message, err := cfg.Git.CommitMessage(ctx, s.commit)
+		if err != nil {
+			return err
+		}
+		template := strings.TrimRight(message, "\n") + "\n" + fmt.Sprintf(rewordHelp, s.commit[:12])
+		s.message, err = editText(ctx, cfg, "MERDE_EDITMSG", template, "edit message of "+s.commit[:12])
+		if err != nil {
+			return err
+		}
+		if s.message == "" {
+			return fmt.Errorf("aborting due to empty commit message for %s", s.commit[:12])
+		}
+	}
+	return nil
+}
+
+// rewords formats the new messages of the plan's reworded commits for the server, as "commit:message".
+func rewords(steps []*planStep) []string {
+	var rw []string
+	for _, s := range steps {
+		if s.action == "reword" {
+			rw = append(rw, s.commit+":"+s.message)
+		}
+	}
+	return rw
+}
+
+// parseRebasePlan parses an edited rebase plan of commits.
+// Commits missing from the plan are dropped.
+func parseRebasePlan(text string, commits []string) ([]*planStep, error) {
+	var steps []*planStep
+	seen := make(map[string]bool)
+	for i, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("rebase plan line %d: missing commit", i+1)
+		}
+		action, ok := planActions[fields[0]]
+		if !ok {
+			return nil, fmt.Errorf("rebase plan line %d: unknown command %q", i+1, fields[0])
+		}
+		idx := slices.IndexFunc(commits, func(c string) bool { return strings.HasPrefix(c, fields[1]) })
+		if idx < 0 {
+			return nil, fmt.Errorf("rebase plan line %d: %s is not a commit being rebased", i+1, fields[1])
+		}
+		commit := commits[idx]
+		if seen[commit] {
+			return nil, fmt.Errorf("rebase plan line %d: %s appears more than once", i+1, fields[1])
+		}
+		seen[commit] = true
+		if action == "squash" && !slices.ContainsFunc(steps, func(s *planStep) bool { return s.action != "drop" }) {
+			return nil, fmt.Errorf("rebase plan line %d: cannot squash without a previous commit", i+1)
+		}
+		steps = append(steps, &planStep{action: action, commit: commit})
+	}
+	for _, c := range commits {
+		if !seen[c] {
+			steps = append(steps, &planStep{action: "drop", commit: c})
+		}
+	}
+	return steps, nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+)
+
+// conflictFree reports whether git alone can merge mainRef into topicRef, or rebase topicRef onto mainRef,
+// without conflicts, by doing the work in memory.
+func conflictFree(ctx context.Context, cfg *Config, verb, mainRef, topicRef string) (bool, error) {
+	if verb == "merge" {
+		_, conflicts, err := cfg.Git.MergeTree(ctx, topicRef, mainRef)
+		return err == nil && len(conflicts) == 0, err
+	}
+	// Replay the topic's commits as git rebase would, stopping at the first that conflicts.
+	commits, err := cfg.Git.CommitsInRange(ctx, mainRef+".."+topicRef)
+	if err != nil {
+		return false, err
+	}
+	tip, err := cfg.Git.ResolveRef(ctx, mainRef)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range commits {
+		onto, pick, err := pickCommits(ctx, cfg, c, tip)
+		if err != nil {
+			return false, err
+		}
+		tree, conflicts, err := cfg.Git.MergeTree(ctx, onto, pick)
+		if err != nil || len(conflicts) > 0 {
+			return false, err
+		}
+		tip, err = cfg.Git.CommitTree(ctx, tree, "merde: "+c, tip)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// skipIfConflictFree tells the user how to do the operation with git and reports true
+// if it has no conflicts for merde to resolve.
+func skipIfConflictFree(ctx context.Context, cfg *Config, verb, mainRef, topicRef string) (bool, error) {
+	if verb == "rebase" && flagInteractive {
+		return false, nil // the plan may reorder or drop commits
+	}
+	ok, err := conflictFree(ctx, cfg, verb, mainRef, topicRef)
+	if err != nil || !ok {
+		return false, err
+	}
+	if verb == "merge" {
+		ui.Status("no conflicts: git can merge %s into %s on its own; run: git merge %s", mainRef, topicRef, mainRef)
+	} else {
+		ui.Status("no conflicts: git can rebase %s onto %s on its own; run: git rebase %s %s", topicRef, mainRef, mainRef, topicRef)
+	}
+	return true, nil
+}
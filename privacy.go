@@ -0,0 +1,113 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+
+	"merde.ai/git"
+)
+
+// Optional metadata, which the redact config can suppress.
+const (
+	redactOS       = "os"
+	redactArch     = "arch"
+	redactGo       = "go"
+	redactGit      = "git"
+	redactRemotes  = "remotes"
+	redactRefs     = "refs"
+	redactLanguage = "language"
+)
+
+var redactable = []string{redactOS, redactArch, redactGo, redactGit, redactRemotes, redactRefs, redactLanguage}
+
+// redacted reports whether the user asked not to send the optional metadata name.
+func redacted(cfg *Config, name string) bool {
+	for _, r := range strings.Split(cfg.Get(redactKey), ",") {
+		if strings.TrimSpace(r) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// unlessRedacted returns value, or "" if the user asked not to send the optional metadata name.
+// Used with HeaderOptional, which omits empty headers.
+func unlessRedacted(cfg *Config, name, value string) string {
+	if redacted(cfg, name) {
+		return ""
+	}
+	return value
+}
+
+// A metadataItem is metadata merde sends with requests, as shown by merde privacy.
+type metadataItem struct {
+	header string
+	redact string // name to suppress it with the redact config; "" if it is required
+	value  string // value on this machine, or a description
+	why    string
+}
+
+func doPrivacy(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		return usageErrorf("merde privacy takes no arguments")
+	}
+	path, err := DefaultPath()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadValues(path)
+	if err != nil {
+		return err
+	}
+	remotes := "none found"
+	if gg, err := git.NewGit(ctx, cfg.Get(gitExeKey)); err == nil {
+		cfg.Git = gg
+		cfg.GitVersion, _ = gg.Version(ctx)
+		if urls, _ := forgeRemotes(ctx, cfg); len(urls) > 0 {
+			remotes = strings.Join(urls, ", ")
+		}
+	}
+	items := []metadataItem{
+		{"Authorization", "", "your merde token", "identifying your account"},
+		{"Merde-Client-Version, -Commit, -Date", "", fmt.Sprintf("%s, %s, %s", version, commit, date), "working around client bugs"},
+		{"Merde-Client-API-Version", "", apiRequestVersion, "selecting the protocol"},
+		{"Merde-Client-OS", redactOS, runtime.GOOS, "platform-specific advice, such as line endings"},
+		{"Merde-Client-Arch", redactArch, runtime.GOARCH, "diagnosing platform-specific bugs"},
+		{"Merde-Client-Go", redactGo, runtime.Version(), "diagnosing toolchain-specific bugs"},
+		{"Git-Version", redactGit, cfg.GitVersion, "using only git features you have"},
+		{"Remote", redactRemotes, remotes, "associating operations with a forge repository; only GitHub, Bitbucket, sourcehut, and GitLab remotes are sent"},
+		{"Accept-Language", redactLanguage, cmp.Or(language, "none"), "localizing help and messages from the server"},
+		{"Main-Ref, Topic-Ref", redactRefs, "branch names of each merge or rebase", "naming the results"},
+		{"Main-SHA, Topic-SHA, Pack-Size", "", "commit hashes and upload size of each merge or rebase", "the operation itself"},
+		{"query parameters", "", "paths of conflicted files, with the results of merde's local analysis", "the operation itself"},
+		{"request body", "", "a pack of the objects needed to merge the two branches", "the operation itself"},
+	}
+	fmt.Printf("merde sends these with requests to %s:\n\n", cfg.Get(serverRootKey))
+	for _, it := range items {
+		status := "required"
+		if it.redact != "" {
+			status = "sent; suppress with redact=" + it.redact
+			if redacted(cfg, it.redact) {
+				status = "suppressed"
+			}
+		}
+		fmt.Printf("%s: %s\n  %s\n  for %s\n", it.header, status, it.value, it.why)
+	}
+	for _, r := range strings.Split(cfg.Get(redactKey), ",") {
+		if r = strings.TrimSpace(r); r != "" && !slices.Contains(redactable, r) {
+			ui.Warn("unknown redact item %q; known items: %s", r, strings.Join(redactable, ", "))
+		}
+	}
+	fmt.Printf("\nsuppress optional metadata with: merde config %s %s\n", redactKey, strings.Join(redactable, ","))
+	fmt.Printf("anonymous usage metrics are separate; see: merde telemetry status\n")
+	fmt.Printf("see and delete what the server stores for each repository with: merde repos list\n")
+	return nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// processAlive reports whether the process with the given pid exists.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// detachedProcAttr returns the attributes that detach a background process from the terminal.
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
+
+// fileOwner returns the name of the user that owns the file described by fi, or "" if unknown.
+func fileOwner(fi os.FileInfo) string {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ""
+	}
+	uid := strconv.FormatUint(uint64(st.Uid), 10)
+	u, err := user.LookupId(uid)
+	if err != nil {
+		return "uid " + uid
+	}
+	return u.Username
+}
+
+// canWrite checks that a file can be created in dir, asking the kernel rather than creating one.
+func canWrite(dir string) error {
+	const wOK, xOK = 0x2, 0x1 // creating a file needs write and search permission
+	err := syscall.Access(dir, wOK|xOK)
+	if err != nil {
+		return &os.PathError{Op: "access", Path: dir, Err: err}
+	}
+	return nil
+}
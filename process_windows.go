@@ -0,0 +1,49 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+const processQueryLimitedInformation = 0x1000
+
+// processAlive reports whether the process with the given pid exists.
+func processAlive(pid int) bool {
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(h)
+	var code uint32
+	err = syscall.GetExitCodeProcess(h, &code)
+	const stillActive = 259
+	return err == nil && code == stillActive
+}
+
+const detachedProcess = 0x00000008
+
+// detachedProcAttr returns the attributes that detach a background process from the console.
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: detachedProcess | syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// fileOwner returns the name of the user that owns the file described by fi, or "" if unknown.
+// Windows file ownership is not reported.
+func fileOwner(fi os.FileInfo) string {
+	return ""
+}
+
+// canWrite checks that a file can be created in dir.
+// Windows access control lists are not worth evaluating by hand, so it creates and removes one.
+func canWrite(dir string) error {
+	f, err := os.CreateTemp(dir, ".merde-probe-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,160 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+const (
+	progressFrame    = 100 * time.Millisecond // how often an animated progress line is redrawn
+	progressInterval = 10 * time.Second       // how often progress is reported as a status line instead
+	progressBarWidth = 24
+)
+
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+// A progress reports on a slow step while it runs.
+// With rich output on a terminal, it animates a line below the step's status:
+// a spinner, or a bar with speed and time left once the step's size is known.
+// Otherwise, as when stdout is a log or in accessibility mode, it repeats a plain status line now and then.
+type progress struct {
+	label string       // what the step is doing, such as "uploading"
+	total int64        // bytes the step will handle; 0 if unknown
+	count func() int64 // bytes handled so far; nil if the step is not measured in bytes
+	start time.Time
+
+	once sync.Once
+	quit chan struct{}
+	done chan struct{}
+}
+
+// progressMu serializes drawing the animated progress line with other output to the terminal.
+var (
+	progressMu    sync.Mutex
+	progressDrawn bool // whether the animated progress line is on screen
+)
+
+// startProgress starts reporting on a step. The caller reports the step's start as usual, with ui.Status,
+// and calls stop when the step is over.
+func startProgress(label string, total int64, count func() int64) *progress {
+	p := &progress{
+		label: label,
+		total: total,
+		count: count,
+		start: time.Now(),
+		quit:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	r, rich := ui.(richRenderer)
+	go p.run(rich && r.out == os.Stdout && ansi && isTerminal(os.Stdout))
+	return p
+}
+
+func (p *progress) run(animate bool) {
+	defer close(p.done)
+	interval := progressInterval
+	if animate {
+		interval = progressFrame
+	}
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for frame := 0; ; frame++ {
+		select {
+		case <-p.quit:
+			if animate {
+				progressMu.Lock()
+				clearProgress()
+				progressMu.Unlock()
+			}
+			return
+		case <-tick.C:
+		}
+		if !animate {
+			ui.Status("%s", p.describe())
+			continue
+		}
+		line := spinnerFrames[frame%len(spinnerFrames)] + " "
+		if p.total > 0 {
+			line = p.bar() + " "
+		}
+		progressMu.Lock()
+		fmt.Print("\r\x1b[K" + line + p.describe())
+		progressDrawn = true
+		progressMu.Unlock()
+	}
+}
+
+// stop stops reporting and erases the animated line, if any. It may be called more than once.
+func (p *progress) stop() {
+	p.once.Do(func() { close(p.quit) })
+	<-p.done
+}
+
+// describe says how far the step has got.
+func (p *progress) describe() string {
+	elapsed := time.Since(p.start)
+	if p.count == nil {
+		return fmt.Sprintf(tr("%s: %v so far"), tr(p.label), elapsed.Round(time.Second))
+	}
+	n := p.count()
+	rate := float64(n) / elapsed.Seconds()
+	if p.total <= 0 {
+		return fmt.Sprintf(tr("%s: %s (%s/s)"), tr(p.label), humanize.Bytes(uint64(n)), humanize.Bytes(uint64(rate)))
+	}
+	n = min(n, p.total)
+	left := "?"
+	if rate > 0 {
+		left = (time.Duration(float64(p.total-n) / rate * float64(time.Second))).Round(time.Second).String()
+	}
+	return fmt.Sprintf(tr("%s: %d%% of %s (%s/s, %s left)"), tr(p.label), 100*n/p.total, humanize.Bytes(uint64(p.total)), humanize.Bytes(uint64(rate)), left)
+}
+
+// bar draws how far the step has got as a bar, such as [=====>      ].
+func (p *progress) bar() string {
+	filled := int(min(p.count(), p.total) * progressBarWidth / p.total)
+	b := strings.Repeat("=", filled)
+	if filled < progressBarWidth {
+		b += ">" + strings.Repeat(" ", progressBarWidth-filled-1)
+	}
+	return "[" + b + "]"
+}
+
+// clearProgress erases the animated progress line, if it is on screen, so that other output starts on a clean line.
+// The caller holds progressMu.
+func clearProgress() {
+	if progressDrawn {
+		fmt.Print("\r\x1b[K")
+		progressDrawn = false
+	}
+}
+
+// withProgressCleared runs f, which writes to the terminal, with the animated progress line out of its way.
+func withProgressCleared(f func()) {
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	clearProgress()
+	f()
+}
+
+// A countingReader counts the bytes read from it.
+type countingReader struct {
+	io.ReadCloser
+	n *atomic.Int64
+}
+
+func (r countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n.Add(int64(n))
+	return n, err
+}
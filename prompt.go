@@ -0,0 +1,191 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var (
+	stdin = bufio.NewReader(os.Stdin)
+
+	// interactive reports whether the user can be prompted.
+	// It is false when stdin is used for something else, such as a protocol.
+	interactive = true
+)
+
+// prompt asks the user question until they answer with one of choices, and returns the answer.
+func prompt(question string, choices ...string) (string, error) {
+	if !interactive {
+		return "", fmt.Errorf("cannot ask %q: not running interactively", question)
+	}
+	for {
+		fmt.Printf("%s: ", tr(question))
+		line, err := stdin.ReadString('\n')
+		answer := strings.ToLower(strings.TrimSpace(line))
+		if slices.Contains(choices, answer) {
+			return answer, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("no answer to %q: %w", question, err)
+		}
+		fmt.Printf(tr("please answer one of: %s")+"\n", strings.Join(choices, ", "))
+	}
+}
+
+// Confirmations, named for the assume_yes and assume_no configs.
+const (
+	askAdoptUnexpected = "adopt-unexpected" // adopting a result that changes files that had no conflicts
+	askCost            = "cost"             // an operation estimated to cost more than confirm_credits
+	askDropLanded      = "drop-landed"      // dropping commits that already landed from a rebase
+	askSplitHistory    = "split-history"    // keeping the history merde split a commit into
+	askDeleteRepo      = "delete-repo"      // deleting a repository's data from the server
+	askWatchResolve    = "watch-resolve"    // resolving a conflicted git merge or rebase merde watch found
+)
+
+// errUnattended is returned by confirm when it would need to ask, but cannot.
+var errUnattended = errors.New("not running interactively")
+
+// askedByName reports whether the confirmation name is one --yes and assume_yes=all leave alone,
+// because what it approves cannot be undone. The assume_yes config must name it, or the user answer it.
+func askedByName(name string) bool {
+	return name == askDeleteRepo
+}
+
+// assumedAnswer returns the answer the confirmation policy gives to the confirmation name,
+// and whether it gives one: --yes or --no, or else the assume_no and then the assume_yes config.
+func assumedAnswer(cfg *Config, name string) (yes, ok bool, err error) {
+	switch {
+	case flagYes && flagNo:
+		return false, false, usageErrorf("--yes and --no cannot be used together")
+	case flagYes && !askedByName(name):
+		return true, true, nil
+	case flagNo:
+		return false, true, nil
+	}
+	for _, key := range []string{assumeNoKey, assumeYesKey} {
+		for _, n := range strings.Split(cfg.Get(key), ",") {
+			n = strings.TrimSpace(n)
+			if n == name || n == "all" && (key == assumeNoKey || !askedByName(name)) {
+				return key == assumeYesKey, true, nil
+			}
+		}
+	}
+	return false, false, nil
+}
+
+// confirm asks the yes-or-no question for the confirmation name, unless the confirmation policy answers it.
+// If it must ask but the user cannot be prompted, it returns an error wrapping errUnattended.
+func confirm(cfg *Config, name, question string) (bool, error) {
+	yes, ok, err := assumedAnswer(cfg, name)
+	if ok || err != nil {
+		return yes, err
+	}
+	if !interactive && askedByName(name) {
+		return false, fmt.Errorf("cannot ask %q: %w; answer it with merde config %s %s", question, errUnattended, assumeYesKey, name)
+	}
+	if !interactive {
+		return false, fmt.Errorf("cannot ask %q: %w; answer it with --yes or --no, or with merde config %s %s", question, errUnattended, assumeYesKey, name)
+	}
+	// The catalogs translate the question; the suffix is the same in every language.
+	answer, err := prompt(tr(question)+" [y/n]", "y", "n")
+	return answer == "y", err
+}
+
+// promptText asks the user question and returns their non-empty answer.
+func promptText(question string) (string, error) {
+	if !interactive {
+		return "", fmt.Errorf("cannot ask %q: not running interactively", question)
+	}
+	for {
+		fmt.Printf("%s: ", tr(question))
+		line, err := stdin.ReadString('\n')
+		answer := strings.TrimSpace(line)
+		if answer != "" {
+			return answer, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("no answer to %q: %w", question, err)
+		}
+	}
+}
+
+// promptSecret asks the user question and returns their non-empty answer, without echoing it.
+func promptSecret(question string) (string, error) {
+	if !interactive {
+		return "", fmt.Errorf("cannot ask %q: not running interactively", question)
+	}
+	restore := disableEcho()
+	answer, err := promptText(question)
+	restore()
+	fmt.Println()
+	return answer, err
+}
+
+// editMessage lets the user edit a commit message in their git editor, starting from initial.
+// Lines starting with # are dropped, as git commit does.
+func editMessage(ctx context.Context, cfg *Config, initial string) (string, error) {
+	if !interactive {
+		return "", fmt.Errorf("cannot edit the commit message: not running interactively")
+	}
+	template := strings.TrimRight(initial, "\n") + "\n\n" +
+		"# Please enter the commit message for the merge. Lines starting\n" +
+		"# with '#' will be ignored, and an empty message aborts.\n"
+	message, err := editText(ctx, cfg, "MERDE_MSG", template, "edit commit message")
+	if err != nil {
+		return "", err
+	}
+	if message == "" {
+		return "", fmt.Errorf("aborting due to empty commit message")
+	}
+	return message + "\n", nil
+}
+
+// editText lets the user edit text in their git editor, in a file named name in the git directory.
+// It returns the edited text, without lines starting with # and surrounding whitespace.
+func editText(ctx context.Context, cfg *Config, name, text, desc string) (string, error) {
+	editor, err := cfg.Git.Editor(ctx)
+	if err != nil {
+		return "", err
+	}
+	gitDir, err := cfg.Git.GitDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(gitDir, name)
+	defer os.Remove(path)
+	err = os.WriteFile(path, []byte(text), 0o644)
+	if err != nil {
+		return "", err
+	}
+	err = cfg.Git.Shell(ctx, editor+` "$@"`, path).
+		Stdin(os.Stdin).
+		Stdout(os.Stdout).
+		Stderr(os.Stderr).
+		Describef("%s", desc).
+		Run().
+		Wait()
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, strings.TrimRight(line, " \t\r"))
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), nil
+}
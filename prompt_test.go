@@ -0,0 +1,54 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import "testing"
+
+func TestAssumedAnswer(t *testing.T) {
+	tests := []struct {
+		yes, no       bool
+		assumeYes     string
+		assumeNo      string
+		name          string
+		wantYes, want bool
+	}{
+		{name: askCost},
+		{yes: true, name: askCost, wantYes: true, want: true},
+		{no: true, name: askCost, want: true},
+		{assumeYes: "all", name: askCost, wantYes: true, want: true},
+		{assumeYes: "drop-landed, cost", name: askCost, wantYes: true, want: true},
+		{assumeYes: "drop-landed", name: askCost},
+		{assumeYes: "all", assumeNo: "cost", name: askCost, want: true},
+		{assumeNo: "all", name: askCost, want: true},
+
+		// Deleting a repository's data is only approved by name or by the user.
+		{yes: true, name: askDeleteRepo},
+		{assumeYes: "all", name: askDeleteRepo},
+		{yes: true, assumeYes: "all", name: askDeleteRepo},
+		{assumeYes: "delete-repo", name: askDeleteRepo, wantYes: true, want: true},
+		{yes: true, assumeYes: "delete-repo", name: askDeleteRepo, wantYes: true, want: true},
+		{no: true, name: askDeleteRepo, want: true},
+		{assumeNo: "all", name: askDeleteRepo, want: true},
+	}
+	for _, tt := range tests {
+		flagYes, flagNo = tt.yes, tt.no
+		t.Setenv(configEnv(assumeYesKey), "")
+		t.Setenv(configEnv(assumeNoKey), "")
+		cfg := &Config{Values: map[string]string{assumeYesKey: tt.assumeYes, assumeNoKey: tt.assumeNo}}
+		yes, ok, err := assumedAnswer(cfg, tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if yes != tt.wantYes || ok != tt.want {
+			t.Errorf("--yes=%v --no=%v assume_yes=%q assume_no=%q: assumedAnswer(%q) = %v, %v; want %v, %v",
+				tt.yes, tt.no, tt.assumeYes, tt.assumeNo, tt.name, yes, ok, tt.wantYes, tt.want)
+		}
+	}
+	flagYes, flagNo = true, true
+	if _, _, err := assumedAnswer(&Config{}, askCost); err == nil {
+		t.Errorf("--yes --no: assumedAnswer succeeded")
+	}
+	flagYes, flagNo = false, false
+}
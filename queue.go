@@ -0,0 +1,97 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// A queueResult is the machine-readable outcome of merde queue.
+type queueResult struct {
+	OK         bool   `json:"ok"`
+	Result     string `json:"result,omitempty"`    // commit hash of the resolved commit
+	Ref        string `json:"ref,omitempty"`       // ref holding the result
+	Operation  string `json:"operation,omitempty"` // merde operation ID, for merde review
+	Conflicts  int    `json:"conflicts"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// resolveForQueue resolves head against the queue's speculative base and stores the result in ref,
+// pushing it to remote if set.
+func resolveForQueue(ctx context.Context, cfg *Config, verb, base, head, ref, remote string) (*queueResult, error) {
+	err := requireCleanGitStatus(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	op, err := deconflict(ctx, cfg, verb, base, head)
+	if err != nil {
+		return nil, err
+	}
+	result := op.result()
+	if result == "" {
+		return nil, fmt.Errorf("operation %s produced no result", op.ID)
+	}
+	if remote != "" {
+		err = cfg.Git.Push(ctx, remote, result, ref)
+	} else {
+		err = cfg.Git.SetRef(ctx, ref, result)
+	}
+	if err != nil {
+		return nil, err
+	}
+	deleteResultRefs(ctx, cfg, op)
+	return &queueResult{OK: true, Result: result, Ref: ref, Operation: op.ID, Conflicts: len(op.Report.Conflicts)}, nil
+}
+
+func doQueue(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return usageErrorf("merde queue takes exactly 2 arguments")
+	}
+	base, head := args[0], args[1]
+	switch flagQueueVerb {
+	case "merge", "rebase":
+	default:
+		return fmt.Errorf("unknown --verb %q: want merge or rebase", flagQueueVerb)
+	}
+	// stdout carries the result.
+	err := takeOverStdout()
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, flagQueueTimeout)
+	defer cancel()
+	var res *queueResult
+	cfg, err := LoadDefault(ctx)
+	if err == nil {
+		ref := flagQueueRef
+		if ref == "" {
+			ref = refNamespace(cfg) + "queue/" + head
+		}
+		err = collectExit(ctx, func(ctx context.Context) error {
+			var err error
+			res, err = resolveForQueue(ctx, cfg, flagQueueVerb, base, head, ref, flagQueuePush)
+			return err
+		})
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %v", flagQueueTimeout)
+	}
+	if err != nil {
+		res = &queueResult{Error: err.Error()}
+	}
+	res.DurationMS = time.Since(start).Milliseconds()
+	encErr := json.NewEncoder(os.Stdout).Encode(res)
+	if err != nil {
+		return err
+	}
+	return encErr
+}
@@ -0,0 +1,98 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/carlmjohnson/requests"
+)
+
+// A quote is the server's estimate of what an operation will cost.
+type quote struct {
+	Credits   float64  `json:"credits"`   // estimated credits the operation will use
+	Remaining *float64 `json:"remaining"` // credits left on the account, if known
+}
+
+// fetchQuote asks the server what the operation described by info will cost.
+// It returns nil if the server does not bill per operation.
+func fetchQuote(ctx context.Context, cfg *Config, info *deconflictRequestInfo) (*quote, error) {
+	q := new(quote)
+	err := baseRequest(cfg).
+		Path("/cli/quote").
+		Accept("application/json").
+		Param("verb", info.verb).
+		ParamOptional("pack_size", packSizeParam(info)).
+		ParamOptional("pack_objects", packObjectsParam(info)).
+		Param("paths", strconv.Itoa(len(unresolved(info)))).
+		ToJSON(q).
+		Fetch(ctx)
+	if requests.HasStatusErr(err, http.StatusNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetching cost estimate: %w", err)
+	}
+	return q, nil
+}
+
+// packSizeParam returns the size of info's pack for a quote, or "" if it is streamed and so not yet known.
+func packSizeParam(info *deconflictRequestInfo) string {
+	if info.packPlan != nil {
+		return ""
+	}
+	return strconv.Itoa(len(info.pack))
+}
+
+// packObjectsParam returns the number of objects in info's pack for a quote, if it is streamed.
+func packObjectsParam(info *deconflictRequestInfo) string {
+	if info.packPlan == nil {
+		return ""
+	}
+	return strconv.Itoa(len(info.packPlan.Objects))
+}
+
+// confirmCost shows what the operation described by info will cost,
+// and asks for confirmation if that is more than the confirm_credits threshold.
+func confirmCost(ctx context.Context, cfg *Config, info *deconflictRequestInfo) error {
+	q, err := fetchQuote(ctx, cfg, info)
+	if err != nil || q == nil {
+		return err
+	}
+	if q.Remaining != nil {
+		ui.Status("this operation will use ~%g credits (%g remaining)", q.Credits, *q.Remaining)
+		if q.Credits > *q.Remaining {
+			return fmt.Errorf("not enough credits: this operation needs ~%g, and %g remain", q.Credits, *q.Remaining)
+		}
+	} else {
+		ui.Status("this operation will use ~%g credits", q.Credits)
+	}
+	threshold := cfg.Get(confirmCreditsKey)
+	if threshold == "" {
+		return nil
+	}
+	limit, err := strconv.ParseFloat(threshold, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", confirmCreditsKey, threshold, err)
+	}
+	if q.Credits <= limit {
+		return nil
+	}
+	yes, err := confirm(cfg, askCost, fmt.Sprintf("that is more than %s %g; continue?", confirmCreditsKey, limit))
+	if errors.Is(err, errUnattended) {
+		return fmt.Errorf("this operation would use ~%g credits, more than %s %g; raise it with: merde config %s <credits>", q.Credits, confirmCreditsKey, limit, confirmCreditsKey)
+	}
+	if err != nil {
+		return err
+	}
+	if !yes {
+		return fmt.Errorf("cancelled")
+	}
+	return nil
+}
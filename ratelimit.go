@@ -0,0 +1,61 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// uploadRate returns the upload bandwidth limit in bytes per second, or 0 for none.
+func uploadRate(cfg *Config) (int64, error) {
+	limit := cmp.Or(flagLimitRate, cfg.Get(limitRateKey))
+	if limit == "" {
+		return 0, nil
+	}
+	n, err := humanize.ParseBytes(limit)
+	if err != nil {
+		return 0, fmt.Errorf("invalid upload rate %q: %w", limit, err)
+	}
+	return int64(n), nil
+}
+
+// A throttledReader reads from r at no more than rate bytes per second, using a token bucket
+// that holds a quarter second of bytes, so that the rate is smooth rather than bursty.
+type throttledReader struct {
+	r      io.Reader
+	rate   float64 // bytes per second
+	burst  float64 // most tokens the bucket holds
+	tokens float64
+	last   time.Time
+}
+
+// throttle returns r limited to rate bytes per second, or r itself if rate is 0.
+func throttle(r io.Reader, rate int64) io.Reader {
+	if rate <= 0 {
+		return r
+	}
+	burst := max(float64(rate)/4, 4096)
+	return &throttledReader{r: r, rate: float64(rate), burst: burst, tokens: burst, last: time.Now()}
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > int(t.burst) {
+		p = p[:int(t.burst)]
+	}
+	n, err := t.r.Read(p)
+	now := time.Now()
+	t.tokens = min(t.burst, t.tokens+now.Sub(t.last).Seconds()*t.rate) - float64(n)
+	t.last = now
+	if t.tokens < 0 {
+		// Pay off the debt before handing the bytes on.
+		time.Sleep(time.Duration(-t.tokens / t.rate * float64(time.Second)))
+	}
+	return n, err
+}
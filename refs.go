@@ -0,0 +1,161 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+const defaultRefNamespace = "refs/merde/"
+
+// refNamespace returns the configured prefix for refs merde creates, ending in a slash.
+func refNamespace(cfg *Config) string {
+	ns := cfg.Get(refNamespaceKey)
+	if !strings.HasPrefix(ns, "refs/") {
+		ns = "refs/" + ns
+	}
+	if !strings.HasSuffix(ns, "/") {
+		ns += "/"
+	}
+	return ns
+}
+
+// namespacedRef moves ref, as named by the server, into the configured namespace.
+func namespacedRef(cfg *Config, ref string) string {
+	if rest, ok := strings.CutPrefix(ref, defaultRefNamespace); ok {
+		return refNamespace(cfg) + rest
+	}
+	return ref
+}
+
+// refAllowed reports whether the server may create ref.
+// Refs in the merde namespace are always allowed; others only if they match allowed_refs,
+// whose patterns are either exact ref names or prefixes ending in "*".
+func refAllowed(cfg *Config, ref string) bool {
+	if strings.HasPrefix(ref, refNamespace(cfg)) {
+		return true
+	}
+	for _, pattern := range strings.Split(cfg.Get(allowedRefsKey), ",") {
+		pattern = strings.TrimSpace(pattern)
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(ref, prefix) {
+			return true
+		}
+		if pattern == ref {
+			return true
+		}
+	}
+	return false
+}
+
+// backupRef returns the ref that holds the topic branch's value from before op was applied.
+func backupRef(cfg *Config, op *operation) string {
+	return refNamespace(cfg) + "backup/" + op.ID
+}
+
+// ownedRefs returns the refs in the merde namespace that op created, with the values op gave them.
+// A later operation may reuse a ref name; the value tells them apart.
+func ownedRefs(cfg *Config, op *operation) []reportRef {
+	refs := []reportRef{{Ref: backupRef(cfg, op), SHA: op.Report.TopicSHA}}
+	for _, r := range op.Report.Refs {
+		if strings.HasPrefix(r.Ref, refNamespace(cfg)) {
+			refs = append(refs, r)
+		}
+	}
+	return refs
+}
+
+// parseAge parses a duration such as "30d", "12h", or "90m".
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+func doCleanup(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		return usageErrorf("merde cleanup takes no arguments")
+	}
+	age, err := parseAge(flagCleanupOlderThan)
+	if err != nil {
+		return err
+	}
+	cfg, err := LoadDefault(ctx)
+	if err != nil {
+		return err
+	}
+	unlock, err := lockRepo(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	refs, err := cfg.Git.ListRefs(ctx, refNamespace(cfg))
+	if err != nil {
+		return err
+	}
+	ids, err := operationIDs(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-age)
+	owned := make(map[string]bool)
+	var deleted int
+	for _, id := range ids {
+		op, err := loadOperation(ctx, cfg, id)
+		if err != nil {
+			return err
+		}
+		for _, r := range ownedRefs(cfg, op) {
+			if refs[r.Ref] != r.SHA || owned[r.Ref] {
+				continue
+			}
+			owned[r.Ref] = true
+			if op.Time.After(cutoff) {
+				continue
+			}
+			if r.Ref == backupRef(cfg, op) && op.Adopted != nil && op.Undone == nil {
+				// merde undo restores the topic branch from this ref.
+				ui.Status("leaving %s: operation %s was adopted; merde undo needs it", r.Ref, op.ID)
+				continue
+			}
+			ui.Status("deleting %s (operation %s, %s)", r.Ref, op.ID, humanize.Time(op.Time))
+			deleted++
+			if flagCleanupDryRun {
+				continue
+			}
+			err = cfg.Git.DeleteRef(ctx, r.Ref, r.SHA)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	var untracked []string
+	for ref := range refs {
+		if !owned[ref] {
+			untracked = append(untracked, ref)
+		}
+	}
+	slices.Sort(untracked)
+	for _, ref := range untracked {
+		ui.Status("leaving %s: not tracked by any recorded operation", ref)
+	}
+	if flagCleanupDryRun {
+		ui.Status("would delete %d refs", deleted)
+		return nil
+	}
+	ui.Status("deleted %d refs", deleted)
+	return nil
+}
@@ -0,0 +1,244 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Output modes, for --output and the output config.
+const (
+	outputPlain = "plain" // text, without escape sequences
+	outputRich  = "rich"  // text, with color when stdout is a terminal
+	outputJSON  = "json"  // one JSON object per line, for CI and tools
+	outputQuiet = "quiet" // only warnings and errors
+)
+
+// A renderer presents what merde has to say while it works.
+// Commands report through ui rather than printing, so that the output mode applies everywhere.
+// Renderers write what belongs on stdout to the writer they were made with,
+// so that modes that take over stdout, such as lsp, can send it elsewhere.
+type renderer interface {
+	// Status reports progress or the outcome of a step, such as "analyzing...".
+	Status(format string, args ...any)
+	// Warn reports something the user should look at, though merde carries on.
+	Warn(format string, args ...any)
+	// Server relays output the server asked the client to show.
+	Server(stdout, stderr string)
+	// Operation reports a recorded operation, once its result is ready for review.
+	Operation(op *operation)
+	// Error reports the error a command failed with.
+	Error(err error)
+	// Output returns where the output of programs merde runs for the user, such as hooks, goes.
+	Output() io.Writer
+}
+
+// ui is the renderer for the output mode; main sets it once flags are parsed.
+var ui renderer = plainRenderer{out: os.Stdout}
+
+// outputMode is the output mode given by --output or the output config.
+var outputMode string
+
+// accessible reports whether the user asked for output that suits screen readers and dumb terminals,
+// with --plain or the accessibility config. It implies no escape sequences and no rich output;
+// anything that would animate, such as a spinner, prints a plain status line now and then instead.
+var accessible bool
+
+// configureOutput sets ui for the output mode given by --output or the output config,
+// and applies accessibility mode.
+func configureOutput() error {
+	cfg, err := loadConfigValues()
+	if err != nil {
+		return err
+	}
+	v := cfg.Get(accessibilityKey)
+	accessible = flagPlain || v == "on" || v == "true"
+	outputMode = cmp.Or(flagOutput, cfg.Get(outputKey))
+	if accessible {
+		ansi = false
+		if outputMode == outputRich {
+			outputMode = outputPlain
+		}
+	}
+	r, err := newRenderer(outputMode, os.Stdout)
+	if err != nil {
+		return err
+	}
+	ui = r
+	return nil
+}
+
+// takeOverStdout sends what ui writes to stdout to stderr instead, and stops merde from prompting,
+// for modes whose stdout carries a protocol or other output for programs.
+func takeOverStdout() error {
+	r, err := newRenderer(outputMode, os.Stderr)
+	if err != nil {
+		return err
+	}
+	ui = r
+	interactive = false
+	return nil
+}
+
+// newRenderer returns the renderer for mode, which may be "" to choose by whether stdout is a terminal,
+// writing what belongs on stdout to out.
+func newRenderer(mode string, out io.Writer) (renderer, error) {
+	switch mode {
+	case "":
+		if ansi {
+			return richRenderer{plainRenderer{out}}, nil
+		}
+		return plainRenderer{out}, nil
+	case outputPlain:
+		return plainRenderer{out}, nil
+	case outputRich:
+		return richRenderer{plainRenderer{out}}, nil
+	case outputJSON:
+		return jsonRenderer{out}, nil
+	case outputQuiet:
+		return quietRenderer{plainRenderer{out}}, nil
+	}
+	return nil, usageErrorf("unknown output mode %q; use plain, rich, json, or quiet", mode)
+}
+
+// operationRecorded is the message reporting that an operation is ready for review.
+const operationRecorded = "operation %s recorded; review it with: merde review, then adopt it with: merde adopt"
+
+// plainRenderer writes text: status to stdout, warnings and errors to stderr.
+// The text renderers translate messages into the user's language.
+type plainRenderer struct {
+	out io.Writer // stdout, unless a mode has taken it over
+}
+
+func (r plainRenderer) Status(format string, args ...any) {
+	fmt.Fprintf(r.out, tr(format)+"\n", args...)
+}
+
+func (plainRenderer) Warn(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, tr("warning")+": "+tr(format)+"\n", args...)
+}
+
+func (r plainRenderer) Server(stdout, stderr string) {
+	fmt.Fprint(r.out, stdout)
+	fmt.Fprint(os.Stderr, stderr)
+}
+
+func (r plainRenderer) Operation(op *operation) {
+	op.Report.printUnexpected(r)
+	r.Status(operationRecorded, op.ID)
+}
+
+func (plainRenderer) Error(err error) {
+	fmt.Fprintf(os.Stderr, "%s: %v\n", tr("error"), err)
+}
+
+func (r plainRenderer) Output() io.Writer {
+	return r.out
+}
+
+// richRenderer is plainRenderer with color on a terminal.
+// Its output moves any animated progress line out of the way.
+type richRenderer struct {
+	plainRenderer
+}
+
+func (r richRenderer) Status(format string, args ...any) {
+	withProgressCleared(func() { r.plainRenderer.Status(format, args...) })
+}
+
+func (r richRenderer) Server(stdout, stderr string) {
+	withProgressCleared(func() { r.plainRenderer.Server(stdout, stderr) })
+}
+
+const (
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiReset  = "\x1b[0m"
+)
+
+func (richRenderer) Warn(format string, args ...any) {
+	withProgressCleared(func() {
+		fmt.Fprintf(os.Stderr, ansiYellow+tr("warning")+":"+ansiReset+" "+tr(format)+"\n", args...)
+	})
+}
+
+func (r richRenderer) Operation(op *operation) {
+	op.Report.printUnexpected(r)
+	withProgressCleared(func() {
+		fmt.Fprintln(r.out, ansiBold+fmt.Sprintf(tr(operationRecorded), op.ID)+ansiReset)
+	})
+}
+
+func (richRenderer) Error(err error) {
+	withProgressCleared(func() {
+		fmt.Fprintf(os.Stderr, ansiRed+tr("error")+":"+ansiReset+" %v\n", err)
+	})
+}
+
+// jsonRenderer writes one JSON object per line to stdout, each with a type field.
+// Its messages stay in English, for tools that match on them.
+type jsonRenderer struct {
+	out io.Writer
+}
+
+func (r jsonRenderer) emit(v map[string]any) {
+	json.NewEncoder(r.out).Encode(v)
+}
+
+func (r jsonRenderer) Status(format string, args ...any) {
+	r.emit(map[string]any{"type": "status", "message": fmt.Sprintf(format, args...)})
+}
+
+func (r jsonRenderer) Warn(format string, args ...any) {
+	r.emit(map[string]any{"type": "warning", "message": fmt.Sprintf(format, args...)})
+}
+
+func (r jsonRenderer) Server(stdout, stderr string) {
+	r.emit(map[string]any{"type": "server", "stdout": stdout, "stderr": stderr})
+}
+
+func (r jsonRenderer) Operation(op *operation) {
+	r.emit(map[string]any{"type": "operation", "operation": op})
+}
+
+func (r jsonRenderer) Error(err error) {
+	r.emit(map[string]any{"type": "error", "message": err.Error()})
+}
+
+// Output is stderr, since the output of other programs would break up the JSON lines.
+func (jsonRenderer) Output() io.Writer {
+	return os.Stderr
+}
+
+// quietRenderer writes only warnings, errors, and the server's stderr.
+type quietRenderer struct {
+	plainRenderer
+}
+
+func (quietRenderer) Status(format string, args ...any) {}
+
+func (quietRenderer) Server(stdout, stderr string) {
+	fmt.Fprint(os.Stderr, stderr)
+}
+
+func (r quietRenderer) Operation(op *operation) {
+	op.Report.printUnexpected(r)
+}
+
+// Output discards what other programs write to stdout, as Server does the server's; their stderr still shows.
+func (quietRenderer) Output() io.Writer {
+	return io.Discard
+}
+
+// indent indents each line of lines by two spaces, for lists within a status or warning.
+func indent(lines []string) string {
+	return "  " + strings.Join(lines, "\n  ")
+}
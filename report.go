@@ -0,0 +1,194 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A createdRef is a ref created or updated during an operation.
+type createdRef struct {
+	ref string
+	sha string
+	old string // previous value, if the ref was updated rather than created
+}
+
+// A report describes a completed operation, for humans reviewing what merde did.
+type report struct {
+	Verb        string             `json:"verb"`
+	MainRef     string             `json:"main_ref"`
+	TopicRef    string             `json:"topic_ref"`
+	MainSHA     string             `json:"main_sha"`
+	TopicSHA    string             `json:"topic_sha"`
+	BaseSHA     string             `json:"base_sha"`
+	PackSize    int                `json:"pack_size"`
+	Conflicts   []reportConflict   `json:"conflicts"`
+	Resolutions []reportResolution `json:"resolutions"`
+	Unexpected  []string           `json:"unexpected,omitempty"` // files changed in the result that had no conflicts
+	Refs        []reportRef        `json:"refs"`
+	Undo        []string           `json:"undo"`
+}
+
+type reportConflict struct {
+	Path   string   `json:"path"`
+	Kind   string   `json:"kind"`             // content, delete/modify, or mode
+	Owners []string `json:"owners,omitempty"` // from CODEOWNERS
+}
+
+type reportResolution struct {
+	Path        string  `json:"path"`
+	By          string  `json:"by"` // local or server
+	Explanation string  `json:"explanation"`
+	Confidence  float64 `json:"confidence,omitempty"`
+}
+
+type reportRef struct {
+	Ref string `json:"ref"`
+	SHA string `json:"sha"`
+}
+
+// makeReport assembles a report from info after the server's response has been processed.
+func makeReport(info *deconflictRequestInfo) *report {
+	r := &report{
+		Verb:     info.verb,
+		MainRef:  info.mainRef,
+		TopicRef: info.topicRef,
+		MainSHA:  info.mainSHA,
+		TopicSHA: info.topicSHA,
+		BaseSHA:  info.baseSHA,
+		PackSize: info.packSize(),
+	}
+	for _, p := range bothModified(info) {
+		r.Conflicts = append(r.Conflicts, reportConflict{Path: p, Kind: "content", Owners: info.owners[p]})
+	}
+	for _, dm := range info.deleteModify {
+		r.Conflicts = append(r.Conflicts, reportConflict{Path: dm.path, Kind: "delete/modify", Owners: info.owners[dm.path]})
+		if dm.policy != leaveBoth {
+			r.Resolutions = append(r.Resolutions, reportResolution{Path: dm.path, By: "local", Explanation: dm.policy})
+		}
+	}
+	for _, mc := range info.modes {
+		if mc.conflicts() {
+			r.Conflicts = append(r.Conflicts, reportConflict{Path: mc.path, Kind: "mode"})
+		}
+	}
+	for _, lr := range info.resolved {
+		r.Resolutions = append(r.Resolutions, reportResolution{Path: lr.path, By: "local", Explanation: lr.how})
+	}
+	for _, sr := range info.serverResolutions {
+		r.Resolutions = append(r.Resolutions, reportResolution{Path: sr.Path, By: "server", Explanation: sr.Explanation, Confidence: sr.Confidence})
+	}
+	r.Unexpected = info.outOfScope
+	for _, cr := range info.createdRefs {
+		r.Refs = append(r.Refs, reportRef{Ref: cr.ref, SHA: cr.sha})
+	}
+	// Undo in reverse order, so that updated refs are restored before they are deleted.
+	for i := len(info.createdRefs) - 1; i >= 0; i-- {
+		cr := info.createdRefs[i]
+		if cr.old != "" {
+			r.Undo = append(r.Undo, fmt.Sprintf("git update-ref %s %s %s", cr.ref, cr.old, cr.sha))
+		} else {
+			r.Undo = append(r.Undo, fmt.Sprintf("git update-ref -d %s %s", cr.ref, cr.sha))
+		}
+	}
+	return r
+}
+
+// writeReport writes a report of info to path, as JSON or markdown depending on its extension.
+// An empty path means no report was requested.
+func writeReport(info *deconflictRequestInfo, path string) error {
+	if path == "" {
+		return nil
+	}
+	r := makeReport(info)
+	var data []byte
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		var err error
+		data, err = json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+	case ".md", ".markdown":
+		data = r.markdown()
+	default:
+		return fmt.Errorf("unsupported report format %q, want .md or .json", filepath.Ext(path))
+	}
+	err := os.WriteFile(path, data, 0o644)
+	if err != nil {
+		return err
+	}
+	ui.Status("wrote report to %s", path)
+	return nil
+}
+
+// printUnexpected warns, through rr, about the files r's result changed that had no conflicts, if any.
+func (r *report) printUnexpected(rr renderer) {
+	if len(r.Unexpected) == 0 {
+		return
+	}
+	rr.Warn("unexpected changes: the result changes %d files that had no conflicts:\n%s", len(r.Unexpected), indent(r.Unexpected))
+}
+
+// markdown renders r as markdown, suitable for attaching to a pull request.
+func (r *report) markdown() []byte {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "# merde %s report\n\n", r.Verb)
+	fmt.Fprintf(buf, "| | ref | commit |\n|---|---|---|\n")
+	fmt.Fprintf(buf, "| main | `%s` | `%s` |\n", r.MainRef, r.MainSHA)
+	fmt.Fprintf(buf, "| topic | `%s` | `%s` |\n", r.TopicRef, r.TopicSHA)
+	fmt.Fprintf(buf, "| merge base | | `%s` |\n\n", r.BaseSHA)
+	fmt.Fprintf(buf, "Uploaded %d bytes.\n\n", r.PackSize)
+
+	fmt.Fprintf(buf, "## Conflicts\n\n")
+	if len(r.Conflicts) == 0 {
+		fmt.Fprintf(buf, "None.\n")
+	}
+	for _, c := range r.Conflicts {
+		fmt.Fprintf(buf, "- `%s` (%s)", c.Path, c.Kind)
+		if len(c.Owners) > 0 {
+			fmt.Fprintf(buf, ", owned by %s", strings.Join(c.Owners, " "))
+		}
+		fmt.Fprintf(buf, "\n")
+	}
+
+	fmt.Fprintf(buf, "\n## Resolutions\n\n")
+	if len(r.Resolutions) == 0 {
+		fmt.Fprintf(buf, "None.\n")
+	} else {
+		fmt.Fprintf(buf, "| path | by | confidence | explanation |\n|---|---|---|---|\n")
+	}
+	for _, res := range r.Resolutions {
+		confidence := ""
+		if res.Confidence > 0 {
+			confidence = fmt.Sprintf("%.0f%%", res.Confidence*100)
+		}
+		explanation := strings.ReplaceAll(res.Explanation, "\n", " ")
+		fmt.Fprintf(buf, "| `%s` | %s | %s | %s |\n", res.Path, res.By, confidence, explanation)
+	}
+
+	if len(r.Unexpected) > 0 {
+		fmt.Fprintf(buf, "\n## Unexpected changes\n\n")
+		fmt.Fprintf(buf, "The result changes these files, which had no conflicts. Check them before adopting it.\n\n")
+		for _, p := range r.Unexpected {
+			fmt.Fprintf(buf, "- `%s`\n", p)
+		}
+	}
+
+	fmt.Fprintf(buf, "\n## Results\n\n")
+	for _, ref := range r.Refs {
+		fmt.Fprintf(buf, "- `%s` → `%s`\n", ref.Ref, ref.SHA)
+	}
+	if len(r.Undo) > 0 {
+		fmt.Fprintf(buf, "\n## Undo\n\n```sh\n%s\n```\n", strings.Join(r.Undo, "\n"))
+	}
+	return buf.Bytes()
+}
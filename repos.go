@@ -0,0 +1,125 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// A serverRepo is a repository the server associates with the user's account,
+// from the Remote headers of their operations.
+type serverRepo struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Remotes    []string  `json:"remotes"`
+	Operations int       `json:"operations"` // operations the server has records of
+	DataSize   int64     `json:"data_size"`  // bytes the server stores for the repository
+	LastUsed   time.Time `json:"last_used"`
+}
+
+func doReposList(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		return usageErrorf("merde repos list takes no arguments")
+	}
+	cfg, err := loadConfigValues()
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Repositories []serverRepo `json:"repositories"`
+	}
+	err = baseRequest(cfg).
+		Path("/cli/repos").
+		Accept("application/json").
+		ToJSON(&resp).
+		Fetch(ctx)
+	if err != nil {
+		return err
+	}
+	if len(resp.Repositories) == 0 {
+		fmt.Printf("no repositories\n")
+		return nil
+	}
+	for _, r := range resp.Repositories {
+		fmt.Printf("%-12s  %-24s  %4d operations  %8s  last used %s\n", r.ID, r.Name, r.Operations, humanize.Bytes(uint64(r.DataSize)), humanize.Time(r.LastUsed))
+		for _, remote := range r.Remotes {
+			fmt.Printf("              %s\n", remote)
+		}
+	}
+	return nil
+}
+
+func doReposRename(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return usageErrorf("merde repos rename takes a repository and its new name")
+	}
+	cfg, err := loadConfigValues()
+	if err != nil {
+		return err
+	}
+	err = baseRequest(cfg).
+		Path("/cli/repos/" + url.PathEscape(args[0])).
+		Method("PATCH").
+		Accept("application/json").
+		BodyJSON(map[string]string{"name": args[1]}).
+		Fetch(ctx)
+	if err != nil {
+		return err
+	}
+	ui.Status("renamed %s to %s", args[0], args[1])
+	return nil
+}
+
+func doReposDetach(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return usageErrorf("merde repos detach takes exactly one repository")
+	}
+	cfg, err := loadConfigValues()
+	if err != nil {
+		return err
+	}
+	err = baseRequest(cfg).
+		Path("/cli/repos/" + url.PathEscape(args[0]) + "/detach").
+		Accept("application/json").
+		Post().
+		Fetch(ctx)
+	if err != nil {
+		return err
+	}
+	ui.Status("detached %s; its data stays until you run: merde repos delete %s", args[0], args[0])
+	return nil
+}
+
+func doReposDelete(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return usageErrorf("merde repos delete takes exactly one repository")
+	}
+	cfg, err := loadConfigValues()
+	if err != nil {
+		return err
+	}
+	yes, err := confirm(cfg, askDeleteRepo, fmt.Sprintf("delete everything merde.ai stores for %s, including shared resolutions? this cannot be undone", args[0]))
+	if err != nil {
+		return err
+	}
+	if !yes {
+		return fmt.Errorf("not deleted")
+	}
+	err = baseRequest(cfg).
+		Path("/cli/repos/" + url.PathEscape(args[0])).
+		Accept("application/json").
+		Delete().
+		Fetch(ctx)
+	if err != nil {
+		return err
+	}
+	ui.Status("deleted the server's data for %s", args[0])
+	return nil
+}
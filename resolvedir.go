@@ -0,0 +1,104 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// underDir reports whether the repository path p is dir or inside it.
+// The empty dir is the top of the repository.
+func underDir(p, dir string) bool {
+	return dir == "" || p == dir || strings.HasPrefix(p, dir+"/")
+}
+
+// doResolveDir resolves the conflicts of an in-progress git merge or rebase under one directory with merde,
+// and leaves the rest for the user.
+func doResolveDir(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return usageErrorf("merde resolve-dir takes exactly one directory")
+	}
+	cfg, err := LoadDefault(ctx)
+	if err != nil {
+		return err
+	}
+	root, err := cfg.Git.RootDir(ctx)
+	if err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(args[0])
+	if err != nil {
+		return err
+	}
+	dir, err := filepath.Rel(root, abs)
+	if err != nil || dir == ".." || strings.HasPrefix(dir, "../") {
+		return fmt.Errorf("%s is outside the repository", args[0])
+	}
+	dir = filepath.ToSlash(dir)
+	if dir == "." {
+		dir = ""
+	}
+	unmerged, err := cfg.Git.UnmergedPaths(ctx)
+	if err != nil {
+		return err
+	}
+	if len(unmerged) == 0 {
+		return fmt.Errorf("no conflicted paths; is a merge or rebase in progress?")
+	}
+	var resolved, left int
+	for _, p := range unmerged {
+		if !underDir(p, dir) {
+			continue
+		}
+		// Stage 1 is the base, 2 ours, and 3 theirs; a missing base means both sides added the path.
+		base, _ := cfg.Git.ReadObject(ctx, ":1:"+p)
+		ours, err := cfg.Git.ReadObject(ctx, ":2:"+p)
+		if err != nil {
+			ui.Status("%s: not modified on both sides, leaving it", p)
+			left++
+			continue
+		}
+		theirs, err := cfg.Git.ReadObject(ctx, ":3:"+p)
+		if err != nil {
+			ui.Status("%s: not modified on both sides, leaving it", p)
+			left++
+			continue
+		}
+		name := filepath.Join(root, filepath.FromSlash(p))
+		if fi, err := os.Lstat(name); err == nil && !fi.Mode().IsRegular() {
+			ui.Status("%s: not a regular file, leaving it", p)
+			left++
+			continue
+		}
+		res, err := resolveFileRemote(ctx, cfg, p, base, ours, theirs)
+		if err != nil {
+			return fmt.Errorf("resolving %s: %w", p, err)
+		}
+		err = writeRegularFile(name, res.Contents)
+		if err != nil {
+			return err
+		}
+		if res.Conflicts {
+			ui.Status("%s: partly resolved, conflict markers remain", p)
+			left++
+			continue
+		}
+		err = cfg.Git.Add(ctx, p)
+		if err != nil {
+			return err
+		}
+		ui.Status("%s: resolved", p)
+		resolved++
+	}
+	if resolved+left == 0 {
+		return fmt.Errorf("no conflicted paths under %s", args[0])
+	}
+	ui.Status("resolved %d paths under %s; %d conflicted paths remain in the repository", resolved, args[0], len(unmerged)-resolved)
+	return nil
+}
@@ -0,0 +1,127 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"merde.ai/git"
+)
+
+// readMergeInput reads one input of merde resolve-file: a file, or failing that, a git blob such as a hash or commit:path.
+func readMergeInput(ctx context.Context, cfg *Config, arg string) ([]byte, error) {
+	data, err := os.ReadFile(arg)
+	if err == nil || !os.IsNotExist(err) {
+		return data, err
+	}
+	if cfg.Git == nil {
+		cfg.Git, err = git.NewGit(ctx, cfg.Get(gitExeKey))
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a file, and there is no git repository to read it from: %w", arg, err)
+		}
+	}
+	return cfg.Git.ReadObject(ctx, arg)
+}
+
+// A fileResolution is the server's three-way merge of one file.
+type fileResolution struct {
+	Contents    []byte `json:"contents"`
+	Conflicts   bool   `json:"conflicts"` // whether conflict markers remain in contents
+	Explanation string `json:"explanation"`
+}
+
+// resolveFileRemote asks the server to merge the changes from base to theirs into ours.
+// The path, if known, tells the server the file's language.
+func resolveFileRemote(ctx context.Context, cfg *Config, path string, base, ours, theirs []byte) (*fileResolution, error) {
+	req := struct {
+		Path   string `json:"path,omitempty"`
+		Base   []byte `json:"base"`
+		Ours   []byte `json:"ours"`
+		Theirs []byte `json:"theirs"`
+	}{path, base, ours, theirs}
+	audit, err := beginAudit(ctx, cfg, "resolve-file")
+	if err != nil {
+		return nil, err
+	}
+	if audit != nil {
+		audit.Path = path
+		audit.BytesSent = int64(len(base) + len(ours) + len(theirs))
+	}
+	res := new(fileResolution)
+	err = baseRequest(cfg).
+		Path("/cli/resolve-file").
+		Accept("application/json").
+		BodyJSON(&req).
+		ToJSON(res).
+		Fetch(ctx)
+	if audit != nil {
+		audit.BytesReceived = int64(len(res.Contents))
+	}
+	err = cmp.Or(err, audit.finish(ctx, cfg, err))
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func doResolveFile(ctx context.Context, args []string) error {
+	if len(args) != 3 {
+		return usageErrorf("merde resolve-file takes base, ours, and theirs")
+	}
+	path, err := DefaultPath()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadValues(path)
+	if err != nil {
+		return err
+	}
+	var inputs [3][]byte
+	for i, arg := range args {
+		inputs[i], err = readMergeInput(ctx, cfg, arg)
+		if err != nil {
+			return err
+		}
+	}
+	res, err := resolveFileRemote(ctx, cfg, flagResolveFilePath, inputs[0], inputs[1], inputs[2])
+	if err != nil {
+		return err
+	}
+	if flagResolveFileOutput == "" {
+		_, err = os.Stdout.Write(res.Contents)
+	} else {
+		err = writeRegularFile(flagResolveFileOutput, res.Contents)
+	}
+	if err != nil {
+		return err
+	}
+	if res.Explanation != "" {
+		fmt.Fprintln(os.Stderr, res.Explanation)
+	}
+	if res.Conflicts {
+		// Like git merge-file, exit 1 so that git treats the result as still conflicted.
+		fmt.Fprintln(os.Stderr, "conflicts remain")
+		return &ExitError{Code: 1}
+	}
+	return nil
+}
+
+// writeRegularFile writes data to name, which must be a regular file or not exist.
+// Unlike os.WriteFile, it does not write through a symlink to wherever it points.
+func writeRegularFile(name string, data []byte) error {
+	fi, err := os.Lstat(name)
+	if err == nil && !fi.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file; not writing to it", name)
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return os.WriteFile(name, data, 0o644)
+}
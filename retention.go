@@ -0,0 +1,33 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import "fmt"
+
+// retentionEphemeral as retention asks the server to delete uploaded objects as soon as it has resolved the conflicts.
+const retentionEphemeral = "ephemeral"
+
+// ephemeral reports whether to ask the server not to keep the objects uploaded by an operation,
+// from --ephemeral or the retention config.
+func ephemeral(cfg *Config) bool {
+	return flagEphemeral || cfg.Get(retentionKey) == retentionEphemeral
+}
+
+// retentionParam returns the Retention header for an upload: ephemeral or "" for the server's default.
+func retentionParam(cfg *Config) string {
+	if ephemeral(cfg) {
+		return retentionEphemeral
+	}
+	return ""
+}
+
+// checkDeleted fails an ephemeral upload whose response lacked the server's acknowledgment that it deleted the objects:
+// a server that ignores the Retention header keeps them.
+func checkDeleted(cfg *Config, deleted bool) error {
+	if ephemeral(cfg) && !deleted {
+		return fmt.Errorf("the server did not confirm deleting the uploaded objects, as --ephemeral or %s=%s asked; it may have kept them", retentionKey, retentionEphemeral)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// revertCommit reverts commit on top of tip, resolving any conflicts with merde, and returns the new commit.
+// It returns "" if reverting commit changes nothing in tip.
+//
+// The revert is posed as a single commit on top of commit that restores its parent's tree.
+// Its merge base with tip is commit itself, so combining it with tip undoes commit's changes, as git revert does.
+// If that conflicts, the server resolves it as a revert, which it handles like rebasing that commit onto tip.
+func revertCommit(ctx context.Context, cfg *Config, commit, tip, branch string) (string, error) {
+	parents, err := cfg.Git.Parents(ctx, commit)
+	if err != nil {
+		return "", err
+	}
+	if len(parents) != 1 {
+		return "", fmt.Errorf("cannot revert %s: it has %d parents", commit, len(parents))
+	}
+	bases, err := cfg.Git.MergeBases(ctx, []string{tip, commit})
+	if err != nil {
+		return "", err
+	}
+	if !slices.Equal(bases, []string{commit}) {
+		return "", fmt.Errorf("cannot revert %s: it is not in %s", commit, branch)
+	}
+	subject, err := cfg.Git.CommitMessage(ctx, commit)
+	if err != nil {
+		return "", err
+	}
+	subject, _, _ = strings.Cut(subject, "\n")
+	message := fmt.Sprintf("Revert \"%s\"\n\nThis reverts commit %s.\n", subject, commit)
+	parentTree, err := cfg.Git.Tree(ctx, parents[0])
+	if err != nil {
+		return "", err
+	}
+	tipTree, err := cfg.Git.Tree(ctx, tip)
+	if err != nil {
+		return "", err
+	}
+	undo, err := cfg.Git.CommitTree(ctx, parentTree, message, commit)
+	if err != nil {
+		return "", err
+	}
+	tree, conflicts, err := cfg.Git.MergeTree(ctx, tip, undo)
+	if err != nil {
+		return "", err
+	}
+	if len(conflicts) > 0 {
+		ui.Status("%s: %d conflicting paths, resolving with merde", commit[:12], len(conflicts))
+		tree, err = resolveRevert(ctx, cfg, branch, undo)
+		if err != nil {
+			return "", fmt.Errorf("reverting %s: %w", commit, err)
+		}
+	}
+	if tree == tipTree {
+		return "", nil
+	}
+	return cfg.Git.CommitTree(ctx, tree, message, tip)
+}
+
+// resolveRevert has merde apply the reverting commit undo to branch, and returns the resolved tree.
+func resolveRevert(ctx context.Context, cfg *Config, branch, undo string) (string, error) {
+	ref := refNamespace(cfg) + "tmp/revert"
+	err := cfg.Git.SetRef(ctx, ref, undo)
+	if err != nil {
+		return "", err
+	}
+	defer cfg.Git.DeleteRef(ctx, ref, undo)
+	op, err := deconflict(ctx, cfg, "revert", branch, ref)
+	if err != nil {
+		return "", err
+	}
+	// The operation's refs are only a step towards the revert commit.
+	defer deleteResultRefs(ctx, cfg, op)
+	result := op.result()
+	if result == "" {
+		return "", fmt.Errorf("merde produced no result")
+	}
+	return cfg.Git.Tree(ctx, result)
+}
+
+func doRevert(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return usageErrorf("merde revert takes at least one commit or commit range")
+	}
+	cfg, err := LoadDefault(ctx)
+	if err != nil {
+		return err
+	}
+	head, err := cfg.Git.FullRefName(ctx, "HEAD")
+	if err != nil {
+		return err
+	}
+	branch, ok := strings.CutPrefix(head, "refs/heads/")
+	if !ok {
+		return fmt.Errorf("cannot revert onto a detached HEAD; check out a branch first")
+	}
+	// As with git revert, the commits in a range are reverted newest first.
+	var commits []string
+	for _, arg := range args {
+		cs, err := cfg.Git.CommitsInRange(ctx, arg)
+		if err != nil {
+			return err
+		}
+		if len(cs) == 0 {
+			return fmt.Errorf("no commits in %s", arg)
+		}
+		slices.Reverse(cs)
+		commits = append(commits, cs...)
+	}
+	ui.Status("plan: revert %d commits on %s", len(commits), branch)
+	start, err := cfg.Git.ResolveRef(ctx, branch)
+	if err != nil {
+		return err
+	}
+	tip := start
+	for _, c := range commits {
+		reverted, err := revertCommit(ctx, cfg, c, tip, branch)
+		if err != nil {
+			return err
+		}
+		if reverted == "" {
+			ui.Status("%s  skipped, nothing left to revert", c[:12])
+			continue
+		}
+		ui.Status("%s  -> %s", c[:12], reverted[:12])
+		tip = reverted
+	}
+	if tip == start {
+		return fmt.Errorf("nothing to revert: %s no longer has those changes", branch)
+	}
+	_, err = saveReplay(ctx, cfg, "revert", branch, tip)
+	return err
+}
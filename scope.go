@@ -0,0 +1,67 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// verifyResult checks the server's result sha independently of how it arrived:
+// it checks that every object the result refers to is present,
+// then records in info.outOfScope the paths the result changes outside the conflict scope.
+func verifyResult(ctx context.Context, cfg *Config, info *deconflictRequestInfo, sha string) error {
+	err := cfg.Git.VerifyConnected(ctx, sha, info.mainSHA, info.topicSHA)
+	if err != nil {
+		return fmt.Errorf("server result %s: %w", sha, err)
+	}
+	paths, err := outOfScopePaths(ctx, cfg, info, sha)
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		if !slices.Contains(info.outOfScope, p) {
+			info.outOfScope = append(info.outOfScope, p)
+		}
+	}
+	return nil
+}
+
+// outOfScopePaths returns the paths that sha changes beyond what the operation needs, sorted.
+// For a merge, that is any path where sha differs from git's own merge of main and topic
+// other than the conflicted ones. For a rebase, it is any path sha changes relative to main
+// that neither the topic commits changed nor the merge conflicts on.
+func outOfScopePaths(ctx context.Context, cfg *Config, info *deconflictRequestInfo, sha string) ([]string, error) {
+	merged, conflicted, err := cfg.Git.MergeTree(ctx, info.mainSHA, info.topicSHA)
+	if err != nil {
+		return nil, err
+	}
+	scope := make(map[string]bool)
+	for _, p := range conflicted {
+		scope[p] = true
+	}
+	var changed map[string]string
+	switch info.verb {
+	case "merge":
+		changed, err = cfg.Git.ChangedPaths(ctx, merged, sha)
+	case "rebase", "revert":
+		for p := range info.topicChanges {
+			scope[p] = true
+		}
+		changed, err = cfg.Git.ChangedPaths(ctx, info.mainSHA, sha)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, p := range slices.Sorted(maps.Keys(changed)) {
+		if !scope[p] {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
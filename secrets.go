@@ -0,0 +1,249 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// secretKeys are the config keys whose values are encrypted when config encryption is on.
+var secretKeys = []string{tokenKey, githubTokenKey, bitbucketTokenKey, bitbucketServerTokenKey, authHeadersKey}
+
+const (
+	encryptionKeyFile    = "keyfile"
+	encryptionPassphrase = "passphrase"
+
+	encryptedPrefix = "enc:v1:" // marks an encrypted value: base64 of nonce and AES-256-GCM ciphertext
+
+	passphraseEnv        = "MERDE_PASSPHRASE" // passphrase for encrypted config, for non-interactive use
+	passphraseIterations = 600_000
+)
+
+// keyFilePath returns the path of the key file that encrypts the config's secrets.
+func (c *Config) keyFilePath() string {
+	if p := c.Get(keyFileKey); p != "" {
+		return p
+	}
+	return filepath.Join(filepath.Dir(c.path), "key")
+}
+
+// ciphers holds the cipher of each config file, by path, once its key is known.
+// The config is loaded several times per run; sharing the cipher asks for the passphrase only once.
+var (
+	ciphersMu sync.Mutex
+	ciphers   = make(map[string]cipher.AEAD)
+	undecrypt = make(map[string]bool) // encrypted values already reported as undecryptable
+)
+
+// aead returns the cipher that encrypts the config's secrets, reading the key file or asking for the passphrase
+// the first time it is needed in this process.
+func (c *Config) aead() (cipher.AEAD, error) {
+	ciphersMu.Lock()
+	defer ciphersMu.Unlock()
+	if aead, ok := ciphers[c.path]; ok {
+		return aead, nil
+	}
+	var key []byte
+	switch mode := c.Values[encryptionKey]; mode {
+	case encryptionKeyFile:
+		data, err := os.ReadFile(c.keyFilePath())
+		if err != nil {
+			return nil, err
+		}
+		key, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("%s is not a merde key file", c.keyFilePath())
+		}
+	case encryptionPassphrase:
+		salt, err := base64.StdEncoding.DecodeString(c.Values[encryptionSaltKey])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", encryptionSaltKey, err)
+		}
+		passphrase := os.Getenv(passphraseEnv)
+		if passphrase == "" {
+			passphrase, err = promptSecret("merde config passphrase")
+			if err != nil {
+				return nil, fmt.Errorf("%w; or set %s", err, passphraseEnv)
+			}
+		}
+		key = pbkdf2.Key([]byte(passphrase), salt, passphraseIterations, 32, sha256.New)
+	default:
+		return nil, fmt.Errorf("config is not encrypted")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	ciphers[c.path] = aead
+	return aead, nil
+}
+
+// decryptSecrets decrypts the config's encrypted secrets, stored or from the environment, as it is loaded.
+// Decrypting up front keeps the passphrase prompt on the goroutine that loads the config,
+// before any other goroutine, spinner, or prompt gets going.
+func (c *Config) decryptSecrets() {
+	for _, key := range secretKeys {
+		for _, value := range []string{os.Getenv(configEnv(key)), c.Values[key]} {
+			if !strings.HasPrefix(value, encryptedPrefix) {
+				continue
+			}
+			plain, err := c.decrypt(key, value)
+			if err != nil {
+				ciphersMu.Lock()
+				warned := undecrypt[value]
+				undecrypt[value] = true
+				ciphersMu.Unlock()
+				if !warned {
+					ui.Warn("cannot decrypt %s: %v", key, err)
+				}
+				continue
+			}
+			c.plain[value] = plain
+		}
+	}
+}
+
+// encrypt encrypts value if key is secret and config encryption is on.
+func (c *Config) encrypt(key, value string) (string, error) {
+	if !slices.Contains(secretKeys, key) {
+		return value, nil
+	}
+	return c.seal(key, value)
+}
+
+// seal encrypts value, which is stored under name, if config encryption is on.
+// Encrypting secrets kept outside the config, such as session cookies, with the config's key
+// lets them be unlocked together.
+func (c *Config) seal(name, value string) (string, error) {
+	if c.Values[encryptionKey] == "" || value == "" || strings.HasPrefix(value, encryptedPrefix) {
+		return value, nil
+	}
+	aead, err := c.aead()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	rand.Read(nonce)
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(name))
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt decrypts the stored value of key, or of another secret stored under that name.
+func (c *Config) decrypt(key, value string) (string, error) {
+	aead, err := c.aead()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("corrupt encrypted value")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, ciphertext, []byte(key))
+	if err != nil {
+		return "", errors.New("wrong key or passphrase")
+	}
+	return string(plain), nil
+}
+
+func doConfigEncrypt(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		return usageErrorf("merde config encrypt takes no arguments")
+	}
+	cfg, err := loadConfigValues()
+	if err != nil {
+		return err
+	}
+	if mode := cfg.Values[encryptionKey]; mode != "" {
+		return fmt.Errorf("config secrets are already encrypted with a %s", mode)
+	}
+	// Read the secrets before turning encryption on.
+	var secrets []string
+	for _, k := range secretKeys {
+		if v := cfg.Values[k]; v != "" {
+			secrets = append(secrets, k, v)
+		}
+	}
+	var pairs []string
+	if flagConfigKeyFile != "" {
+		pairs = append(pairs, keyFileKey, flagConfigKeyFile)
+	}
+	if flagConfigPassphrase {
+		passphrase := os.Getenv(passphraseEnv)
+		if passphrase == "" {
+			passphrase, err = promptSecret("new passphrase")
+			if err != nil {
+				return err
+			}
+			again, err := promptSecret("repeat passphrase")
+			if err != nil {
+				return err
+			}
+			if again != passphrase {
+				return fmt.Errorf("passphrases do not match")
+			}
+		}
+		os.Setenv(passphraseEnv, passphrase) // so that the secrets below are encrypted without asking again
+		salt := make([]byte, 16)
+		rand.Read(salt)
+		pairs = append(pairs, encryptionSaltKey, base64.StdEncoding.EncodeToString(salt), encryptionKey, encryptionPassphrase)
+	} else {
+		if flagConfigKeyFile != "" {
+			cfg.Values[keyFileKey] = flagConfigKeyFile
+		}
+		path := cfg.keyFilePath()
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			key := make([]byte, 32)
+			rand.Read(key)
+			err = os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0o600)
+			if err != nil {
+				return err
+			}
+			ui.Status("created key file %s; keep it safe, and off shared storage", path)
+		}
+		pairs = append(pairs, encryptionKey, encryptionKeyFile)
+	}
+	err = cfg.Update(append(pairs, secrets...)...)
+	if err != nil {
+		return err
+	}
+	ui.Status("encrypted %d secret(s) in %s", len(secrets)/2, cfg.path)
+	// Saving the session cookies again encrypts them too.
+	if _, err := os.Stat(cookiesPath(cfg)); err == nil {
+		server, err := url.Parse(cfg.Get(serverRootKey))
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", serverRootKey, err)
+		}
+		jar, err := loadSessionJar(cfg, server)
+		if err != nil {
+			return err
+		}
+		err = jar.save()
+		if err != nil {
+			return err
+		}
+		ui.Status("encrypted the session cookies in %s", jar.path)
+	}
+	return nil
+}
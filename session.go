@@ -0,0 +1,294 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Session modes.
+const (
+	sessionCookies = "cookies"
+)
+
+// configureHTTP sets up the HTTP client for the server,
+// which an enterprise may put behind an SSO proxy or require client certificates for.
+func configureHTTP() error {
+	cfg, err := loadConfigValues()
+	if err != nil {
+		return err
+	}
+	server, err := url.Parse(cfg.Get(serverRootKey))
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", serverRootKey, err)
+	}
+	// Don't follow the server's redirects to other hosts, such as an identity provider's login page:
+	// startResponse turns them into a re-login.
+	http.DefaultClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if via[0].URL.Host == server.Host && req.URL.Host != server.Host {
+			return http.ErrUseLastResponse
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	_, err = authHeaders(cfg)
+	if err != nil {
+		// Not fatal, so that merde config can fix it.
+		ui.Warn("%v", err)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	err = configureDial(cfg, server, transport)
+	if err != nil {
+		ui.Warn("%v", err)
+	}
+	http.DefaultClient.Transport = configureTLS(cfg, server, transport)
+	if cfg.Get(sessionKey) == sessionCookies {
+		jar, err := loadSessionJar(cfg, server)
+		if err != nil {
+			return err
+		}
+		http.DefaultClient.Jar = jar
+	}
+	return nil
+}
+
+// authHeaders returns the extra headers configured for an SSO proxy, as name-value pairs.
+func authHeaders(cfg *Config) ([][2]string, error) {
+	var headers [][2]string
+	for _, h := range strings.Split(cfg.Get(authHeadersKey), ";") {
+		if strings.TrimSpace(h) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(h, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid %s entry %q; want Name: value", authHeadersKey, strings.TrimSpace(h))
+		}
+		headers = append(headers, [2]string{strings.TrimSpace(name), strings.TrimSpace(value)})
+	}
+	return headers, nil
+}
+
+// A sessionJar is a cookie jar that keeps the server's session cookies across runs.
+// With config encryption on, it encrypts the cookies' values as it does the config's secrets.
+type sessionJar struct {
+	*cookiejar.Jar
+	cfg    *Config
+	path   string
+	server *url.URL
+}
+
+// A savedCookie is a session cookie as stored on disk.
+type savedCookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// cookieSecret names the value of the session cookie name, to encrypt it.
+func cookieSecret(name string) string {
+	return "cookie:" + name
+}
+
+// cookiesPath returns the file that keeps session cookies.
+func cookiesPath(cfg *Config) string {
+	return filepath.Join(filepath.Dir(cfg.path), "cookies.json")
+}
+
+// loadSessionJar loads the session cookies saved for server.
+// A damaged cookies file only costs a new login, so it is not an error.
+func loadSessionJar(cfg *Config, server *url.URL) (*sessionJar, error) {
+	inner, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	jar := &sessionJar{Jar: inner, cfg: cfg, path: cookiesPath(cfg), server: server}
+	if cfg.Values[encryptionKey] != "" {
+		// Unlock the key now, on the main goroutine, rather than when the server first sets a cookie.
+		_, err = cfg.aead()
+		if err != nil {
+			return nil, err
+		}
+	}
+	data, err := os.ReadFile(jar.path)
+	if os.IsNotExist(err) {
+		return jar, nil
+	}
+	var saved []savedCookie
+	if err == nil {
+		err = json.Unmarshal(data, &saved)
+	}
+	if err != nil {
+		ui.Warn("ignoring saved session: %v", err)
+		return jar, nil
+	}
+	var cookies []*http.Cookie
+	for _, c := range saved {
+		value := c.Value
+		if strings.HasPrefix(value, encryptedPrefix) {
+			value, err = cfg.decrypt(cookieSecret(c.Name), value)
+			if err != nil {
+				ui.Warn("ignoring saved session: %v", err)
+				return jar, nil
+			}
+		}
+		cookies = append(cookies, &http.Cookie{Name: c.Name, Value: value, Path: "/"})
+	}
+	inner.SetCookies(server, cookies)
+	return jar, nil
+}
+
+// SetCookies implements http.CookieJar, saving the server's cookies whenever they change.
+func (j *sessionJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.Jar.SetCookies(u, cookies)
+	if u.Host == j.server.Host {
+		err := j.save()
+		if err != nil {
+			ui.Warn("cannot save session cookies: %v", err)
+		}
+	}
+}
+
+func (j *sessionJar) save() error {
+	var saved []savedCookie
+	for _, c := range j.Jar.Cookies(j.server) {
+		value, err := j.cfg.seal(cookieSecret(c.Name), c.Value)
+		if err != nil {
+			return err
+		}
+		saved = append(saved, savedCookie{Name: c.Name, Value: value})
+	}
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(j.path), 0o700)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(j.path, data, 0o600)
+}
+
+// An ssoError reports that an SSO proxy in front of the server wants the user to log in.
+type ssoError struct {
+	loginURL string
+}
+
+func (e *ssoError) Error() string {
+	return fmt.Sprintf("your SSO session has expired or is missing; log in at %s, then run merde again interactively to store the session", e.loginURL)
+}
+
+// identityProviders are the hosts of common identity providers, whose login pages an SSO proxy redirects to.
+var identityProviders = []string{
+	"okta.com", "oktapreview.com", "okta-emea.com",
+	"login.microsoftonline.com", "login.microsoft.com", "login.windows.net",
+	"accounts.google.com",
+	"auth0.com",
+	"onelogin.com",
+	"pingidentity.com", "pingone.com",
+	"duosecurity.com",
+	"jumpcloud.com",
+	"cloudflareaccess.com",
+}
+
+// isIdentityProvider reports whether u is on an identity provider's host, or on that of the sso_login page.
+func isIdentityProvider(cfg *Config, u *url.URL) bool {
+	host := u.Hostname()
+	if login, err := url.Parse(cfg.Get(ssoLoginKey)); err == nil && login.Host != "" && login.Hostname() == host {
+		return true
+	}
+	for _, idp := range identityProviders {
+		if host == idp || strings.HasSuffix(host, "."+idp) {
+			return true
+		}
+	}
+	return false
+}
+
+// ssoChallenge returns the error for resp, a response to req that is an SSO proxy's challenge
+// rather than the server's answer, or nil if it is not one:
+// a 401 or redirect towards an identity provider, or, with sso_login set, any 401, redirect to another host,
+// or HTML page. Without sso_login, a server's own HTML page, such as an error page, is not taken for a login.
+func ssoChallenge(cfg *Config, req *http.Request, resp *http.Response) *ssoError {
+	loginURL := cfg.Get(ssoLoginKey)
+	loc, err := resp.Location()
+	redirect := err == nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode >= 300 && resp.StatusCode < 400)
+	if redirect && isIdentityProvider(cfg, loc) {
+		return &ssoError{loginURL: cmp.Or(loginURL, loc.String())}
+	}
+	if loginURL == "" {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized && (redirect || mediaType == "text/html"):
+	case redirect:
+	case resp.StatusCode == http.StatusOK && mediaType == "text/html":
+	default:
+		return nil
+	}
+	return &ssoError{loginURL: loginURL}
+}
+
+// ssoLogin walks the user through logging in to the SSO proxy again,
+// and reports whether the session can now be retried.
+func ssoLogin(cfg *Config, e *ssoError) (bool, error) {
+	jar, ok := http.DefaultClient.Jar.(*sessionJar)
+	if !interactive {
+		return false, e
+	}
+	fmt.Fprintf(os.Stderr, "Your organization's SSO session has expired or is missing.\n")
+	fmt.Fprintf(os.Stderr, "Open this page in your browser and log in:\n\n  %s\n\n", e.loginURL)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Then update the headers merde sends with: merde config %s 'Name: value'\n", authHeadersKey)
+		fmt.Fprintf(os.Stderr, "or keep browser cookies with: merde config %s %s\n", sessionKey, sessionCookies)
+		return false, e
+	}
+	fmt.Fprintf(os.Stderr, "Then copy the Cookie header your browser sends to %s, from its developer tools.\n", jar.server.Host)
+	header, err := promptSecret("Cookie")
+	if err != nil {
+		return false, err
+	}
+	cookies, err := http.ParseCookie(header)
+	if err != nil {
+		return false, fmt.Errorf("invalid cookies: %w", err)
+	}
+	jar.SetCookies(jar.server, cookies)
+	fmt.Fprintf(os.Stderr, "session stored in %s\n", jar.path)
+	return true, nil
+}
+
+// retryable returns a copy of req that can be sent again, or nil if its body cannot be replayed.
+func retryable(req *http.Request) *http.Request {
+	if req.Body != nil && req.GetBody == nil {
+		return nil
+	}
+	again := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil
+		}
+		again.Body = body
+	}
+	return again
+}
+
+// discard drains and closes body, so that its connection can be reused.
+func discard(body io.ReadCloser) {
+	io.Copy(io.Discard, io.LimitReader(body, 1<<20))
+	body.Close()
+}
@@ -0,0 +1,55 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSSOChallenge(t *testing.T) {
+	req, err := http.NewRequest("GET", "https://merde.example.com/cli/root", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		ssoLogin    string
+		status      int
+		location    string
+		contentType string
+		want        string // login URL, or "" for no challenge
+	}{
+		{status: 200, contentType: "text/html; charset=utf-8"},
+		{status: 401, contentType: "text/html"},
+		{status: 302, location: "https://cdn.example.net/moved"},
+		{status: 302, location: "https://acme.okta.com/app/sso", want: "https://acme.okta.com/app/sso"},
+		{status: 401, location: "https://login.microsoftonline.com/common", want: "https://login.microsoftonline.com/common"},
+		{status: 302, location: "https://notokta.com/login"},
+		{ssoLogin: "https://sso.acme.com/", status: 302, location: "https://sso.acme.com/start", want: "https://sso.acme.com/"},
+		{ssoLogin: "https://sso.acme.com/", status: 200, contentType: "text/html", want: "https://sso.acme.com/"},
+		{ssoLogin: "https://sso.acme.com/", status: 401, contentType: "text/html", want: "https://sso.acme.com/"},
+		{ssoLogin: "https://sso.acme.com/", status: 200, contentType: "application/json"},
+		{ssoLogin: "https://sso.acme.com/", status: 500, contentType: "text/html"},
+	}
+	for _, tt := range tests {
+		t.Setenv(configEnv(ssoLoginKey), "")
+		cfg := &Config{Values: map[string]string{ssoLoginKey: tt.ssoLogin}}
+		resp := &http.Response{StatusCode: tt.status, Header: make(http.Header), Request: req}
+		if tt.location != "" {
+			resp.Header.Set("Location", tt.location)
+		}
+		if tt.contentType != "" {
+			resp.Header.Set("Content-Type", tt.contentType)
+		}
+		got := ""
+		if e := ssoChallenge(cfg, req, resp); e != nil {
+			got = e.loginURL
+		}
+		if got != tt.want {
+			t.Errorf("sso_login=%q %d Location=%q Content-Type=%q: login URL %q; want %q",
+				tt.ssoLogin, tt.status, tt.location, tt.contentType, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// signatureContentType is the content type of a response part that signs the part after it.
+	signatureContentType = "application/x-merde-signature"
+	// endContentType is the content type of the empty, signed part that ends a signed response.
+	endContentType = "application/x-merde-end"
+	// serverKeyTOFU as server_key pins the first signing key the server presents.
+	serverKeyTOFU = "tofu"
+)
+
+// A partVerifier checks the server's signatures over the parts of one response,
+// so that a proxy that terminates TLS cannot alter them undetected.
+// Each part is preceded by a signature part whose body is a partSignature.
+// The server signs, with Ed25519, the message
+//
+//	merde-part-v1 NUL nonce NUL index NUL content type NUL body
+//
+// where nonce is the Merde-Signature-Nonce request header and index counts the signed parts from 0,
+// so that parts cannot be replayed from another response, reordered, or dropped from the middle.
+// The last signed part is an empty application/x-merde-end part, so that the response cannot be cut short
+// after any part: its index is the number of parts before it.
+type partVerifier struct {
+	cfg   *Config
+	key   ed25519.PublicKey // pinned key; nil if pinning on first use
+	nonce string
+	next  []byte // signature over the next part
+	index int
+	ended bool // whether the signed end part has been received
+}
+
+// A partSignature is the body of a signature part.
+type partSignature struct {
+	Key       string `json:"key"`       // base64 Ed25519 public key
+	Signature string `json:"signature"` // base64 signature
+}
+
+// newPartVerifier returns a verifier for the response to req,
+// asking the server to sign it, or nil if server_key is unset.
+func newPartVerifier(cfg *Config, req *http.Request) (*partVerifier, error) {
+	pinned := cfg.Get(serverKeyKey)
+	if pinned == "" {
+		return nil, nil
+	}
+	v := &partVerifier{cfg: cfg}
+	if pinned != serverKeyTOFU {
+		key, err := base64.StdEncoding.DecodeString(pinned)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%s must be a base64 Ed25519 public key or %q", serverKeyKey, serverKeyTOFU)
+		}
+		v.key = key
+	}
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	v.nonce = base64.StdEncoding.EncodeToString(nonce)
+	req.Header.Set("Merde-Signature-Nonce", v.nonce)
+	return v, nil
+}
+
+// record reads a signature part, which signs the part after it.
+// On first use of server_key=tofu, it pins the key the server presents.
+// Without server_key, signatures are ignored.
+func (v *partVerifier) record(body io.Reader) error {
+	if v == nil {
+		return nil
+	}
+	var ps partSignature
+	err := json.NewDecoder(io.LimitReader(body, 4096)).Decode(&ps)
+	if err != nil {
+		return fmt.Errorf("decoding response signature: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(ps.Key)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("the server presented an invalid signing key %q", ps.Key)
+	}
+	if v.key == nil {
+		err := v.cfg.Update(serverKeyKey, ps.Key)
+		if err != nil {
+			return err
+		}
+		ui.Status("pinned the server's signing key %s", ps.Key)
+		v.key = key
+	}
+	if !bytes.Equal(key, v.key) {
+		return fmt.Errorf("the server signed its response with key %s, not the pinned %s; if the server's key really changed, run: merde config %s %s", ps.Key, base64.StdEncoding.EncodeToString(v.key), serverKeyKey, serverKeyTOFU)
+	}
+	v.next, err = base64.StdEncoding.DecodeString(ps.Signature)
+	if err != nil {
+		return fmt.Errorf("decoding response signature: %w", err)
+	}
+	return nil
+}
+
+// verify checks the signature over a part, reading it into memory, and returns the part's body.
+func (v *partVerifier) verify(contentType string, body io.Reader) (io.Reader, error) {
+	if v == nil {
+		return body, nil
+	}
+	if v.ended {
+		return nil, fmt.Errorf("the server sent a %s part after the end of its signed response", contentType)
+	}
+	if v.next == nil {
+		return nil, fmt.Errorf("the server sent an unsigned %s part", contentType)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	msg := new(bytes.Buffer)
+	for _, field := range []string{"merde-part-v1", v.nonce, strconv.Itoa(v.index), contentType} {
+		msg.WriteString(field)
+		msg.WriteByte(0)
+	}
+	msg.Write(data)
+	if !ed25519.Verify(v.key, msg.Bytes(), v.next) {
+		return nil, fmt.Errorf("the signature over %s part %d does not verify: the response was altered in transit", contentType, v.index)
+	}
+	v.next = nil
+	v.index++
+	v.ended = contentType == endContentType
+	return bytes.NewReader(data), nil
+}
+
+// finish checks, once the response is over, that it ended with the signed end part.
+func (v *partVerifier) finish() error {
+	if v == nil || v.ended {
+		return nil
+	}
+	return errors.New("the response ended before the server's signed end part: it was cut short in transit")
+}
@@ -0,0 +1,61 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import "regexp"
+
+// sourcehutRepoRx extracts owner and repo from a sourcehut remote URL,
+// e.g. git@git.sr.ht:~owner/repo or https://git.sr.ht/~owner/repo.
+var sourcehutRepoRx = regexp.MustCompile(`git\.sr\.ht[:/](~[^/]+)/([^/]+?)/?$`)
+
+// A sourcehutForge is a repository on sourcehut.
+// Sourcehut takes patches by email rather than pull requests,
+// so merde only recognizes its remotes.
+type sourcehutForge struct {
+	noPullRequests
+	owner, repo string
+}
+
+func matchSourcehut(cfg *Config, url string) forge {
+	m := sourcehutRepoRx.FindStringSubmatch(url)
+	if m == nil {
+		return nil
+	}
+	return &sourcehutForge{
+		noPullRequests: noPullRequests{why: "sourcehut has no pull requests; send patches with git send-email"},
+		owner:          m[1],
+		repo:           m[2],
+	}
+}
+
+func (s *sourcehutForge) String() string {
+	return s.owner + "/" + s.repo
+}
+
+// gitlabRepoRx extracts the project path from a GitLab remote URL,
+// e.g. git@gitlab.com:group/subgroup/repo.git or https://gitlab.com/group/repo.
+var gitlabRepoRx = regexp.MustCompile(`gitlab\.com[:/](.+?)(?:\.git)?/?$`)
+
+// A gitlabForge is a repository on GitLab.
+// Merde recognizes its remotes but does not yet use its merge requests.
+type gitlabForge struct {
+	noPullRequests
+	project string
+}
+
+func matchGitLab(cfg *Config, url string) forge {
+	m := gitlabRepoRx.FindStringSubmatch(url)
+	if m == nil {
+		return nil
+	}
+	return &gitlabForge{
+		noPullRequests: noPullRequests{why: "merde does not support GitLab merge requests yet"},
+		project:        m[1],
+	}
+}
+
+func (g *gitlabForge) String() string {
+	return g.project
+}
@@ -0,0 +1,194 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+)
+
+// A splitGroup is a set of conflicting paths resolved together in one commit by merde split.
+type splitGroup struct {
+	name  string
+	paths []string
+}
+
+// groupByDir groups paths by their first depth directories.
+// Paths with fewer directories are grouped by their full directory; top-level paths are grouped as "top level".
+func groupByDir(paths []string, depth int) []*splitGroup {
+	groups := make(map[string]*splitGroup)
+	for _, p := range paths {
+		dir := path.Dir(p)
+		if parts := strings.Split(dir, "/"); dir != "." && len(parts) > depth {
+			dir = strings.Join(parts[:depth], "/")
+		}
+		if dir == "." {
+			dir = "top level"
+		}
+		g, ok := groups[dir]
+		if !ok {
+			g = &splitGroup{name: dir}
+			groups[dir] = g
+		}
+		g.paths = append(g.paths, p)
+	}
+	return slices.SortedFunc(maps.Values(groups), func(a, b *splitGroup) int { return cmp.Compare(a.name, b.name) })
+}
+
+// groupByOwner groups paths by their CODEOWNERS owners.
+func groupByOwner(paths []string, co codeowners) []*splitGroup {
+	groups := make(map[string]*splitGroup)
+	for _, p := range paths {
+		name := "no owner"
+		if owners := co.owners(p); len(owners) > 0 {
+			name = strings.Join(owners, " ")
+		}
+		g, ok := groups[name]
+		if !ok {
+			g = &splitGroup{name: name}
+			groups[name] = g
+		}
+		g.paths = append(g.paths, p)
+	}
+	return slices.SortedFunc(maps.Values(groups), func(a, b *splitGroup) int { return cmp.Compare(a.name, b.name) })
+}
+
+// splitOperation rewrites the result of the merge op as a chain of commits:
+// first the merge itself with every conflicting path left at the topic's version,
+// then one commit per group applying that group's resolutions.
+// Groups are by directory or by CODEOWNERS owner, according to --by.
+// The chain ends in the same tree as the original result, and replaces it as op's result.
+func splitOperation(ctx context.Context, cfg *Config, op *operation) error {
+	r := op.Report
+	result := op.result()
+	if result == "" {
+		return fmt.Errorf("operation %s has no result to split", op.ID)
+	}
+	var conflicted []string
+	for _, c := range r.Conflicts {
+		if c.Kind == "content" {
+			conflicted = append(conflicted, c.Path)
+		}
+	}
+	resolved, err := cfg.Git.PathBlobs(ctx, result, conflicted)
+	if err != nil {
+		return err
+	}
+	topic, err := cfg.Git.PathBlobs(ctx, r.TopicSHA, conflicted)
+	if err != nil {
+		return err
+	}
+	// Only paths present on both sides of the chain, and changed by the resolution, make up groups.
+	var changed []string
+	unresolved := make(map[string]string)
+	for _, p := range conflicted {
+		if resolved[p] != "" && topic[p] != "" && resolved[p] != topic[p] {
+			changed = append(changed, p)
+			unresolved[p] = topic[p]
+		}
+	}
+	if len(changed) == 0 {
+		fmt.Printf("no resolutions to split\n")
+		return nil
+	}
+	var groups []*splitGroup
+	switch flagSplitBy {
+	case "dir":
+		groups = groupByDir(changed, flagSplitDepth)
+	case "owner":
+		groups = groupByOwner(changed, loadCodeowners(ctx, cfg, r.MainSHA))
+	}
+
+	// The merge commit, with the conflicting paths as they were on the topic branch.
+	tip, err := cfg.Git.ReplaceBlobs(ctx, result, unresolved)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s  merge, with %d conflicting paths left as on %s\n", tip[:12], len(changed), r.TopicRef)
+	for i, g := range groups {
+		blobs := make(map[string]string)
+		for _, p := range g.paths {
+			blobs[p] = resolved[p]
+		}
+		tree, err := cfg.Git.ReplaceBlobsTree(ctx, tip, blobs)
+		if err != nil {
+			return err
+		}
+		subject := "Resolve merge conflicts in " + g.name
+		if flagSplitBy == "owner" {
+			subject = "Resolve merge conflicts owned by " + g.name
+		}
+		message := fmt.Sprintf("%s\n\nResolved with merde (group %d of %d):\n\n", subject, i+1, len(groups))
+		for _, p := range g.paths {
+			message += "- " + p + "\n"
+		}
+		tip, err = cfg.Git.CommitTree(ctx, tree, message, tip)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s  %s: %d paths\n", tip[:12], g.name, len(g.paths))
+		for _, p := range g.paths {
+			fmt.Printf("    %s", p)
+			for _, res := range r.Resolutions {
+				if res.Path == p && res.Explanation != "" {
+					fmt.Printf(": %s", res.Explanation)
+				}
+			}
+			fmt.Printf("\n")
+		}
+	}
+	want, err := cfg.Git.Tree(ctx, result)
+	if err != nil {
+		return err
+	}
+	got, err := cfg.Git.Tree(ctx, tip)
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return fmt.Errorf("internal error: split result %s does not match %s", tip, result)
+	}
+
+	ref := r.Refs[len(r.Refs)-1].Ref
+	err = cfg.Git.UpdateRef(ctx, ref, tip, result)
+	if err != nil {
+		return err
+	}
+	r.Refs = append(r.Refs, reportRef{Ref: ref, SHA: tip})
+	r.Undo = append([]string{fmt.Sprintf("git update-ref %s %s %s", ref, result, tip)}, r.Undo...)
+	return writeOperation(ctx, cfg, op)
+}
+
+func doSplit(ctx context.Context, args []string) error {
+	if flagSplitDepth < 1 {
+		return usageErrorf("--depth must be at least 1")
+	}
+	if flagSplitBy != "dir" && flagSplitBy != "owner" {
+		return usageErrorf("--by must be dir or owner")
+	}
+	cfg, err := LoadDefault(ctx)
+	if err != nil {
+		return err
+	}
+	err = requireCleanGitStatus(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	mainRef, topicRef, err := mainTopic(ctx, cfg, "merge", args)
+	if err != nil {
+		return err
+	}
+	ui.Status("plan: merge %s into %s, split into one commit per %s", mainRef, topicRef, flagSplitBy)
+	op, err := deconflict(ctx, cfg, "merge", mainRef, topicRef)
+	if err != nil {
+		return err
+	}
+	return splitOperation(ctx, cfg, op)
+}
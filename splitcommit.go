@@ -0,0 +1,117 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// findHeavyCommit finds the topic commit of a rebase that touches most of the conflicting paths, if any.
+// With --split-commits, the server is asked to split it into smaller logical commits as it resolves;
+// otherwise the user gets a hint.
+func findHeavyCommit(ctx context.Context, cfg *Config, info *deconflictRequestInfo) error {
+	if info.verb != "rebase" {
+		return nil
+	}
+	conflicts := unresolved(info)
+	if len(conflicts) < 3 {
+		return nil
+	}
+	commits, err := cfg.Git.CommitsInRange(ctx, info.baseSHA+".."+info.topicSHA)
+	if err != nil {
+		return err
+	}
+	if len(commits) < 2 && !flagSplitCommits {
+		return nil
+	}
+	var heavy string
+	var most int
+	for _, c := range commits {
+		if slices.Contains(info.landed, c) {
+			continue
+		}
+		changed, err := cfg.Git.ChangedPaths(ctx, c+"^", c)
+		if err != nil {
+			return err
+		}
+		n := 0
+		for _, p := range conflicts {
+			if _, ok := changed[p]; ok {
+				n++
+			}
+		}
+		if n > most {
+			heavy, most = c, n
+		}
+	}
+	if most*2 < len(conflicts) {
+		return nil
+	}
+	if !flagSplitCommits {
+		ui.Status("hint: %s touches %d of %d conflicting paths; merde rebase --split-commits asks merde to split it into smaller commits", heavy[:12], most, len(conflicts))
+		return nil
+	}
+	ui.Status("asking merde to split %s, which touches %d of %d conflicting paths", heavy[:12], most, len(conflicts))
+	info.split = heavy
+	return nil
+}
+
+// approveSplit shows the history that resulted from splitting a commit and asks the user to approve it.
+// If they do not, it undoes the refs the operation created or moved.
+// When the user cannot be asked, the result stands; it is not applied until merde adopt.
+func approveSplit(ctx context.Context, cfg *Config, info *deconflictRequestInfo) error {
+	if info.split == "" || len(info.createdRefs) == 0 {
+		return nil
+	}
+	result := info.createdRefs[len(info.createdRefs)-1].sha
+	log, err := cfg.Git.CommitsInRange(ctx, info.mainSHA+".."+result)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("merde split %s; the rebased history is:\n", info.split[:12])
+	for _, c := range log {
+		subject, err := cfg.Git.CommitMessage(ctx, c)
+		if err != nil {
+			return err
+		}
+		subject, _, _ = strings.Cut(subject, "\n")
+		fmt.Printf("  %s %s\n", c[:12], subject)
+	}
+	yes, err := confirm(cfg, askSplitHistory, "keep this history?")
+	if errors.Is(err, errUnattended) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if yes {
+		return nil
+	}
+	// Put each ref back the way it was before the operation.
+	restored := make(map[string]bool)
+	for _, cr := range info.createdRefs {
+		if restored[cr.ref] {
+			continue
+		}
+		restored[cr.ref] = true
+		current, err := cfg.Git.ResolveRef(ctx, cr.ref)
+		if err != nil {
+			continue
+		}
+		if cr.old != "" {
+			err = cfg.Git.SetRef(ctx, cr.ref, cr.old)
+		} else {
+			err = cfg.Git.DeleteRef(ctx, cr.ref, current)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("split rejected; run merde rebase without --split-commits to keep %s whole", info.split[:12])
+}
@@ -0,0 +1,255 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/carlmjohnson/requests"
+)
+
+// Telemetry settings.
+const (
+	telemetryOn  = "on"
+	telemetryOff = "off"
+)
+
+// A metric is one anonymous usage record: coarse buckets only, never names, paths, or contents.
+type metric struct {
+	Command  string `json:"command"`
+	Duration string `json:"duration"`
+	PackSize string `json:"pack_size,omitempty"`
+	Result   string `json:"result"`
+	Version  string `json:"version"`
+	OS       string `json:"os"`
+	Arch     string `json:"arch"`
+}
+
+// telemetryTimeout bounds sending the queued metrics, which happens alongside the command.
+const telemetryTimeout = time.Second
+
+var (
+	telemetryFlush sync.WaitGroup // the background send of metrics queued by earlier commands
+
+	packSizeMu sync.Mutex
+	packSize   = -1 // size of the pack built by this command, or -1 if none
+)
+
+// recordPackSize records the size of the pack the command built, for telemetry.
+func recordPackSize(n int) {
+	packSizeMu.Lock()
+	defer packSizeMu.Unlock()
+	packSize = n
+}
+
+// bucket returns the label of the first of bounds that v is below, or the last label.
+func bucket[T int | time.Duration](v T, bounds []T, labels []string) string {
+	for i, b := range bounds {
+		if v < b {
+			return labels[i]
+		}
+	}
+	return labels[len(labels)-1]
+}
+
+// resultClass classifies the outcome of a command by err.
+func resultClass(err error) string {
+	var ue *usageError
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.As(err, &ue):
+		return "usage"
+	case isNetworkError(err):
+		return "network"
+	default:
+		return "error"
+	}
+}
+
+// commandName returns the name of the subcommand selected by args, or "" if there is none.
+// Only known command names are ever reported.
+func commandName(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	for _, cmd := range rootCommand.Subcommands {
+		if cmd.Name == args[0] {
+			return cmd.Name
+		}
+	}
+	return ""
+}
+
+// telemetryPath returns the file queuing metrics not yet sent.
+func telemetryPath(cfg *Config) string {
+	return filepath.Join(filepath.Dir(cfg.path), "telemetry.jsonl")
+}
+
+// startTelemetry starts sending the metrics queued by earlier commands in the background, if the user opted in,
+// so that no command waits on the telemetry server.
+func startTelemetry() {
+	path, err := DefaultPath()
+	if err != nil {
+		return
+	}
+	cfg, err := loadValues(path)
+	if err != nil || cfg.Get(telemetryKey) != telemetryOn {
+		return
+	}
+	telemetryFlush.Add(1)
+	go func() {
+		defer telemetryFlush.Done()
+		flushTelemetry(cfg)
+	}()
+}
+
+// recordTelemetry queues the outcome of the command run with args, if the user opted in.
+// The next command sends it.
+// The first time a merge or rebase finishes interactively, it asks the user whether to opt in.
+// Telemetry is best effort: it never fails the command.
+func recordTelemetry(args []string, start time.Time, runErr error) {
+	command := commandName(args)
+	if command == "" || command == "telemetry" {
+		return
+	}
+	path, err := DefaultPath()
+	if err != nil {
+		return
+	}
+	cfg, err := loadValues(path)
+	if err != nil {
+		return
+	}
+	// Consent is never assumed, and automation that answers with --yes or --no is not asked.
+	if cfg.Get(telemetryKey) == "" && (command == "merge" || command == "rebase") && isTerminal(os.Stdin) && !flagYes && !flagNo {
+		askTelemetry(cfg)
+	}
+	if cfg.Get(telemetryKey) != telemetryOn {
+		return
+	}
+	m := &metric{
+		Command:  command,
+		Duration: bucket(time.Since(start), []time.Duration{time.Second, 10 * time.Second, time.Minute, 10 * time.Minute}, []string{"<1s", "<10s", "<1m", "<10m", ">=10m"}),
+		Result:   resultClass(runErr),
+		Version:  version,
+		OS:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+	}
+	packSizeMu.Lock()
+	if packSize >= 0 {
+		m.PackSize = bucket(packSize, []int{1 << 20, 10 << 20, 100 << 20}, []string{"<1MB", "<10MB", "<100MB", ">=100MB"})
+	}
+	packSizeMu.Unlock()
+	data, err := json.Marshal(m)
+	if err != nil {
+		return
+	}
+	// The background send removes the queue once it is delivered; let it finish first,
+	// which it does within telemetryTimeout of the command's start.
+	telemetryFlush.Wait()
+	f, err := os.OpenFile(telemetryPath(cfg), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(f, "%s\n", data)
+	f.Close()
+}
+
+// askTelemetry asks the user once whether to send anonymous usage metrics, and records the answer.
+func askTelemetry(cfg *Config) {
+	fmt.Printf("\nmerde can send anonymous usage metrics (command, coarse duration and pack size, success or failure) to help improve it.\n")
+	fmt.Printf("You can change your mind any time with: merde telemetry on|off\n")
+	answer, err := prompt("send anonymous usage metrics? [y/n]", "y", "n")
+	if err != nil {
+		return
+	}
+	setting := telemetryOff
+	if answer == "y" {
+		setting = telemetryOn
+	}
+	cfg.Update(telemetryKey, setting)
+}
+
+// queuedMetrics returns the metrics waiting to be sent.
+func queuedMetrics(cfg *Config) []json.RawMessage {
+	data, err := os.ReadFile(telemetryPath(cfg))
+	if err != nil {
+		return nil
+	}
+	var metrics []json.RawMessage
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if json.Valid(scanner.Bytes()) {
+			metrics = append(metrics, json.RawMessage(bytes.Clone(scanner.Bytes())))
+		}
+	}
+	return metrics
+}
+
+// flushTelemetry sends the queued metrics, keeping them queued if the server cannot be reached within telemetryTimeout.
+// It sends no token or client details beyond those in the metrics, so the metrics stay anonymous.
+func flushTelemetry(cfg *Config) {
+	metrics := queuedMetrics(cfg)
+	if len(metrics) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), telemetryTimeout)
+	defer cancel()
+	err := requests.URL(cfg.Get(serverRootKey)).
+		Path("/cli/telemetry").
+		BodyJSON(metrics).
+		Fetch(ctx)
+	if err != nil {
+		return
+	}
+	os.Remove(telemetryPath(cfg))
+}
+
+func doTelemetry(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return usageErrorf("merde telemetry takes exactly one of status, on, or off")
+	}
+	path, err := DefaultPath()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadValues(path)
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "status":
+		setting := cfg.Get(telemetryKey)
+		if setting == "" {
+			setting = telemetryOff + " (not yet asked)"
+		}
+		fmt.Printf("telemetry: %s\n", setting)
+		fmt.Printf("queued metrics: %d\n", len(queuedMetrics(cfg)))
+		return nil
+	case telemetryOn, telemetryOff:
+		err := cfg.Update(telemetryKey, args[0])
+		if err != nil {
+			return err
+		}
+		if args[0] == telemetryOff {
+			os.Remove(telemetryPath(cfg))
+		}
+		fmt.Printf("telemetry: %s\n", args[0])
+		return nil
+	default:
+		return usageErrorf("unknown telemetry setting %q", args[0])
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// gitTimingsShown is how many of the slowest git commands --git-timings lists.
+const gitTimingsShown = 10
+
+// A gitTiming records one git command merde ran, for --git-timings.
+type gitTiming struct {
+	args    []string
+	elapsed time.Duration
+}
+
+var (
+	gitTimingsMu sync.Mutex
+	gitTimings   []gitTiming
+)
+
+// recordGitTiming records a finished git command, for --git-timings.
+func recordGitTiming(_ string, args []string, elapsed time.Duration) {
+	gitTimingsMu.Lock()
+	defer gitTimingsMu.Unlock()
+	gitTimings = append(gitTimings, gitTiming{args: args, elapsed: elapsed})
+}
+
+// printGitTimings writes the --git-timings report to stderr:
+// the slowest git commands, then the total time by git subcommand.
+func printGitTimings() {
+	gitTimingsMu.Lock()
+	defer gitTimingsMu.Unlock()
+	var total time.Duration
+	bySub := make(map[string]time.Duration)
+	counts := make(map[string]int)
+	for _, t := range gitTimings {
+		total += t.elapsed
+		sub := "?"
+		if len(t.args) > 0 {
+			sub = t.args[0]
+		}
+		bySub[sub] += t.elapsed
+		counts[sub]++
+	}
+	w := os.Stderr
+	fmt.Fprintf(w, "\ngit timings: %d commands, %v in total\n", len(gitTimings), total.Round(time.Millisecond))
+	if len(gitTimings) == 0 {
+		return
+	}
+	slowest := slices.SortedFunc(slices.Values(gitTimings), func(a, b gitTiming) int {
+		return cmp.Compare(b.elapsed, a.elapsed)
+	})
+	fmt.Fprintf(w, "slowest:\n")
+	for _, t := range slowest[:min(gitTimingsShown, len(slowest))] {
+		command := "git " + strings.Join(t.args, " ")
+		if len(command) > 72 {
+			command = command[:69] + "..."
+		}
+		fmt.Fprintf(w, "  %9v  %s\n", t.elapsed.Round(time.Millisecond), command)
+	}
+	subs := slices.SortedFunc(maps.Keys(bySub), func(a, b string) int {
+		return cmp.Or(cmp.Compare(bySub[b], bySub[a]), cmp.Compare(a, b))
+	})
+	fmt.Fprintf(w, "by git command:\n")
+	for _, sub := range subs {
+		fmt.Fprintf(w, "  %9v  %4d× %s\n", bySub[sub].Round(time.Millisecond), counts[sub], sub)
+	}
+}
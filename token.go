@@ -0,0 +1,98 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// cliScopes are the token scopes merde needs: resolving conflicts and sharing team memory.
+var cliScopes = []string{"cli", "memory"}
+
+// tokenExpiryWarning is how close to expiry a token must be for merde auth to warn about it.
+const tokenExpiryWarning = 7 * 24 * time.Hour
+
+// tokenInfo describes an API token, as reported by the server.
+type tokenInfo struct {
+	Scopes       []string   `json:"scopes"`
+	Expires      *time.Time `json:"expires"`      // nil if the token does not expire
+	Repositories []string   `json:"repositories"` // remote URLs the token is limited to; empty means any
+}
+
+// fetchTokenInfo asks the server about the configured token.
+func fetchTokenInfo(ctx context.Context, cfg *Config) (*tokenInfo, error) {
+	info := new(tokenInfo)
+	err := baseRequest(cfg).
+		Path("/cli/token").
+		Accept("application/json").
+		ToJSON(info).
+		Fetch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reading token details: %w", err)
+	}
+	return info, nil
+}
+
+// print describes t, warning if it grants more than merde needs or expires soon.
+func (t *tokenInfo) print() {
+	fmt.Printf("scopes: %s\n", strings.Join(t.Scopes, ", "))
+	switch {
+	case t.Expires == nil:
+		fmt.Printf("expires: never\n")
+	default:
+		fmt.Printf("expires: %s (%s)\n", t.Expires.Format(time.DateTime), humanize.Time(*t.Expires))
+	}
+	if len(t.Repositories) == 0 {
+		fmt.Printf("repositories: any\n")
+	} else {
+		fmt.Printf("repositories: %s\n", strings.Join(t.Repositories, ", "))
+	}
+
+	var extra []string
+	for _, s := range t.Scopes {
+		if !slices.Contains(cliScopes, s) {
+			extra = append(extra, s)
+		}
+	}
+	if len(extra) > 0 || len(t.Repositories) == 0 {
+		what := "access to every repository"
+		if len(extra) > 0 {
+			what = "scopes merde does not need (" + strings.Join(extra, ", ") + ")"
+		}
+		ui.Warn("this token grants %s; for a token limited to this repository, run: merde auth --repo", what)
+	}
+	if t.Expires == nil {
+		ui.Warn("this token never expires")
+	} else if time.Until(*t.Expires) < tokenExpiryWarning {
+		ui.Warn("this token expires %s", humanize.Time(*t.Expires))
+	}
+}
+
+// deriveToken asks the server for a token derived from the configured one,
+// limited to the repositories at remotes and the scopes merde needs.
+func deriveToken(ctx context.Context, cfg *Config, remotes []string) (string, error) {
+	var resp struct {
+		Token string `json:"token"`
+	}
+	err := baseRequest(cfg).
+		Path("/cli/token").
+		Accept("application/json").
+		BodyJSON(map[string]any{"scopes": cliScopes, "repositories": remotes}).
+		ToJSON(&resp).
+		Fetch(ctx)
+	if err != nil {
+		return "", fmt.Errorf("deriving a token: %w", err)
+	}
+	if resp.Token == "" {
+		return "", fmt.Errorf("deriving a token: the server returned no token")
+	}
+	return resp.Token, nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// verifyTopology checks that sha, a result sent by the server, exists locally
+// and has the history that info's operation should produce:
+// a merge of exactly the topic and main commits, or a rebase that puts no more than
+// the topic's own commits, none of them merges, on top of main.
+// It refuses results with any other history, so that no ref ever points at it.
+func verifyTopology(ctx context.Context, cfg *Config, info *deconflictRequestInfo, sha string) error {
+	_, err := cfg.Git.ResolveRef(ctx, sha)
+	if err != nil {
+		return fmt.Errorf("server result %s is not a commit in the local repository", sha)
+	}
+	switch info.verb {
+	case "merge":
+		parents, err := cfg.Git.Parents(ctx, sha)
+		if err != nil {
+			return err
+		}
+		want := []string{info.topicSHA, info.mainSHA}
+		if len(parents) != 2 || !slices.Contains(parents, info.topicSHA) || !slices.Contains(parents, info.mainSHA) {
+			return fmt.Errorf("server result %s has parents %v, want %v; refusing it", sha, parents, want)
+		}
+	case "rebase", "revert":
+		rebased, err := cfg.Git.CommitsWithParents(ctx, info.mainSHA, sha)
+		if err != nil {
+			return err
+		}
+		original, err := cfg.Git.CommitsWithParents(ctx, info.baseSHA, info.topicSHA)
+		if err != nil {
+			return err
+		}
+		if len(rebased) > len(original) {
+			return fmt.Errorf("server result %s adds %d commits to %s, but %s has only %d; refusing it",
+				sha, len(rebased), info.mainRef, info.topicRef, len(original))
+		}
+		for _, c := range rebased {
+			if len(c) != 2 {
+				return fmt.Errorf("server result %s contains commit %s with %d parents; refusing it", sha, c[0], len(c)-1)
+			}
+		}
+		if len(rebased) == 0 && sha != info.mainSHA {
+			return fmt.Errorf("server result %s is not based on %s (%s); refusing it", sha, info.mainRef, info.mainSHA)
+		}
+		if len(rebased) > 0 && rebased[len(rebased)-1][1] != info.mainSHA {
+			return fmt.Errorf("server result %s is not based on %s (%s); refusing it", sha, info.mainRef, info.mainSHA)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/websocket"
+)
+
+// Transports for server responses.
+// Multipart is a single streamed response; some proxies buffer it whole,
+// which breaks progress and prompts. Server-sent events and WebSockets survive them.
+const (
+	transportMultipart = "multipart"
+	transportSSE       = "sse" // server-sent events; prompt answers are POSTed
+	transportWS        = "ws"  // WebSocket; prompt answers go back on the socket
+)
+
+// doSSERequest sends req and yields the server-sent events of the response as parts.
+// Each event's name is the part's content type and its data the part's body,
+// base64-encoded for application/octet-stream.
+func doSSERequest(cfg *Config, req *http.Request, v *partVerifier) iter.Seq2[*Response, error] {
+	return func(yield func(*Response, error) bool) {
+		maxPart, maxResponse, err := responseLimits(cfg)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		resp, err := startResponse(cfg, req)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer resp.Body.Close()
+		if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+			yield(nil, fmt.Errorf("unexpected content type: %s", ct))
+			return
+		}
+
+		sc := bufio.NewScanner(&limitedReader{r: resp.Body, n: maxResponse, limit: maxResponseSizeKey})
+		// Events hold whole parts; base64 inflates binary ones by a third.
+		sc.Buffer(nil, int(min(maxPart*4/3+64, 1<<31-1)))
+		var event string
+		data := new(bytes.Buffer)
+		for sc.Scan() {
+			line := sc.Text()
+			switch {
+			case line == "":
+				if event == "" && data.Len() == 0 {
+					continue
+				}
+				var body io.Reader = bytes.NewReader(bytes.TrimSuffix(data.Bytes(), []byte("\n")))
+				if event == "application/octet-stream" {
+					body = base64.NewDecoder(base64.StdEncoding, body)
+				}
+				r, err := decodePart(v, event, body, maxPart)
+				if r != nil || err != nil {
+					if !yield(r, err) || err != nil {
+						return
+					}
+				}
+				event = ""
+				data.Reset()
+			case strings.HasPrefix(line, ":"):
+				// comment, typically a keep-alive
+			case strings.HasPrefix(line, "event:"):
+				event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			case strings.HasPrefix(line, "data:"):
+				data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+				data.WriteByte('\n')
+			}
+		}
+		if err := sc.Err(); err != nil {
+			yield(nil, err)
+			return
+		}
+		if err := v.finish(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// A wsFrame is a WebSocket message, remembering whether it was text or binary.
+type wsFrame struct {
+	data   []byte
+	binary bool
+}
+
+var wsCodec = websocket.Codec{
+	Marshal: func(v any) ([]byte, byte, error) {
+		f := v.(*wsFrame)
+		if f.binary {
+			return f.data, websocket.BinaryFrame, nil
+		}
+		return f.data, websocket.TextFrame, nil
+	},
+	Unmarshal: func(data []byte, payloadType byte, v any) error {
+		f := v.(*wsFrame)
+		f.data, f.binary = data, payloadType == websocket.BinaryFrame
+		return nil
+	},
+}
+
+// dialWS opens the WebSocket described by wscfg, dialing like the HTTP transport does.
+func dialWS(ctx context.Context, wscfg *websocket.Config) (*websocket.Conn, error) {
+	u := wscfg.Location
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"ws": "80", "wss": "443"}[u.Scheme]
+	}
+	conn, err := dialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "wss" {
+		tc := new(tls.Config)
+		if tlsConfig != nil {
+			tc = tlsConfig.Clone()
+		}
+		tc.ServerName = u.Hostname()
+		tlsConn := tls.Client(conn, tc)
+		err = tlsConn.HandshakeContext(ctx)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	ws, err := websocket.NewClient(wscfg, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ws, nil
+}
+
+// doWSRequest sends req over a WebSocket and yields the messages the server sends back as parts.
+// The request body goes up as one binary message.
+// Binary messages from the server are application/octet-stream parts;
+// text messages are a content type, a newline, and the part's body.
+// Prompt answers are sent back as application/x-merde-answer text messages.
+func doWSRequest(cfg *Config, req *http.Request, v *partVerifier) iter.Seq2[*Response, error] {
+	return func(yield func(*Response, error) bool) {
+		maxPart, maxResponse, err := responseLimits(cfg)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		u := *req.URL
+		u.Scheme = strings.Replace(u.Scheme, "http", "ws", 1)
+		wscfg, err := websocket.NewConfig(u.String(), cfg.Get(serverRootKey))
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		wscfg.Header = req.Header.Clone()
+		wscfg.Header.Set("Merde-Method", req.Method)
+		if jar := http.DefaultClient.Jar; jar != nil {
+			for _, c := range jar.Cookies(req.URL) {
+				wscfg.Header.Add("Cookie", c.String())
+			}
+		}
+		ws, err := dialWS(req.Context(), wscfg)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer ws.Close()
+		ws.MaxPayloadBytes = int(min(maxPart, 1<<31-1))
+		stop := context.AfterFunc(req.Context(), func() { ws.Close() })
+		defer stop()
+
+		var body []byte
+		if req.Body != nil {
+			body, err = io.ReadAll(req.Body)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+		err = wsCodec.Send(ws, &wsFrame{data: body, binary: true})
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		remaining := maxResponse
+		for {
+			f := new(wsFrame)
+			err := wsCodec.Receive(ws, f)
+			if errors.Is(err, io.EOF) {
+				if err := v.finish(); err != nil {
+					yield(nil, err)
+				}
+				return
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			remaining -= int64(len(f.data))
+			if remaining < 0 {
+				yield(nil, fmt.Errorf("server response exceeds %s; raise it with: merde config %s <size>", maxResponseSizeKey, maxResponseSizeKey))
+				return
+			}
+			contentType, payload := "application/octet-stream", f.data
+			if !f.binary {
+				ct, rest, _ := bytes.Cut(f.data, []byte("\n"))
+				contentType, payload = string(ct), rest
+			}
+			r, err := decodePart(v, contentType, bytes.NewReader(payload), maxPart)
+			if err == nil && r == nil {
+				continue // signature or end
+			}
+			if err == nil && r.Prompt != nil {
+				id := r.Prompt.ID
+				r.Prompt.reply = func(ctx context.Context, answer string) error {
+					msg, err := json.Marshal(map[string]string{"id": id, "answer": answer})
+					if err != nil {
+						return err
+					}
+					return wsCodec.Send(ws, &wsFrame{data: append([]byte("application/x-merde-answer\n"), msg...)})
+				}
+			}
+			if !yield(r, err) || err != nil {
+				return
+			}
+		}
+	}
+}
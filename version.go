@@ -0,0 +1,241 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/carlmjohnson/requests"
+)
+
+// releaseRepo is the GitHub repository official builds are released from.
+const releaseRepo = "merde-bot/merde-cli"
+
+// printProvenance prints how this binary was built.
+func printProvenance() {
+	fmt.Printf("built by: %s\n", builtBy)
+	fmt.Printf("commit:   %s\n", commit)
+	fmt.Printf("date:     %s\n", date)
+	fmt.Printf("platform: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	fmt.Printf("go:       %s\n", info.GoVersion)
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision", "vcs.time", "vcs.modified", "-tags":
+			fmt.Printf("%s: %s\n", s.Key, s.Value)
+		}
+	}
+}
+
+// A release is a GitHub release, as returned by the releases API.
+type release struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"assets"`
+}
+
+// releaseRequest returns a request builder for the GitHub API of releaseRepo.
+// It uses the configured GitHub token, if any, to avoid rate limits.
+func releaseRequest(cfg *Config) *requests.Builder {
+	rb := requests.URL("https://api.github.com").
+		Accept("application/vnd.github+json").
+		Header("X-GitHub-Api-Version", "2022-11-28")
+	if tok := cfg.Get(githubTokenKey); tok != "" {
+		rb = rb.Bearer(tok)
+	}
+	return rb
+}
+
+// fetchAsset downloads the asset of rel whose name has the given suffix.
+func fetchAsset(ctx context.Context, cfg *Config, rel *release, suffix string) (name string, data []byte, err error) {
+	for _, a := range rel.Assets {
+		if !strings.HasSuffix(a.Name, suffix) {
+			continue
+		}
+		var buf bytes.Buffer
+		err := releaseRequest(cfg).
+			BaseURL(a.URL).
+			Accept("application/octet-stream").
+			ToBytesBuffer(&buf).
+			Fetch(ctx)
+		if err != nil {
+			return "", nil, fmt.Errorf("downloading %s: %w", a.Name, err)
+		}
+		return a.Name, buf.Bytes(), nil
+	}
+	return "", nil, fmt.Errorf("release %s has no %s", rel.TagName, suffix)
+}
+
+// checkVersion reports whether a newer release exists,
+// then checks that the running binary is the one in the official release of its version.
+func checkVersion(ctx context.Context, cfg *Config) error {
+	var latest release
+	err := releaseRequest(cfg).
+		Pathf("/repos/%s/releases/latest", releaseRepo).
+		ToJSON(&latest).
+		Fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("finding the latest release: %w", err)
+	}
+	if latest.TagName == "v"+version {
+		fmt.Printf("merde %s is the latest release\n", version)
+	} else {
+		fmt.Printf("latest release: %s (this is v%s); upgrade with: brew upgrade merde-bot/tap/merde\n", latest.TagName, version)
+	}
+	if version == "dev" {
+		fmt.Println("this is a development build, so there is no release to verify it against")
+		return nil
+	}
+
+	rel := &latest
+	if latest.TagName != "v"+version {
+		rel = new(release)
+		err = releaseRequest(cfg).
+			Pathf("/repos/%s/releases/tags/v%s", releaseRepo, version).
+			ToJSON(rel).
+			Fetch(ctx)
+		if err != nil {
+			return fmt.Errorf("finding release v%s: %w", version, err)
+		}
+	}
+	_, sums, err := fetchAsset(ctx, cfg, rel, "checksums.txt")
+	if err != nil {
+		return err
+	}
+	archiveName, archive, err := fetchAsset(ctx, cfg, rel, "_"+releaseArchiveSuffix())
+	if err != nil {
+		return err
+	}
+	want, err := checksumFor(sums, archiveName)
+	if err != nil {
+		return err
+	}
+	if got := sha256Hex(archive); got != want {
+		return fmt.Errorf("%s does not match the release checksums (got %s, want %s)", archiveName, got, want)
+	}
+	official, err := binaryFromArchive(archiveName, archive)
+	if err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	exe, err = filepath.EvalSymlinks(exe)
+	if err != nil {
+		return err
+	}
+	local, err := os.ReadFile(exe)
+	if err != nil {
+		return err
+	}
+	if sha256Hex(local) != sha256Hex(official) {
+		return fmt.Errorf("%s does not match the official %s release (sha256 %s, want %s); reinstall it from https://github.com/%s/releases", exe, rel.TagName, sha256Hex(local), sha256Hex(official), releaseRepo)
+	}
+	fmt.Printf("%s matches the official %s release (sha256 %s)\n", exe, rel.TagName, sha256Hex(local))
+	return nil
+}
+
+// releaseArchiveSuffix returns the end of the name of the release archive for this platform,
+// following the archive name template in .goreleaser.yaml.
+func releaseArchiveSuffix() string {
+	goos := strings.ToUpper(runtime.GOOS[:1]) + runtime.GOOS[1:]
+	arch := runtime.GOARCH
+	switch arch {
+	case "amd64":
+		arch = "x86_64"
+	case "386":
+		arch = "i386"
+	case "arm":
+		if info, ok := debug.ReadBuildInfo(); ok {
+			for _, s := range info.Settings {
+				if s.Key == "GOARM" {
+					arch += "v" + s.Value
+				}
+			}
+		}
+	}
+	ext := ".tar.gz"
+	if runtime.GOOS == "windows" {
+		ext = ".zip"
+	}
+	return goos + "_" + arch + ext
+}
+
+// checksumFor returns the sha256 that sums, in sha256sum format, lists for name.
+func checksumFor(sums []byte, name string) (string, error) {
+	sc := bufio.NewScanner(bytes.NewReader(sums))
+	for sc.Scan() {
+		sum, file, ok := strings.Cut(sc.Text(), "  ")
+		if ok && file == name {
+			return sum, nil
+		}
+	}
+	return "", fmt.Errorf("the release checksums do not list %s", name)
+}
+
+// binaryFromArchive returns the merde binary in a release archive.
+func binaryFromArchive(name string, archive []byte) ([]byte, error) {
+	bin := "merde"
+	if strings.HasSuffix(name, ".zip") {
+		zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if f.Name != bin+".exe" {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return io.ReadAll(rc)
+		}
+		return nil, fmt.Errorf("%s has no %s.exe", name, bin)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s has no %s", name, bin)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Name == bin {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
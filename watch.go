@@ -0,0 +1,138 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"time"
+
+	"github.com/josharian/xc"
+)
+
+// watchInterval is how often merde watch checks the repository
+// where it cannot have the system tell it of changes to the git dir.
+const watchInterval = time.Second
+
+// pollChanges is the fallback for watchChanges: its channel receives a value straight away and then every watchInterval.
+func pollChanges(ctx context.Context) <-chan struct{} {
+	changes := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case changes <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return changes
+}
+
+// notifyDesktop shows a desktop notification, best effort.
+func notifyDesktop(ctx context.Context, title, message string) {
+	var cmd *xc.Builder
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %q with title %q", message, title)
+		cmd = xc.Command(ctx, "osascript", "-e", script)
+	case "linux":
+		cmd = xc.Command(ctx, "notify-send", title, message)
+	default:
+		return
+	}
+	cmd.Run().Wait()
+}
+
+// watchConflicted reports whether the repository is in a conflicted merge or rebase.
+func watchConflicted(ctx context.Context, cfg *Config) (*inProgress, error) {
+	ip, err := findInProgress(ctx, cfg)
+	if err != nil || ip == nil {
+		return nil, err
+	}
+	unmerged, err := cfg.Git.UnmergedPaths(ctx)
+	if err != nil || len(unmerged) == 0 {
+		return nil, err
+	}
+	return ip, nil
+}
+
+func doWatch(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		return usageErrorf("merde watch takes no arguments")
+	}
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	cfg, err := LoadDefault(ctx)
+	if err != nil {
+		return err
+	}
+	root, err := cfg.Git.RootDir(ctx)
+	if err != nil {
+		return err
+	}
+	gitDir, err := cfg.Git.GitDir(ctx)
+	if err != nil {
+		return err
+	}
+	// Git records a merge or rebase, and writes the index it stops with, at the top of the git dir.
+	changes, err := watchChanges(ctx, gitDir)
+	if err != nil {
+		if !errors.Is(err, errors.ErrUnsupported) {
+			ui.Warn("cannot watch %s (%v); checking it every %v instead", gitDir, err, watchInterval)
+		}
+		changes = pollChanges(ctx)
+	}
+	ui.Status("watching %s for conflicts (ctrl-c to stop)", root)
+	wasConflicted := false
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-changes:
+			if !ok && ctx.Err() == nil {
+				return fmt.Errorf("stopped receiving changes to %s", gitDir)
+			}
+		}
+		ip, err := watchConflicted(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		conflicted := ip != nil
+		if !conflicted || wasConflicted {
+			wasConflicted = conflicted
+			continue
+		}
+		wasConflicted = true
+		ui.Status("conflicted git %s detected", ip.verb)
+		if flagWatchNotify {
+			notifyDesktop(ctx, "merde", fmt.Sprintf("git %s stopped on conflicts in %s", ip.verb, root))
+		}
+		if !flagWatchAuto {
+			yes, err := confirm(cfg, askWatchResolve, "resolve with merde?")
+			if err != nil {
+				return err
+			}
+			if !yes {
+				continue
+			}
+		}
+		err = doContinue(ctx, nil)
+		if err != nil {
+			ui.Error(err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+//go:build darwin || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"time"
+)
+
+// watchChanges returns a channel that receives a value straight away and again whenever entries directly in dir change,
+// until ctx is done. Bursts of changes may arrive as one value.
+func watchChanges(ctx context.Context, dir string) (<-chan struct{}, error) {
+	kq, err := syscall.Kqueue()
+	if err != nil {
+		return nil, os.NewSyscallError("kqueue", err)
+	}
+	syscall.CloseOnExec(kq)
+	dirFD, err := syscall.Open(dir, syscall.O_RDONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		syscall.Close(kq)
+		return nil, &os.PathError{Op: "open", Path: dir, Err: err}
+	}
+	// A directory's vnode sees a write when an entry is added, removed, or renamed;
+	// files replaced by renaming over them, as git does with the index, count.
+	var ev syscall.Kevent_t
+	syscall.SetKevent(&ev, dirFD, syscall.EVFILT_VNODE, syscall.EV_ADD|syscall.EV_CLEAR)
+	ev.Fflags = syscall.NOTE_WRITE | syscall.NOTE_DELETE | syscall.NOTE_RENAME
+	_, err = syscall.Kevent(kq, []syscall.Kevent_t{ev}, nil, nil)
+	if err != nil {
+		syscall.Close(dirFD)
+		syscall.Close(kq)
+		return nil, os.NewSyscallError("kevent", err)
+	}
+	changes := make(chan struct{}, 1)
+	changes <- struct{}{}
+	go func() {
+		defer close(changes)
+		defer syscall.Close(dirFD)
+		defer syscall.Close(kq)
+		// Closing a kqueue does not wake a blocked kevent, so wait in short spells and check ctx between them.
+		timeout := syscall.NsecToTimespec(int64(250 * time.Millisecond))
+		events := make([]syscall.Kevent_t, 8)
+		for ctx.Err() == nil {
+			n, err := syscall.Kevent(kq, nil, events, &timeout)
+			if err == syscall.EINTR {
+				continue
+			}
+			if err != nil {
+				return
+			}
+			if n == 0 {
+				continue
+			}
+			select {
+			case changes <- struct{}{}:
+			default: // a change is already pending
+			}
+		}
+	}()
+	return changes, nil
+}
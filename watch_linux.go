@@ -0,0 +1,49 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"os"
+	"syscall"
+)
+
+// watchChanges returns a channel that receives a value straight away and again whenever entries directly in dir change,
+// until ctx is done. Bursts of changes may arrive as one value.
+func watchChanges(ctx context.Context, dir string) (<-chan struct{}, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	const mask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MODIFY | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+	_, err = syscall.InotifyAddWatch(fd, dir, mask)
+	if err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("inotify_add_watch", err)
+	}
+	// The descriptor is non-blocking, so os hands it to the runtime poller and Close interrupts a pending Read.
+	f := os.NewFile(uintptr(fd), "inotify")
+	changes := make(chan struct{}, 1)
+	changes <- struct{}{}
+	go func() {
+		<-ctx.Done()
+		f.Close()
+	}()
+	go func() {
+		defer close(changes)
+		buf := make([]byte, 64<<10)
+		for {
+			_, err := f.Read(buf)
+			if err != nil {
+				return
+			}
+			select {
+			case changes <- struct{}{}:
+			default: // a change is already pending
+			}
+		}
+	}()
+	return changes, nil
+}
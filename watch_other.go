@@ -0,0 +1,17 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+// watchChanges is not implemented here, so merde watch polls with pollChanges instead.
+func watchChanges(ctx context.Context, dir string) (<-chan struct{}, error) {
+	return nil, errors.ErrUnsupported
+}
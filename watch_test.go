@@ -0,0 +1,62 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchChanges(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := watchChanges(ctx, dir)
+	if errors.Is(err, errors.ErrUnsupported) {
+		t.Skip("no file events here")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	receive := func(what string) bool {
+		t.Helper()
+		select {
+		case _, ok := <-changes:
+			return ok
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no value %s", what)
+			return false
+		}
+	}
+	if !receive("at the start") {
+		t.Fatal("changes closed at the start")
+	}
+	select {
+	case <-changes:
+		t.Fatal("change received before anything changed")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Git writes the index to index.lock and renames it into place.
+	err = os.WriteFile(filepath.Join(dir, "index.lock"), []byte("index"), 0o644)
+	if err == nil {
+		err = os.Rename(filepath.Join(dir, "index.lock"), filepath.Join(dir, "index"))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !receive("after a change") {
+		t.Fatal("changes closed after a change")
+	}
+
+	cancel()
+	for receive("after cancelling") {
+		// Drain changes still pending.
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2025 Bold Software, Inc. (https://merde.ai/)
+// Released under the PolyForm Noncommercial License 1.0.0.
+// Please see the README for details.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"unicode"
+)
+
+// Whitespace policies, from least to most permissive.
+// They mirror git diff's --ignore-space-at-eol, --ignore-space-change, and --ignore-all-space.
+const (
+	whitespaceOff      = "off"      // whitespace changes are real changes
+	whitespaceTrailing = "trailing" // ignore trailing whitespace
+	whitespaceAmount   = "amount"   // ignore changes in the amount of whitespace, including indentation
+	whitespaceAll      = "all"      // ignore all whitespace
+)
+
+// whitespaceNormalizer returns a function that normalizes away the whitespace the policy ignores.
+func whitespaceNormalizer(policy string) (func([]byte) []byte, error) {
+	switch policy {
+	case whitespaceOff:
+		return nil, nil
+	case whitespaceTrailing:
+		return mapLines(func(line []byte) []byte {
+			return bytes.TrimRightFunc(line, unicode.IsSpace)
+		}), nil
+	case whitespaceAmount:
+		return mapLines(func(line []byte) []byte {
+			return bytes.Join(bytes.Fields(line), []byte(" "))
+		}), nil
+	case whitespaceAll:
+		return func(data []byte) []byte {
+			return bytes.Join(bytes.Fields(data), nil)
+		}, nil
+	}
+	return nil, fmt.Errorf("invalid %s policy %q, want one of %s, %s, %s, %s", whitespaceKey, policy, whitespaceOff, whitespaceTrailing, whitespaceAmount, whitespaceAll)
+}
+
+// mapLines returns a function that applies f to each line of its input.
+func mapLines(f func([]byte) []byte) func([]byte) []byte {
+	return func(data []byte) []byte {
+		lines := bytes.Split(data, []byte("\n"))
+		for i, line := range lines {
+			lines[i] = f(line)
+		}
+		return bytes.Join(lines, []byte("\n"))
+	}
+}
+
+// resolveWhitespaceOnly resolves paths modified on both sides where at least one side
+// only changed whitespace, as configured by the whitespace policy, by keeping the other side.
+func resolveWhitespaceOnly(ctx context.Context, cfg *Config, info *deconflictRequestInfo) error {
+	normalize, err := whitespaceNormalizer(cfg.Get(whitespaceKey))
+	if err != nil || normalize == nil {
+		return err
+	}
+	for _, p := range unresolved(info) {
+		m, err := readFileMerge(ctx, cfg, info, p)
+		if err != nil {
+			return err
+		}
+		if isBinary(m.Base) || isBinary(m.Ours) || isBinary(m.Theirs) {
+			continue
+		}
+		base, ours, theirs := normalize(m.Base), normalize(m.Ours), normalize(m.Theirs)
+		var result []byte
+		switch {
+		case bytes.Equal(ours, base):
+			result = m.Theirs
+		case bytes.Equal(theirs, base), bytes.Equal(ours, theirs):
+			result = m.Ours
+		default:
+			continue
+		}
+		err = resolveLocally(ctx, cfg, info, p, result, "whitespace only")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}